@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/klippa-app/pdfium-cli/pdf"
 
@@ -13,13 +13,15 @@ import (
 
 func init() {
 	addGenericPDFOptions(attachmentsCmd)
+	addArchiveOption(attachmentsCmd)
+	addOutputTemplateOption(attachmentsCmd, "{name}")
 	rootCmd.AddCommand(attachmentsCmd)
 }
 
 var attachmentsCmd = &cobra.Command{
 	Use:   "attachments [input] [output-folder]",
 	Short: "Extract the attachments of a PDF",
-	Long:  "Extract the attachments of a PDF and store them as file.\n[input] can either be a file path or - for stdin.\n[output-folder] can be either a folder or - for stdout. In the case of stdout, multiple files will be delimited by the value of the std-file-delimiter, with a newline before and after it.",
+	Long:  "Extract the attachments of a PDF and store them as file.\n[input] can either be a file path or - for stdin.\n[output-folder] can be either a folder or - for stdout. The folder will be created when it doesn't exist. In the case of stdout, multiple files are written in the std-format, by default delimited by the value of the std-file-delimiter, with a newline before and after it. When the output ends with .zip, the files are written into a zip archive with a manifest.json.",
 	Args: func(cmd *cobra.Command, args []string) error {
 		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
 			return newExitCodeError(err, ExitCodeInvalidArguments)
@@ -29,15 +31,12 @@ var attachmentsCmd = &cobra.Command{
 			return fmt.Errorf("could not open input file %s: %w\n", args[0], newExitCodeError(err, ExitCodeInvalidInput))
 		}
 
-		if args[1] != stdFilename {
-			folderStat, err := os.Stat(args[1])
-			if err != nil {
-				return fmt.Errorf("could not open output folder %s: %w\n", args[1], newExitCodeError(err, ExitCodeInvalidOutput))
-			}
+		if err := validOutputFolder(args[1]); err != nil {
+			return err
+		}
 
-			if !folderStat.IsDir() {
-				return newExitCodeError(fmt.Errorf("output folder %s is not a folder\n", args[1]), ExitCodeInvalidOutput)
-			}
+		if err := validOutputTemplate(getOutputTemplate(cmd)); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
 		}
 
 		return nil
@@ -65,29 +64,53 @@ var attachmentsCmd = &cobra.Command{
 			return
 		}
 
-		if len(attachments.Attachments) > 0 {
-			for i := 0; i < len(attachments.Attachments); i++ {
-				if args[1] != stdFilename {
-					filePath := path.Join(args[1], attachments.Attachments[i].Name)
-					outFile, err := os.Create(filePath)
-					if err != nil {
-						handleError(cmd, fmt.Errorf("could not create output file for attachment %d for PDF %s: %w\n", i, args[0], err), ExitCodeInvalidOutput)
-						return
-					}
-
-					outFile.Write(attachments.Attachments[i].Content)
-					outFile.Close()
-
-					cmd.Printf("Exported attachment %d into %s\n", i+1, filePath)
-				} else {
-					if i > 0 {
-						os.Stdout.WriteString("\n")
-						os.Stdout.WriteString(stdFileDelimiter)
-						os.Stdout.WriteString("\n")
-					}
-					os.Stdout.Write(attachments.Attachments[i].Content)
+		pageCount, err := pdf.PdfiumInstance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+			Document: document.Document,
+		})
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not get page count for PDF %s: %w\n", args[0], newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+
+		outputArchive, err := newOutputArchive(cmd, args[1], args[0], pageCount.PageCount)
+		if err != nil {
+			handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidArguments)
+			return
+		}
+
+		for i := 0; i < len(attachments.Attachments); i++ {
+			fileName, err := pdf.ExpandOutputTemplate(getOutputTemplate(cmd), pdf.OutputTemplateValues{
+				Input: pdf.OutputTemplateInputName(args[0]),
+				Index: i + 1,
+				Name:  attachments.Attachments[i].Name,
+				Ext:   strings.TrimPrefix(filepath.Ext(attachments.Attachments[i].Name), "."),
+			})
+			if err != nil {
+				handleError(cmd, fmt.Errorf("could not create output path for attachment %d for PDF %s: %w\n", i, args[0], err), ExitCodeInvalidArguments)
+				return
+			}
+
+			if outputArchive == nil {
+				filePath := joinOutputPath(args[1], fileName)
+				outFile, err := createOutputFile(filePath)
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not create output file for attachment %d for PDF %s: %w\n", i, args[0], err), ExitCodeInvalidOutput)
+					return
+				}
+
+				outFile.Write(attachments.Attachments[i].Content)
+				closeOutputFile(cmd, outFile, filePath)
+
+				cmd.Printf("Exported attachment %d into %s\n", i+1, filePath)
+			} else {
+				err = outputArchive.WriteFile(newStdFile(fileName, i+1, 0), attachments.Attachments[i].Content)
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not write attachment %d for PDF %s: %w\n", i, args[0], err), ExitCodeInvalidOutput)
+					return
 				}
 			}
 		}
+
+		outputArchive.finish(cmd)
 	},
 }
@@ -0,0 +1,348 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	batchInputs          []string
+	batchInputList       string
+	batchExtensions      string
+	batchContinueOnError bool
+	batchSummary         string
+	batchParallel        int
+	batchWorkerInputs    string
+)
+
+const (
+	batchStatusSucceeded = "succeeded"
+	batchStatusFailed    = "failed"
+	batchStatusSkipped   = "skipped"
+)
+
+type batchInputResult struct {
+	Input      string
+	Status     string
+	ExitCode   int
+	Error      string
+	DurationMs int64
+}
+
+type batchSummaryResult struct {
+	Command   []string
+	Total     int
+	Succeeded int
+	Failed    int
+	Skipped   int
+	Inputs    []batchInputResult
+}
+
+func init() {
+	batchCmd.Flags().StringArrayVarP(&batchInputs, "input", "i", []string{}, "An input of the batch, can be given multiple times. An input can be a file, a folder that is searched recursively, or a glob pattern like 'docs/*/*.pdf' (use quotes so that your shell doesn't expand it).")
+	batchCmd.Flags().StringVarP(&batchInputList, "input-list", "", "", "A file with an input on every line, or - for stdin. Empty lines and lines starting with # are skipped. The inputs can be files, folders or globs.")
+	batchCmd.Flags().StringVarP(&batchExtensions, "extensions", "", "pdf", "The comma separated file extensions of the files to use when searching folders.")
+	batchCmd.Flags().BoolVarP(&batchContinueOnError, "continue-on-error", "", false, "Continue with the next input when the command fails for an input, otherwise the remaining inputs are skipped. With parallel, every process stops at its own first error.")
+	batchCmd.Flags().StringVarP(&batchSummary, "summary", "", "", "Write a JSON summary with the status, exit code and error of every input to this file, or - for stdout.")
+	batchCmd.Flags().IntVarP(&batchParallel, "parallel", "", 1, "The amount of processes to divide the inputs over. Every process loads pdfium once and runs the command for its inputs.")
+	batchCmd.Flags().StringVarP(&batchWorkerInputs, "worker-inputs", "", "", "A JSON file with the inputs of a batch process, used by parallel.")
+	batchCmd.Flags().MarkHidden("worker-inputs")
+
+	// Flags after the command belong to the command.
+	batchCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(batchCmd)
+}
+
+var batchCmd = &cobra.Command{
+	Use:   "batch [flags] [command] [arguments]",
+	Short: "Run a command for many input files",
+	Long: "Run a command for many input files with one invocation, pdfium is only loaded once.\n" +
+		"[command] is the command to run with its [arguments] and options. The tokens {file} (the path of the input), {dir} (the folder of the input, relative to the searched folder or the part of the glob without wildcards) and {input} (the filename of the input without extension) are replaced in the arguments. When no argument contains {file}, the input is given as the first argument of the command.\n" +
+		"Example: pdfium batch --input docs --continue-on-error render 'out/{dir}/{input}-{page}.png' --dpi 150\n" +
+		"The exit code is " + strconv.Itoa(ExitCodeBatchFailed) + " when the command failed for one or more inputs, the summary contains the exit code of every input.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.MinimumNArgs(1)(cmd, args); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
+		}
+
+		command, _, err := rootCmd.Find(args[:1])
+		if err != nil || command == rootCmd {
+			return newExitCodeError(fmt.Errorf("unknown command %s\n", args[0]), ExitCodeInvalidArguments)
+		}
+
+		if command == cmd {
+			return newExitCodeError(fmt.Errorf("the command batch can't be used in a batch\n"), ExitCodeInvalidArguments)
+		}
+
+		// Check the summary before running the command for every input.
+		if batchSummary != "" && batchSummary != stdFilename {
+			if pdf.IsLocation(batchSummary) {
+				if err := pdf.ValidLocation(batchSummary, true); err != nil {
+					return newExitCodeError(fmt.Errorf("%w\n", err), ExitCodeInvalidOutput)
+				}
+			} else if err := validOutputFolder(filepath.Dir(batchSummary)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if batchParallel < 1 {
+			handleError(cmd, fmt.Errorf("invalid amount of parallel processes %d, should be at least 1\n", batchParallel), ExitCodeInvalidArguments)
+			return
+		}
+
+		inputs, err := getBatchInputs()
+		if err != nil {
+			handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidInput)
+			return
+		}
+
+		if len(inputs) == 0 {
+			handleError(cmd, fmt.Errorf("no inputs given, use the option input or input-list\n"), ExitCodeInvalidInput)
+			return
+		}
+
+		var results []batchInputResult
+		if batchParallel > 1 && len(inputs) > 1 {
+			results, err = runBatchInParallel(cmd, args, inputs)
+			if err != nil {
+				handleError(cmd, fmt.Errorf("could not run batch: %w\n", err), ExitCodeInvalidArguments)
+				return
+			}
+		} else {
+			results = runBatch(cmd, args, inputs)
+		}
+
+		summary := batchSummaryResult{
+			Command: args,
+			Total:   len(results),
+			Inputs:  results,
+		}
+		for _, result := range results {
+			switch result.Status {
+			case batchStatusSucceeded:
+				summary.Succeeded++
+			case batchStatusFailed:
+				summary.Failed++
+			case batchStatusSkipped:
+				summary.Skipped++
+			}
+		}
+
+		if batchSummary != "" {
+			outputJson, _ := json.MarshalIndent(summary, "", "  ")
+			if batchSummary == stdFilename {
+				cmd.Println(string(outputJson))
+			} else {
+				var err error
+				if !pdf.IsLocation(batchSummary) {
+					err = createOutputFolders(batchSummary)
+				}
+				if err == nil {
+					err = writeOutputFile(batchSummary, outputJson)
+				}
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not write summary into %s: %w\n", batchSummary, err), ExitCodeInvalidOutput)
+					return
+				}
+			}
+		}
+
+		// The processes of parallel only write the summary.
+		if batchWorkerInputs != "" {
+			if summary.Failed > 0 {
+				os.Exit(ExitCodeBatchFailed)
+			}
+			return
+		}
+
+		result := fmt.Sprintf("processed %d input(s): %d succeeded, %d failed, %d skipped\n", summary.Total, summary.Succeeded, summary.Failed, summary.Skipped)
+		if summary.Failed > 0 {
+			handleError(cmd, errors.New(result), ExitCodeBatchFailed)
+			return
+		}
+
+		cmd.PrintErr(result)
+	},
+}
+
+// getBatchInputs returns the inputs of the batch from the options.
+func getBatchInputs() ([]pdf.BatchInput, error) {
+	if batchWorkerInputs != "" {
+		workerInputs, err := os.ReadFile(batchWorkerInputs)
+		if err != nil {
+			return nil, fmt.Errorf("could not read worker inputs: %w", err)
+		}
+
+		inputs := []pdf.BatchInput{}
+		if err := json.Unmarshal(workerInputs, &inputs); err != nil {
+			return nil, fmt.Errorf("could not read worker inputs: %w", err)
+		}
+
+		return inputs, nil
+	}
+
+	inputs := append([]string{}, batchInputs...)
+	if batchInputList != "" {
+		var listReader io.Reader = os.Stdin
+		if batchInputList != stdFilename {
+			listFile, err := os.Open(batchInputList)
+			if err != nil {
+				return nil, fmt.Errorf("could not open input list %s: %w", batchInputList, err)
+			}
+			defer listFile.Close()
+			listReader = listFile
+		}
+
+		listInputs, err := pdf.ReadBatchInputList(listReader)
+		if err != nil {
+			return nil, fmt.Errorf("could not read input list %s: %w", batchInputList, err)
+		}
+		inputs = append(inputs, listInputs...)
+	}
+
+	return pdf.FindBatchInputs(inputs, strings.Split(batchExtensions, ","))
+}
+
+// runBatch runs the command for every input in this process.
+func runBatch(cmd *cobra.Command, args []string, inputs []pdf.BatchInput) []batchInputResult {
+	// Keep pdfium loaded for the next inputs.
+	pdf.KeepPdfiumLoaded(true)
+	defer func() {
+		pdf.KeepPdfiumLoaded(false)
+		pdf.ClosePdfium()
+	}()
+
+	results := []batchInputResult{}
+	failed := false
+	for _, input := range inputs {
+		if failed && !batchContinueOnError {
+			results = append(results, batchInputResult{Input: input.Path, Status: batchStatusSkipped})
+			continue
+		}
+
+		start := time.Now()
+		exitCode, err := runCommandInProcess(pdf.ExpandBatchArguments(args, input))
+		result := batchInputResult{
+			Input:      input.Path,
+			Status:     batchStatusSucceeded,
+			ExitCode:   exitCode,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+
+		if err != nil {
+			failed = true
+			result.Status = batchStatusFailed
+			result.Error = strings.TrimSpace(err.Error())
+			cmd.PrintErrf("Could not process %s, exit code %d\n", input.Path, exitCode)
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// runBatchInParallel divides the inputs over multiple processes of this
+// executable in batch mode and collects their summaries.
+func runBatchInParallel(cmd *cobra.Command, args []string, inputs []pdf.BatchInput) ([]batchInputResult, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	tempDir, err := os.MkdirTemp("", "pdfium-cli-batch-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	processes := batchParallel
+	if processes > len(inputs) {
+		processes = len(inputs)
+	}
+
+	// Divide the inputs round-robin, so that the processes get a mix of the
+	// inputs.
+	processInputs := make([][]int, processes)
+	for i := range inputs {
+		processInputs[i%processes] = append(processInputs[i%processes], i)
+	}
+
+	results := make([]batchInputResult, len(inputs))
+	wg := sync.WaitGroup{}
+	for process := range processInputs {
+		workerInputs := []pdf.BatchInput{}
+		for _, i := range processInputs[process] {
+			workerInputs = append(workerInputs, inputs[i])
+		}
+
+		workerInputsJson, _ := json.Marshal(workerInputs)
+		inputsPath := fmt.Sprintf("%s/inputs-%d.json", tempDir, process)
+		summaryPath := fmt.Sprintf("%s/summary-%d.json", tempDir, process)
+		if err := os.WriteFile(inputsPath, workerInputsJson, 0600); err != nil {
+			return nil, err
+		}
+
+		processArgs := []string{"batch", "--worker-inputs", inputsPath, "--summary", summaryPath, "--extensions", batchExtensions}
+		if batchContinueOnError {
+			processArgs = append(processArgs, "--continue-on-error")
+		}
+		processArgs = append(processArgs, args...)
+
+		wg.Add(1)
+		go func(process int) {
+			defer wg.Done()
+
+			processCmd := exec.Command(executable, processArgs...)
+			processCmd.Stdout = cmd.OutOrStdout()
+			processCmd.Stderr = cmd.ErrOrStderr()
+			runErr := processCmd.Run()
+
+			summary := batchSummaryResult{}
+			summaryJson, err := os.ReadFile(summaryPath)
+			if err == nil {
+				err = json.Unmarshal(summaryJson, &summary)
+			}
+
+			for j, i := range processInputs[process] {
+				if err == nil && j < len(summary.Inputs) {
+					results[i] = summary.Inputs[j]
+					continue
+				}
+
+				// The process stopped without a summary.
+				exitCode := ExitCodeBatchFailed
+				exitError := &exec.ExitError{}
+				if errors.As(runErr, &exitError) {
+					exitCode = exitError.ExitCode()
+				}
+
+				results[i] = batchInputResult{
+					Input:    inputs[i].Path,
+					Status:   batchStatusFailed,
+					ExitCode: exitCode,
+					Error:    fmt.Sprintf("the batch process stopped without a result: %v", runErr),
+				}
+			}
+		}(process)
+	}
+	wg.Wait()
+
+	return results, nil
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkOutputType string
+	checkDPI        int
+	checkStrict     bool
+)
+
+func init() {
+	addGenericPDFOptions(checkCmd)
+	checkCmd.Flags().StringVarP(&checkOutputType, "output-type", "", "json", "The file type of the report, text or json")
+	checkCmd.Flags().IntVarP(&checkDPI, "dpi", "", 18, "The DPI to render the pages in to check them")
+	checkCmd.Flags().BoolVarP(&checkStrict, "strict", "", false, fmt.Sprintf("Exit with exit code %d when the status is warn", ExitCodeCheckWarning))
+	rootCmd.AddCommand(checkCmd)
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check [input] [output]",
+	Short: "Check whether a PDF can be processed",
+	Long:  fmt.Sprintf("Check whether a PDF can be processed, by loading every page, extracting its text, rendering it in a low DPI and reading the forms, attachments and signatures. The report contains the errors and timings per page and a status: pass, warn (something could not be read, but the pages can be used) or fail (the document or a page could not be loaded or rendered). When the status is fail, the command exits with the exit code of the pdfium error, or %d.\n[input] can either be a file path or - for stdin.\n[output] can either be a file path or - for stdout (default).", ExitCodeCheckFailed),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.RangeArgs(1, 2)(cmd, args); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
+		}
+
+		if err := validFile(args[0]); err != nil {
+			return fmt.Errorf("could not open input file %s: %w\n", args[0], newExitCodeError(err, ExitCodeInvalidInput))
+		}
+
+		if checkOutputType != "text" && checkOutputType != "json" {
+			return newExitCodeError(fmt.Errorf("invalid output type %s, should be text or json\n", checkOutputType), ExitCodeInvalidArguments)
+		}
+
+		if checkDPI < 1 {
+			return newExitCodeError(fmt.Errorf("invalid DPI %d, should be at least 1\n", checkDPI), ExitCodeInvalidArguments)
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pdf.LoadPdfium()
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not load pdfium: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+		defer pdf.ClosePdfium()
+
+		start := time.Now()
+		var report *pdf.CheckReport
+		var openErr error
+
+		document, closeFile, err := openFile(args[0])
+		if err != nil {
+			report = &pdf.CheckReport{
+				Status: pdf.CheckStatusFail,
+				Error:  err.Error(),
+				Pages:  []pdf.CheckPage{},
+			}
+			openErr = fmt.Errorf("could not open input file %s: %w", args[0], err)
+		} else {
+			defer closeFile()
+			report = pdf.CheckDocument(pdf.PdfiumInstance, document.Document, checkDPI)
+		}
+		report.File = args[0]
+		report.Milliseconds = float64(time.Since(start).Microseconds()) / 1000
+
+		reportBuffer := &bytes.Buffer{}
+		if checkOutputType == "json" {
+			outputJson, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Fprintln(reportBuffer, string(outputJson))
+		} else {
+			writeCheckReport(reportBuffer, *report)
+		}
+
+		if len(args) > 1 && args[1] != stdFilename {
+			if err := writeOutputFile(args[1], reportBuffer.Bytes()); err != nil {
+				handleError(cmd, fmt.Errorf("could not write report into %s: %w\n", args[1], err), ExitCodeInvalidOutput)
+				return
+			}
+		} else {
+			cmd.Print(reportBuffer.String())
+		}
+
+		if report.Status == pdf.CheckStatusFail {
+			checkErr := openErr
+			if checkErr == nil {
+				checkErr = checkReportError(report)
+			}
+			handleError(cmd, fmt.Errorf("check of %s failed: %w\n", args[0], checkErr), ExitCodeCheckFailed)
+			return
+		}
+
+		if report.Status == pdf.CheckStatusWarn && checkStrict {
+			handleError(cmd, fmt.Errorf("check of %s has warnings\n", args[0]), ExitCodeCheckWarning)
+			return
+		}
+	},
+}
+
+// checkReportError returns the error of the first part of the document that
+// made the check fail. The errors of the report are the errors of pdfium,
+// which decide the exit code.
+func checkReportError(report *pdf.CheckReport) error {
+	if report.Error != "" {
+		return fmt.Errorf("could not get page count: %w", newPdfiumError(errors.New(report.Error)))
+	}
+
+	for _, page := range report.Pages {
+		if page.LoadError != "" {
+			return fmt.Errorf("could not load page %d: %w", page.Page, newPdfiumError(errors.New(page.LoadError)))
+		}
+		if page.RenderError != "" {
+			return fmt.Errorf("could not render page %d: %w", page.Page, newPdfiumError(errors.New(page.RenderError)))
+		}
+	}
+
+	return errors.New("the document has no pages")
+}
+
+func writeCheckReport(writer io.Writer, report pdf.CheckReport) {
+	fmt.Fprintf(writer, "Status of %s: %s\n", report.File, report.Status)
+	if report.Error != "" {
+		fmt.Fprintf(writer, "Error: %s\n", report.Error)
+		return
+	}
+
+	if report.ValidCrossReferenceTable != nil && !*report.ValidCrossReferenceTable {
+		fmt.Fprintf(writer, "Cross reference table: invalid, reconstructed\n")
+	}
+
+	fmt.Fprintf(writer, "Page count: %d\n", report.PageCount)
+	for _, page := range report.FailedPages() {
+		for _, pageErr := range []string{page.LoadError, page.RenderError, page.TextError} {
+			if pageErr != "" {
+				fmt.Fprintf(writer, " - Page %d: %s, %s\n", page.Page, page.Status, pageErr)
+			}
+		}
+	}
+
+	for _, item := range []struct {
+		name string
+		pdf.CheckItem
+	}{{"Form fields", report.Forms}, {"Attachments", report.Attachments}, {"Signatures", report.Signatures}} {
+		if item.Error != "" {
+			fmt.Fprintf(writer, "%s: %s, %s\n", item.name, item.Status, item.Error)
+		} else {
+			fmt.Fprintf(writer, "%s: %s, %d\n", item.name, item.Status, item.Count)
+		}
+	}
+
+	fmt.Fprintf(writer, "Checked in %.2f ms\n", report.Milliseconds)
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// runningInProcess is true while a command runs in process, errors then stop
+// the command instead of the process.
+var runningInProcess bool
+
+// inProcessCommandError is the error of a command that was stopped while
+// running in process.
+type inProcessCommandError struct {
+	err      error
+	exitCode int
+}
+
+// runCommandInProcess runs a command in this process and returns its exit
+// code. The first argument is the name of the command. The options of the
+// command are reset to their defaults first, so that the options of an
+// earlier run don't leak into this run. A crash of the command is returned as
+// error.
+func runCommandInProcess(args []string) (exitCode int, err error) {
+	runningInProcess = true
+	defer func() {
+		runningInProcess = false
+
+		// Commands can write their output into a file, reset that for the
+		// next run.
+		for _, command := range rootCmd.Commands() {
+			command.SetOut(nil)
+		}
+
+		if r := recover(); r != nil {
+			commandError, ok := r.(*inProcessCommandError)
+			if !ok {
+				// The command crashed, the pdfium instance could be in a bad
+				// state, replace it for the next command.
+				pdf.KillPdfium()
+				exitCode = ExitCodePdfiumUnknownError
+				err = fmt.Errorf("the command crashed: %v\n", r)
+				return
+			}
+
+			exitCode = commandError.exitCode
+			err = commandError.err
+		}
+	}()
+
+	if command, _, findErr := rootCmd.Find(args); findErr == nil {
+		resetFlags(command)
+	}
+
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err != nil {
+		exitCodeError := &ExitCodeError{}
+		if errors.As(err, &exitCodeError) {
+			return exitCodeError.ExitCode(), err
+		}
+		return ExitCodeInvalidArguments, err
+	}
+
+	return 0, nil
+}
+
+// resetFlags sets the options of a command back to their defaults.
+func resetFlags(command *cobra.Command) {
+	command.Flags().VisitAll(func(flag *pflag.Flag) {
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			defaultValue := strings.TrimSuffix(strings.TrimPrefix(flag.DefValue, "["), "]")
+			values := []string{}
+			if defaultValue != "" {
+				values = strings.Split(defaultValue, ",")
+			}
+			sliceValue.Replace(values)
+		} else {
+			flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	})
+}
+
+// inProcessCommand describes how a command is called by the server and the
+// worker, which let the command write into an output folder.
+type inProcessCommand struct {
+	multipleInputs bool   // Whether the command takes multiple inputs, like merge.
+	output         string // The output argument, relative to the output folder. Empty for an output folder.
+	textOutput     bool   // Whether the output is text or JSON, depending on the option output-type.
+}
+
+var inProcessCommands = map[string]inProcessCommand{
+	"info":        {output: "info", textOutput: true},
+	"text":        {output: "text", textOutput: true},
+	"form":        {output: "form", textOutput: true},
+	"render":      {output: "{input}-{page}.{ext}"},
+	"merge":       {output: "merged.pdf", multipleInputs: true},
+	"explode":     {output: "{input}-{page}.pdf"},
+	"flatten":     {output: "{input}.pdf"},
+	"images":      {},
+	"attachments": {},
+}
+
+// getOutputArgument returns the output argument of the command in the output
+// folder for the first input. With an archive format the command writes one
+// archive.
+func (c inProcessCommand) getOutputArgument(outputFolder string, input string, outputType string, archive string) string {
+	if archive != "" {
+		return filepath.Join(outputFolder, pdf.OutputTemplateInputName(input)+"."+archive)
+	}
+
+	if c.output == "" {
+		return outputFolder
+	}
+
+	outputName := strings.ReplaceAll(c.output, "{input}", pdf.OutputTemplateInputName(input))
+	if c.textOutput {
+		if outputType == "json" {
+			outputName += ".json"
+		} else {
+			outputName += ".txt"
+		}
+	}
+
+	return filepath.Join(outputFolder, outputName)
+}
+
+// sanitizeInputFilename returns a filename for an uploaded input that can't
+// point outside of its folder. The name is used for the {input} token in the
+// output argument, so the characters of tokens are replaced, otherwise a
+// name like {page}.pdf would become a token when the output is expanded.
+func sanitizeInputFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.NewReplacer("{", "_", "}", "_", "%", "_").Replace(name)
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "input.pdf"
+	}
+	return name
+}
+
+// findOutputFiles returns the files that a command wrote into the output
+// folder, sorted by path.
+func findOutputFiles(outputFolder string) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(outputFolder, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
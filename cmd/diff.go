@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/responses"
+	"github.com/spf13/cobra"
+)
+
+// diffTextContext is the amount of lines around a change in the text diff.
+const diffTextContext = 3
+
+var (
+	diffOutputType     string
+	diffDPI            int
+	diffTolerance      int
+	diffMaxScore       float64
+	diffImages         string
+	diffIgnoreMetadata string
+)
+
+func init() {
+	addGenericPDFOptions(diffCmd)
+	diffCmd.Flags().StringVarP(&diffOutputType, "output-type", "", "text", "The file type of the report, text or json")
+	diffCmd.Flags().IntVarP(&diffDPI, "dpi", "", 72, "The DPI to render the pages in to compare them")
+	diffCmd.Flags().IntVarP(&diffTolerance, "tolerance", "", 0, "The difference (0-255) that a color channel of a pixel may have before the pixel counts as different, to ignore small differences in anti-aliasing.")
+	diffCmd.Flags().Float64VarP(&diffMaxScore, "max-score", "", 0, "The fraction (0-1) of pixels of a page that may differ before the page counts as different, e.g. 0.001 for 0.1%.")
+	diffCmd.Flags().StringVarP(&diffImages, "diff-images", "", "", "Write an image of every page that looks different, with the different pixels in red on a faded version of the page of the second PDF. The value is the filename template of the png images, e.g. diff-{page}.png, it should contain a {page} or {index} placeholder when comparing more than one page. Missing folders in the path will be created. "+outputTemplateTokensHelp+" {input} is the name of the first PDF.")
+	diffCmd.Flags().StringVarP(&diffIgnoreMetadata, "ignore-metadata", "", "", "Comma separated list of metadata tags to not compare, e.g. CreationDate,ModDate.")
+	rootCmd.AddCommand(diffCmd)
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [a] [b] [output]",
+	Short: "Compare two PDFs visually and textually",
+	Long:  fmt.Sprintf("Compare two PDFs visually and textually. Both PDFs are rendered in the given DPI and compared pixel by pixel, which gives every page a score: the fraction of the pixels that differ. The text of every page is compared as a unified diff. The page count and metadata are compared as well. The command exits with exit code %d when the PDFs differ.\n[a] and [b] can either be a file path or - for stdin, with - for both the first and second file on stdin are compared.\n[output] is the report and can either be a file path or - for stdout (default).", ExitCodeDiffFound),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.RangeArgs(2, 3)(cmd, args); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
+		}
+
+		for _, input := range args[:2] {
+			if err := validFile(input); err != nil {
+				return fmt.Errorf("could not open input file %s: %w\n", input, newExitCodeError(err, ExitCodeInvalidInput))
+			}
+		}
+
+		if diffOutputType != "text" && diffOutputType != "json" {
+			return newExitCodeError(fmt.Errorf("invalid output type %s, should be text or json\n", diffOutputType), ExitCodeInvalidArguments)
+		}
+
+		if diffDPI < 1 {
+			return newExitCodeError(fmt.Errorf("invalid DPI %d, should be at least 1\n", diffDPI), ExitCodeInvalidArguments)
+		}
+
+		if diffTolerance < 0 || diffTolerance > 255 {
+			return newExitCodeError(fmt.Errorf("invalid tolerance %d, should be between 0 and 255\n", diffTolerance), ExitCodeInvalidArguments)
+		}
+
+		if diffMaxScore < 0 || diffMaxScore > 1 {
+			return newExitCodeError(fmt.Errorf("invalid max score %g, should be between 0 and 1\n", diffMaxScore), ExitCodeInvalidArguments)
+		}
+
+		if diffImages != "" {
+			if err := validOutputTemplate(diffImages); err != nil {
+				return newExitCodeError(err, ExitCodeInvalidArguments)
+			}
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pdf.LoadPdfium()
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not load pdfium: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+		defer pdf.ClosePdfium()
+
+		documentA, closeFileA, err := openFile(args[0])
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not open input file %s: %w\n", args[0], err), ExitCodeInvalidInput)
+			return
+		}
+		defer closeFileA()
+
+		documentB, closeFileB, err := openFile(args[1])
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not open input file %s: %w\n", args[1], err), ExitCodeInvalidInput)
+			return
+		}
+		defer closeFileB()
+
+		report := pdf.DiffReport{
+			A:        args[0],
+			B:        args[1],
+			Metadata: []pdf.DiffMetadata{},
+			Pages:    []pdf.DiffPage{},
+		}
+
+		for i, document := range []references.FPDF_DOCUMENT{documentA.Document, documentB.Document} {
+			pageCount, err := pdf.PdfiumInstance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+				Document: document,
+			})
+			if err != nil {
+				handleError(cmd, fmt.Errorf("could not get page count for PDF %s: %w\n", args[i], newPdfiumError(err)), ExitCodePdfiumError)
+				return
+			}
+
+			if i == 0 {
+				report.PageCountA = pageCount.PageCount
+			} else {
+				report.PageCountB = pageCount.PageCount
+			}
+		}
+
+		if diffImages != "" && max(report.PageCountA, report.PageCountB) > 1 && !pdf.OutputTemplateHasToken(diffImages, pdf.OutputTemplatePageTokens...) {
+			handleError(cmd, fmt.Errorf("diff images %s should contain page pattern {page}, {index} or %%d\n", diffImages), ExitCodeInvalidArguments)
+			return
+		}
+
+		// Getting the metadata fails when the PDF has no metadata.
+		metadataTags := [2][]responses.GetMetaDataTag{}
+		for i, document := range []references.FPDF_DOCUMENT{documentA.Document, documentB.Document} {
+			metadata, err := pdf.PdfiumInstance.GetMetaData(&requests.GetMetaData{
+				Document: document,
+			})
+			if err == nil {
+				metadataTags[i] = metadata.Tags
+			}
+		}
+
+		ignoreMetadata := []string{}
+		if diffIgnoreMetadata != "" {
+			ignoreMetadata = strings.Split(diffIgnoreMetadata, ",")
+		}
+		report.Metadata = pdf.DiffMetadataTags(metadataTags[0], metadataTags[1], ignoreMetadata)
+
+		diffOptions := pdf.DiffOptions{
+			DPI:         diffDPI,
+			Tolerance:   diffTolerance,
+			TextContext: diffTextContext,
+			NameA:       args[0],
+			NameB:       args[1],
+		}
+
+		imageIndex := 0
+		for i := 0; i < max(report.PageCountA, report.PageCountB); i++ {
+			if i >= report.PageCountA {
+				report.Pages = append(report.Pages, pdf.DiffPage{Page: i + 1, MissingIn: "a"})
+				continue
+			}
+			if i >= report.PageCountB {
+				report.Pages = append(report.Pages, pdf.DiffPage{Page: i + 1, MissingIn: "b"})
+				continue
+			}
+
+			page, pixelDiff, err := pdf.ComparePage(pdf.PdfiumInstance, documentA.Document, documentB.Document, i, diffOptions)
+			if err != nil {
+				handleError(cmd, fmt.Errorf("could not compare page %d: %w\n", i+1, newPdfiumError(err)), ExitCodePdfiumError)
+				return
+			}
+
+			if diffImages != "" && page.Score > diffMaxScore {
+				imageIndex++
+				values := pdf.OutputTemplateValues{
+					Input: pdf.OutputTemplateInputName(args[0]),
+					Page:  i + 1,
+					Index: imageIndex,
+					Ext:   "png",
+				}
+				if pdf.OutputTemplateHasToken(diffImages, "label") {
+					values.Label = getPageLabel(pdf.PdfiumInstance, documentA.Document, i)
+				}
+
+				imagePath, err := pdf.ExpandOutputTemplate(diffImages, values)
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not create diff image filename: %w\n", err), ExitCodeInvalidArguments)
+					return
+				}
+
+				imageFile, err := createOutputFile(imagePath)
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not create diff image %s: %w\n", imagePath, err), ExitCodeInvalidOutput)
+					return
+				}
+
+				err = png.Encode(imageFile, pixelDiff.Image)
+				if closeErr := imageFile.Close(); err == nil {
+					err = closeErr
+				}
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not write diff image into %s: %w\n", imagePath, err), ExitCodeInvalidOutput)
+					return
+				}
+				page.Image = imagePath
+			}
+
+			report.Pages = append(report.Pages, page)
+		}
+
+		report.Finish(diffMaxScore)
+
+		reportBuffer := &bytes.Buffer{}
+		if diffOutputType == "json" {
+			outputJson, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Fprintln(reportBuffer, string(outputJson))
+		} else {
+			writeDiffReport(reportBuffer, report)
+		}
+
+		if len(args) > 2 && args[2] != stdFilename {
+			if err := writeOutputFile(args[2], reportBuffer.Bytes()); err != nil {
+				handleError(cmd, fmt.Errorf("could not write report into %s: %w\n", args[2], err), ExitCodeInvalidOutput)
+				return
+			}
+		} else {
+			cmd.Print(reportBuffer.String())
+		}
+
+		if !report.Equal {
+			handleError(cmd, fmt.Errorf("%s and %s differ\n", args[0], args[1]), ExitCodeDiffFound)
+			return
+		}
+	},
+}
+
+func writeDiffReport(writer io.Writer, report pdf.DiffReport) {
+	fmt.Fprintf(writer, "Comparing %s with %s\n", report.A, report.B)
+
+	if report.PageCountA != report.PageCountB {
+		fmt.Fprintf(writer, "Page count: %d, %d\n", report.PageCountA, report.PageCountB)
+	} else {
+		fmt.Fprintf(writer, "Page count: %d\n", report.PageCountA)
+	}
+
+	for _, metadata := range report.Metadata {
+		fmt.Fprintf(writer, "Metadata %s: %q, %q\n", metadata.Tag, metadata.A, metadata.B)
+	}
+
+	for _, page := range report.Pages {
+		if page.MissingIn == "a" {
+			fmt.Fprintf(writer, "Page %d: only in %s\n", page.Page, report.B)
+			continue
+		}
+		if page.MissingIn == "b" {
+			fmt.Fprintf(writer, "Page %d: only in %s\n", page.Page, report.A)
+			continue
+		}
+
+		differences := []string{}
+		if page.DifferentPixels > 0 {
+			differences = append(differences, fmt.Sprintf("%d pixels (%.3f%%) differ", page.DifferentPixels, page.Score*100))
+		}
+		if page.TextDiff != "" {
+			differences = append(differences, "text differs")
+		}
+		if page.Image != "" {
+			differences = append(differences, "diff image "+page.Image)
+		}
+
+		if len(differences) == 0 {
+			fmt.Fprintf(writer, "Page %d: equal\n", page.Page)
+		} else {
+			fmt.Fprintf(writer, "Page %d: %s\n", page.Page, strings.Join(differences, ", "))
+		}
+
+		if page.TextDiff != "" {
+			fmt.Fprint(writer, page.TextDiff)
+		}
+	}
+
+	if report.Equal {
+		fmt.Fprintf(writer, "The PDFs are equal\n")
+	} else {
+		fmt.Fprintf(writer, "The PDFs differ\n")
+	}
+}
@@ -13,4 +13,9 @@ const (
 	ExitCodeInvalidOutput       = 10
 	ExitCodeInvalidPageRange    = 11
 	ExitCodeExperimental        = 12
+	ExitCodeBatchFailed         = 13
+	ExitCodeRepairContentLost   = 14
+	ExitCodeCheckFailed         = 15
+	ExitCodeCheckWarning        = 16
+	ExitCodeDiffFound           = 17
 )
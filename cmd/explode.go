@@ -1,9 +1,10 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"io"
-	"os"
+	"strconv"
 	"strings"
 
 	"github.com/klippa-app/pdfium-cli/pdf"
@@ -14,6 +15,7 @@ import (
 
 func init() {
 	addGenericPDFOptions(explodeCmd)
+	addArchiveOption(explodeCmd)
 	addPagesOption("The pages or page ranges to use in the explode", explodeCmd)
 
 	rootCmd.AddCommand(explodeCmd)
@@ -22,7 +24,7 @@ func init() {
 var explodeCmd = &cobra.Command{
 	Use:   "explode [input] [output]",
 	Short: "Explode a PDF into multiple PDFs",
-	Long:  "Explode a PDF into multiple PDFs.\n[input] can either be a file path or - for stdin.\n[output] can either be a file path or - for stdout. In the case of stdout, multiple files will be delimited by the value of the std-file-delimiter, with a newline before and after it. The output filename should contain a \"%d\" placeholder for the page number, e.g. split invoice.pdf invoice-%d.pdf, the result for a 2-page PDF will be invoice-1.pdf and invoice-2.pdf.",
+	Long:  "Explode a PDF into multiple PDFs.\n[input] can either be a file path or - for stdin.\n[output] can either be a file path or - for stdout. In the case of stdout, multiple files are written in the std-format, by default delimited by the value of the std-file-delimiter, with a newline before and after it. When the output ends with .zip, the files are written into a zip archive with a manifest.json. The output filename should contain a {page} (or \"%d\") or {index} placeholder, e.g. explode invoice.pdf invoice-{page}.pdf, the result for a 2-page PDF will be invoice-1.pdf and invoice-2.pdf. A {label} placeholder is not enough, because page labels don't have to be unique. Missing folders in the output path will be created. " + outputTemplateTokensHelp,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
 			return newExitCodeError(err, ExitCodeInvalidArguments)
@@ -32,8 +34,12 @@ var explodeCmd = &cobra.Command{
 			return fmt.Errorf("could not open input file %s: %w\n", args[0], newExitCodeError(err, ExitCodeInvalidOutput))
 		}
 
-		if args[1] != stdFilename && !strings.Contains(args[1], "%d") {
-			return newExitCodeError(fmt.Errorf("output string %s should contain page pattern %%d\n", args[1]), ExitCodeInvalidOutput)
+		if args[1] != stdFilename && !isOutputArchive(args[1]) && !pdf.OutputTemplateHasToken(args[1], pdf.OutputTemplatePageTokens...) {
+			return newExitCodeError(fmt.Errorf("output string %s should contain page pattern {page}, {index} or %%d\n", args[1]), ExitCodeInvalidOutput)
+		}
+
+		if err := validOutputTemplate(args[1]); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
 		}
 
 		return nil
@@ -74,6 +80,17 @@ var explodeCmd = &cobra.Command{
 
 		splitPages := strings.Split(*parsedPageRange, ",")
 
+		// The files on stdout and in archives are named by a fixed template.
+		outputTemplate := args[1]
+		outputArchive, err := newOutputArchive(cmd, args[1], args[0], pageCount.PageCount)
+		if err != nil {
+			handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidArguments)
+			return
+		}
+		if outputArchive != nil {
+			outputTemplate = "{input}-{page}.{ext}"
+		}
+
 		for i, page := range splitPages {
 			newDocument, err := pdf.PdfiumInstance.FPDF_CreateNewDocument(&requests.FPDF_CreateNewDocument{})
 			if err != nil {
@@ -96,19 +113,32 @@ var explodeCmd = &cobra.Command{
 				return
 			}
 
-			newFilePath := strings.Replace(args[1], "%d", page, -1)
+			pageInt, _ := strconv.Atoi(page)
+			templateValues := pdf.OutputTemplateValues{
+				Input: pdf.OutputTemplateInputName(args[0]),
+				Page:  pageInt,
+				Index: i + 1,
+				Ext:   "pdf",
+			}
+			if pdf.OutputTemplateHasToken(args[1], "label") {
+				templateValues.Label = getPageLabel(pdf.PdfiumInstance, document.Document, pageInt-1)
+			}
+
+			newFilePath, err := pdf.ExpandOutputTemplate(outputTemplate, templateValues)
+			if err != nil {
+				closeFunc()
+				handleError(cmd, fmt.Errorf("could not create output path for page %s: %w\n", page, err), ExitCodeInvalidArguments)
+				return
+			}
 
 			var fileWriter io.Writer
-			if args[1] == stdFilename {
-				if i > 0 {
-					os.Stdout.WriteString("\n")
-					os.Stdout.WriteString(stdFileDelimiter)
-					os.Stdout.WriteString("\n")
-				}
-				fileWriter = os.Stdout
+			archiveFileBuffer := &bytes.Buffer{}
+			if outputArchive != nil {
+				fileWriter = archiveFileBuffer
 			} else {
-				createdFile, err := os.Create(newFilePath)
+				createdFile, err := createOutputFile(newFilePath)
 				if err != nil {
+					closeFunc()
 					handleError(cmd, fmt.Errorf("could not save document for page %s: %w\n", page, err), ExitCodeInvalidOutput)
 					return
 				}
@@ -116,7 +146,7 @@ var explodeCmd = &cobra.Command{
 				originalCloseFunc := closeFunc
 				closeFunc = func() {
 					originalCloseFunc()
-					createdFile.Close()
+					closeOutputFile(cmd, createdFile, newFilePath)
 				}
 			}
 
@@ -132,9 +162,17 @@ var explodeCmd = &cobra.Command{
 
 			closeFunc()
 
-			if args[1] != stdFilename {
+			if outputArchive == nil {
 				cmd.Printf("Exploded page %s into %s\n", page, newFilePath)
+			} else {
+				err = outputArchive.WriteFile(newStdFile(newFilePath, i+1, pageInt), archiveFileBuffer.Bytes())
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not write document for page %s: %w\n", page, err), ExitCodeInvalidOutput)
+					return
+				}
 			}
 		}
+
+		outputArchive.finish(cmd)
 	},
 }
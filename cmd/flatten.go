@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -110,18 +109,16 @@ var flattenCmd = &cobra.Command{
 			closePageFunc()
 		}
 
-		var fileWriter io.Writer
-		if args[len(args)-1] == stdFilename {
-			fileWriter = os.Stdout
-		} else {
-			createdFile, err := os.Create(args[len(args)-1])
+		fileWriter := &outputWriter{Writer: os.Stdout}
+		if args[len(args)-1] != stdFilename {
+			createdFile, err := createOutput(args[len(args)-1])
 			if err != nil {
 				handleError(cmd, fmt.Errorf("could not save document: %w", err), ExitCodeInvalidOutput)
 				return
 			}
 
-			defer createdFile.Close()
-			fileWriter = createdFile
+			defer closeOutputFile(cmd, createdFile, args[len(args)-1])
+			fileWriter.Writer = createdFile
 		}
 
 		_, err = pdf.PdfiumInstance.FPDF_SaveAsCopy(&requests.FPDF_SaveAsCopy{
@@ -129,6 +126,10 @@ var flattenCmd = &cobra.Command{
 			FileWriter: fileWriter,
 		})
 		if err != nil {
+			if fileWriter.err != nil {
+				handleError(cmd, fmt.Errorf("could not save new document: %w\n", fileWriter.err), ExitCodeInvalidOutput)
+				return
+			}
 			handleError(cmd, fmt.Errorf("could not save new document: %w", newPdfiumError(err)), ExitCodePdfiumError)
 			return
 		}
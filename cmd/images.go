@@ -1,33 +1,51 @@
 package cmd
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"image"
 	"image/jpeg"
 	"image/png"
 	"io"
-	"os"
-	"path"
 	"strconv"
 	"strings"
 
 	"github.com/klippa-app/pdfium-cli/pdf"
 
 	"github.com/klippa-app/go-pdfium/enums"
+	"github.com/klippa-app/go-pdfium/references"
 	"github.com/klippa-app/go-pdfium/requests"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Used for flags.
-	jpegQuality int
+	jpegQuality            int
+	dedupeImages           bool
+	imagesMinWidth         int
+	imagesMinHeight        int
+	imagesMinDisplayWidth  float64
+	imagesMinDisplayHeight float64
+	imagesMaxPerPage       int
+	imagesBoundsOutput     string
 )
 
 func init() {
 	addGenericPDFOptions(imagesCmd)
+	addArchiveOption(imagesCmd)
 	addPagesOption("The pages or page to get images of", imagesCmd)
 	imagesCmd.Flags().StringVarP(&fileType, "file-type", "", "jpeg", "The file type to render in, jpeg or png")
 	imagesCmd.Flags().IntVarP(&jpegQuality, "jpeg-quality", "", 95, "Quality to use when file type is jpeg")
+	imagesCmd.Flags().BoolVarP(&dedupeImages, "dedupe", "", false, "Only export the first occurrence of identical images, like a logo that is repeated on every page. Images are identical when they are exported with the same pixels, so the same image data with a different mask, decode array or color space is exported again.")
+	imagesCmd.Flags().IntVarP(&imagesMinWidth, "min-width", "", 0, "The minimum width in pixels of the image, smaller images are skipped.")
+	imagesCmd.Flags().IntVarP(&imagesMinHeight, "min-height", "", 0, "The minimum height in pixels of the image, smaller images are skipped.")
+	imagesCmd.Flags().Float64VarP(&imagesMinDisplayWidth, "min-display-width", "", 0, "The minimum width in points (1/72 inch) that the image is displayed in on the page, smaller images are skipped.")
+	imagesCmd.Flags().Float64VarP(&imagesMinDisplayHeight, "min-display-height", "", 0, "The minimum height in points (1/72 inch) that the image is displayed in on the page, smaller images are skipped.")
+	imagesCmd.Flags().IntVarP(&imagesMaxPerPage, "max-per-page", "", 0, "The maximum amount of images to export per page, 0 for no maximum. Images are exported in the order they are drawn on the page.")
+	addOutputTemplateOption(imagesCmd, "page-{page}-image-{name}.{ext}")
+	imagesCmd.Flags().StringVarP(&imagesBoundsOutput, "bounds", "", "", "Write the rectangle of every exported image on the page (in points, with the origin in the bottom-left corner of the page) as JSON to the given file path, or to stdout with -.")
 
 	rootCmd.AddCommand(imagesCmd)
 }
@@ -35,7 +53,7 @@ func init() {
 var imagesCmd = &cobra.Command{
 	Use:   "images [input] [output-folder]",
 	Short: "Extract the images of a PDF",
-	Long:  "Extract the images of a PDF and store them as file.\n[input] can either be a file path or - for stdin.\n[output-folder] can be either a folder or - for stdout. In the case of stdout, multiple files will be delimited by the value of the std-file-delimiter, with a newline before and after it.",
+	Long:  "Extract the images of a PDF and store them as file.\n[input] can either be a file path or - for stdin.\n[output-folder] can be either a folder or - for stdout. The folder will be created when it doesn't exist. In the case of stdout, multiple files are written in the std-format, by default delimited by the value of the std-file-delimiter, with a newline before and after it. When the output ends with .zip, the files are written into a zip archive with a manifest.json.\nImages inside form XObjects are extracted as well, their name contains the position of every parent form object, e.g. image 3-2 is the second object inside the third object of the page.",
 	Args: func(cmd *cobra.Command, args []string) error {
 		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
 			return newExitCodeError(err, ExitCodeInvalidArguments)
@@ -45,15 +63,16 @@ var imagesCmd = &cobra.Command{
 			return fmt.Errorf("could not open input file %s: %w\n", args[0], newExitCodeError(err, ExitCodeInvalidInput))
 		}
 
-		if args[1] != stdFilename {
-			folderStat, err := os.Stat(args[1])
-			if err != nil {
-				return fmt.Errorf("could not open output folder %s: %w\n", args[1], newExitCodeError(err, ExitCodeInvalidOutput))
-			}
+		if err := validOutputFolder(args[1]); err != nil {
+			return err
+		}
 
-			if !folderStat.IsDir() {
-				return newExitCodeError(fmt.Errorf("output folder %s is not a folder\n", args[1]), ExitCodeInvalidOutput)
-			}
+		if err := validOutputTemplate(getOutputTemplate(cmd)); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
+		}
+
+		if imagesBoundsOutput == stdFilename && args[1] == stdFilename {
+			return newExitCodeError(fmt.Errorf("bounds can't be written to stdout when the images are written to stdout\n"), ExitCodeInvalidArguments)
 		}
 
 		return nil
@@ -92,6 +111,42 @@ var imagesCmd = &cobra.Command{
 			return
 		}
 
+		// Keeps track of the image data we have already exported when
+		// deduplicating, mapped to a description of the exported image.
+		seenImages := map[[sha256.Size]byte]string{}
+
+		type imageBounds struct {
+			Page        int
+			Image       string
+			File        string
+			Left        float64
+			Bottom      float64
+			Right       float64
+			Top         float64
+			Width       float64
+			Height      float64
+			PixelWidth  int
+			PixelHeight int
+		}
+
+		exportedBounds := []imageBounds{}
+
+		outputArchive, err := newOutputArchive(cmd, args[1], args[0], pageCount.PageCount)
+		if err != nil {
+			handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidArguments)
+			return
+		}
+
+		// The bounds on stdout have to stay valid JSON, so the progress is
+		// written to stderr then.
+		printProgress := cmd.Printf
+		if imagesBoundsOutput == stdFilename {
+			printProgress = cmd.PrintErrf
+			if outputArchive != nil {
+				outputArchive.reportToStderr = true
+			}
+		}
+
 		pages := strings.Split(*parsedPageRange, ",")
 		imageCount := 0
 		for _, page := range pages {
@@ -112,206 +167,463 @@ var imagesCmd = &cobra.Command{
 				})
 			}
 
-			objectCount, err := pdf.PdfiumInstance.FPDFPage_CountObjects(&requests.FPDFPage_CountObjects{
-				Page: requests.Page{
-					ByReference: &page.Page,
-				},
-			})
-
+			imageObjects, err := collectImageObjects(page.Page)
 			if err != nil {
 				closePageFunc()
-				handleError(cmd, fmt.Errorf("could not get object count for page %d for PDF %s: %w\n", pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+				handleError(cmd, fmt.Errorf("could not get image objects for page %d for PDF %s: %w\n", pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
 				return
 			}
 
-			for i := 0; i < objectCount.Count; i++ {
-				object, err := pdf.PdfiumInstance.FPDFPage_GetObject(&requests.FPDFPage_GetObject{
+			pageImageCount := 0
+			for _, imageObject := range imageObjects {
+				objectName := imageObject.name()
+
+				if imagesMaxPerPage > 0 && pageImageCount >= imagesMaxPerPage {
+					break
+				}
+
+				pixelSize, err := pdf.PdfiumInstance.FPDFImageObj_GetImagePixelSize(&requests.FPDFImageObj_GetImagePixelSize{
+					ImageObject: imageObject.object,
+				})
+				if err != nil {
+					closePageFunc()
+					handleError(cmd, fmt.Errorf("could not get image size for object %s for page %d for PDF %s: %w\n", objectName, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+					return
+				}
+
+				if int(pixelSize.Width) < imagesMinWidth || int(pixelSize.Height) < imagesMinHeight {
+					continue
+				}
+
+				objectBounds, err := pdf.PdfiumInstance.FPDFPageObj_GetBounds(&requests.FPDFPageObj_GetBounds{
+					PageObject: imageObject.object,
+				})
+				if err != nil {
+					closePageFunc()
+					handleError(cmd, fmt.Errorf("could not get image bounds for object %s for page %d for PDF %s: %w\n", objectName, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+					return
+				}
+
+				// Objects inside form objects have their bounds in the
+				// coordinate space of the form, transform them to the page.
+				pageBounds := imageObject.matrix.TransformRect(pdf.Rect{
+					Left:   float64(objectBounds.Left),
+					Bottom: float64(objectBounds.Bottom),
+					Right:  float64(objectBounds.Right),
+					Top:    float64(objectBounds.Top),
+				})
+
+				if pageBounds.Width() < imagesMinDisplayWidth || pageBounds.Height() < imagesMinDisplayHeight {
+					continue
+				}
+
+				imageBitmap, err := pdf.PdfiumInstance.FPDFImageObj_GetRenderedBitmap(&requests.FPDFImageObj_GetRenderedBitmap{
+					Document: document.Document,
 					Page: requests.Page{
 						ByReference: &page.Page,
 					},
-					Index: i,
+					ImageObject: imageObject.object,
+				})
+
+				if err != nil {
+					closePageFunc()
+					handleError(cmd, fmt.Errorf("could not get image for object %s for page %d for PDF %s: %w\n", objectName, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+					return
+				}
+
+				closeBitmapFunc := func() {
+					pdf.PdfiumInstance.FPDFBitmap_Destroy(&requests.FPDFBitmap_Destroy{
+						Bitmap: imageBitmap.Bitmap,
+					})
+				}
+
+				stride, err := pdf.PdfiumInstance.FPDFBitmap_GetStride(&requests.FPDFBitmap_GetStride{
+					Bitmap: imageBitmap.Bitmap,
 				})
 
 				if err != nil {
 					closePageFunc()
-					handleError(cmd, fmt.Errorf("could not get object %d for page %d for PDF %s: %w\n", i, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+					closeBitmapFunc()
+					handleError(cmd, fmt.Errorf("could not get image stride for object %s for page %d for PDF %s: %w\n", objectName, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
 					return
 				}
 
-				objectType, err := pdf.PdfiumInstance.FPDFPageObj_GetType(&requests.FPDFPageObj_GetType{
-					PageObject: object.PageObject,
+				width, err := pdf.PdfiumInstance.FPDFBitmap_GetWidth(&requests.FPDFBitmap_GetWidth{
+					Bitmap: imageBitmap.Bitmap,
 				})
 
 				if err != nil {
 					closePageFunc()
-					handleError(cmd, fmt.Errorf("could not get object type for object %d for page %d for PDF %s: %w\n", i, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+					closeBitmapFunc()
+					handleError(cmd, fmt.Errorf("could not get image width for object %s for page %d for PDF %s: %w\n", objectName, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
 					return
 				}
 
-				if objectType.Type == enums.FPDF_PAGEOBJ_IMAGE {
-					imageBitmap, err := pdf.PdfiumInstance.FPDFImageObj_GetRenderedBitmap(&requests.FPDFImageObj_GetRenderedBitmap{
-						Document: document.Document,
-						Page: requests.Page{
-							ByReference: &page.Page,
-						},
-						ImageObject: object.PageObject,
-					})
+				height, err := pdf.PdfiumInstance.FPDFBitmap_GetHeight(&requests.FPDFBitmap_GetHeight{
+					Bitmap: imageBitmap.Bitmap,
+				})
 
-					if err != nil {
-						closePageFunc()
-						handleError(cmd, fmt.Errorf("could not get image for object %d for page %d for PDF %s: %w\n", i, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
-						return
-					}
+				if err != nil {
+					closePageFunc()
+					closeBitmapFunc()
+					handleError(cmd, fmt.Errorf("could not get image height for object %s for page %d for PDF %s: %w\n", objectName, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+					return
+				}
 
-					closeBitmapFunc := func() {
-						pdf.PdfiumInstance.FPDFBitmap_Destroy(&requests.FPDFBitmap_Destroy{
-							Bitmap: imageBitmap.Bitmap,
-						})
-					}
+				format, err := pdf.PdfiumInstance.FPDFBitmap_GetFormat(&requests.FPDFBitmap_GetFormat{
+					Bitmap: imageBitmap.Bitmap,
+				})
 
-					stride, err := pdf.PdfiumInstance.FPDFBitmap_GetStride(&requests.FPDFBitmap_GetStride{
-						Bitmap: imageBitmap.Bitmap,
-					})
+				if err != nil {
+					closePageFunc()
+					closeBitmapFunc()
+					handleError(cmd, fmt.Errorf("could not get image format for object %s for page %d for PDF %s: %w\n", objectName, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+					return
+				}
 
-					if err != nil {
-						closePageFunc()
+				buffer, err := pdf.PdfiumInstance.FPDFBitmap_GetBuffer(&requests.FPDFBitmap_GetBuffer{
+					Bitmap: imageBitmap.Bitmap,
+				})
+
+				if err != nil {
+					closePageFunc()
+					closeBitmapFunc()
+					handleError(cmd, fmt.Errorf("could not get image buffer for object %s for page %d for PDF %s: %w\n", objectName, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+					return
+				}
+
+				var img image.Image
+				if format.Format == enums.FPDF_BITMAP_FORMAT_BGRA {
+					img = &BGRA{
+						Pix:    buffer.Buffer,
+						Stride: stride.Stride,
+						Rect:   image.Rect(0, 0, width.Width, height.Height),
+					}
+				} else if format.Format == enums.FPDF_BITMAP_FORMAT_BGRX {
+					img = &BGRX{
+						Pix:    buffer.Buffer,
+						Stride: stride.Stride,
+						Rect:   image.Rect(0, 0, width.Width, height.Height),
+					}
+				} else if format.Format == enums.FPDF_BITMAP_FORMAT_BGR {
+					img = &BGR{
+						Pix:    buffer.Buffer,
+						Stride: stride.Stride,
+						Rect:   image.Rect(0, 0, width.Width, height.Height),
+					}
+				} else if format.Format == enums.FPDF_BITMAP_FORMAT_GRAY {
+					img = &image.Gray{
+						Pix:    buffer.Buffer,
+						Stride: stride.Stride,
+						Rect:   image.Rect(0, 0, width.Width, height.Height),
+					}
+				}
+
+				if dedupeImages {
+					imageHash := hashImageBitmap(format.Format, width.Width, height.Height, stride.Stride, buffer.Buffer)
+					if seenImage, ok := seenImages[imageHash]; ok {
 						closeBitmapFunc()
-						handleError(cmd, fmt.Errorf("could not get image stride for object %d for page %d for PDF %s: %w\n", i, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
-						return
+						if args[1] != stdFilename {
+							printProgress("Skipped image %s from page %d, it is identical to %s\n", objectName, pageInt, seenImage)
+						}
+						continue
 					}
 
-					width, err := pdf.PdfiumInstance.FPDFBitmap_GetWidth(&requests.FPDFBitmap_GetWidth{
-						Bitmap: imageBitmap.Bitmap,
-					})
+					seenImages[imageHash] = fmt.Sprintf("image %s from page %d", objectName, pageInt)
+				}
+
+				ext := "jpg"
+				if fileType == "png" {
+					ext = "png"
+				}
 
+				templateValues := pdf.OutputTemplateValues{
+					Input: pdf.OutputTemplateInputName(args[0]),
+					Page:  pageInt,
+					Index: imageCount + 1,
+					Name:  objectName,
+					Ext:   ext,
+				}
+				if pdf.OutputTemplateHasToken(getOutputTemplate(cmd), "label") {
+					templateValues.Label = getPageLabel(pdf.PdfiumInstance, document.Document, pageInt-1)
+				}
+
+				fileName, err := pdf.ExpandOutputTemplate(getOutputTemplate(cmd), templateValues)
+				if err != nil {
+					closePageFunc()
+					closeBitmapFunc()
+					handleError(cmd, fmt.Errorf("could not create output path for object %s for page %d for PDF %s: %w\n", objectName, pageInt, args[0], err), ExitCodeInvalidArguments)
+					return
+				}
+
+				filePath := joinOutputPath(args[1], fileName)
+
+				closeFunc := func() {}
+				var outWriter io.Writer
+				archiveFileBuffer := &bytes.Buffer{}
+				if outputArchive == nil {
+					outFile, err := createOutputFile(filePath)
 					if err != nil {
 						closePageFunc()
 						closeBitmapFunc()
-						handleError(cmd, fmt.Errorf("could not get image width for object %d for page %d for PDF %s: %w\n", i, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+						handleError(cmd, fmt.Errorf("could not create output file for object %s for page %d for PDF %s: %w\n", objectName, pageInt, args[0], err), ExitCodeInvalidOutput)
 						return
 					}
+					outWriter = outFile
+					closeFunc = func() {
+						closeOutputFile(cmd, outFile, filePath)
+					}
+				} else {
+					outWriter = archiveFileBuffer
+				}
 
-					height, err := pdf.PdfiumInstance.FPDFBitmap_GetHeight(&requests.FPDFBitmap_GetHeight{
-						Bitmap: imageBitmap.Bitmap,
-					})
-
+				if fileType == "png" {
+					err = png.Encode(outWriter, img)
 					if err != nil {
 						closePageFunc()
 						closeBitmapFunc()
-						handleError(cmd, fmt.Errorf("could not get image height for object %d for page %d for PDF %s: %w\n", i, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+						closeFunc()
 						return
 					}
+				} else {
+					var opt jpeg.Options
+					opt.Quality = jpegQuality
 
-					format, err := pdf.PdfiumInstance.FPDFBitmap_GetFormat(&requests.FPDFBitmap_GetFormat{
-						Bitmap: imageBitmap.Bitmap,
-					})
-
+					err = jpeg.Encode(outWriter, img, &opt)
 					if err != nil {
 						closePageFunc()
 						closeBitmapFunc()
-						handleError(cmd, fmt.Errorf("could not get image format for object %d for page %d for PDF %s: %w\n", i, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+						closeFunc()
 						return
 					}
+				}
 
-					buffer, err := pdf.PdfiumInstance.FPDFBitmap_GetBuffer(&requests.FPDFBitmap_GetBuffer{
-						Bitmap: imageBitmap.Bitmap,
-					})
+				closeFunc()
+				closeBitmapFunc()
 
+				if outputArchive == nil {
+					printProgress("Exported image %s from page %d into %s\n", objectName, pageInt, filePath)
+				} else {
+					err = outputArchive.WriteFile(newStdFile(fileName, imageCount+1, pageInt), archiveFileBuffer.Bytes())
 					if err != nil {
 						closePageFunc()
-						closeBitmapFunc()
-						handleError(cmd, fmt.Errorf("could not get image buffer for object %d for page %d for PDF %s: %w\n", i, pageInt, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+						handleError(cmd, fmt.Errorf("could not write image %s for page %d for PDF %s: %w\n", objectName, pageInt, args[0], err), ExitCodeInvalidOutput)
 						return
 					}
-
-					var img image.Image
-					if format.Format == enums.FPDF_BITMAP_FORMAT_BGRA {
-						img = &BGRA{
-							Pix:    buffer.Buffer,
-							Stride: stride.Stride,
-							Rect:   image.Rect(0, 0, width.Width, height.Height),
-						}
-					} else if format.Format == enums.FPDF_BITMAP_FORMAT_BGRX {
-						img = &BGRX{
-							Pix:    buffer.Buffer,
-							Stride: stride.Stride,
-							Rect:   image.Rect(0, 0, width.Width, height.Height),
-						}
-					} else if format.Format == enums.FPDF_BITMAP_FORMAT_BGR {
-						img = &BGR{
-							Pix:    buffer.Buffer,
-							Stride: stride.Stride,
-							Rect:   image.Rect(0, 0, width.Width, height.Height),
-						}
-					} else if format.Format == enums.FPDF_BITMAP_FORMAT_GRAY {
-						img = &image.Gray{
-							Pix:    buffer.Buffer,
-							Stride: stride.Stride,
-							Rect:   image.Rect(0, 0, width.Width, height.Height),
-						}
-					}
-
-					ext := "jpg"
-					if fileType == "png" {
-						ext = "png"
+					filePath = fileName
+					if args[1] == stdFilename {
+						filePath = ""
 					}
+				}
 
-					filePath := path.Join(args[1], fmt.Sprintf("page-%d-image-%d.%s", pageInt, i+1, ext))
-
-					closeFunc := func() {}
-					var outWriter io.Writer
-					if args[1] != stdFilename {
-						outFile, err := os.Create(filePath)
-						if err != nil {
-							closePageFunc()
-							closeBitmapFunc()
-							handleError(cmd, fmt.Errorf("could not create output file for object %d for page %d for PDF %s: %w\n", i, pageInt, args[0], err), ExitCodeInvalidOutput)
-							return
-						}
-						outWriter = outFile
-						closeFunc = func() {
-							outFile.Close()
-						}
-					} else {
-						if imageCount > 0 {
-							os.Stdout.WriteString("\n")
-							os.Stdout.WriteString(stdFileDelimiter)
-							os.Stdout.WriteString("\n")
-						}
-						outWriter = os.Stdout
-					}
+				exportedBounds = append(exportedBounds, imageBounds{
+					Page:        pageInt,
+					Image:       objectName,
+					File:        filePath,
+					Left:        pageBounds.Left,
+					Bottom:      pageBounds.Bottom,
+					Right:       pageBounds.Right,
+					Top:         pageBounds.Top,
+					Width:       pageBounds.Width(),
+					Height:      pageBounds.Height(),
+					PixelWidth:  int(pixelSize.Width),
+					PixelHeight: int(pixelSize.Height),
+				})
 
-					if fileType == "png" {
-						err = png.Encode(outWriter, img)
-						if err != nil {
-							closePageFunc()
-							closeBitmapFunc()
-							closeFunc()
-							return
-						}
-					} else {
-						var opt jpeg.Options
-						opt.Quality = jpegQuality
-
-						err = jpeg.Encode(outWriter, img, &opt)
-						if err != nil {
-							closePageFunc()
-							closeBitmapFunc()
-							closeFunc()
-							return
-						}
-					}
+				imageCount++
+				pageImageCount++
+			}
 
-					closeFunc()
-					closeBitmapFunc()
+			closePageFunc()
+		}
 
-					if args[1] != stdFilename {
-						cmd.Printf("Exported image %d from page %d into %s\n", i+1, pageInt, filePath)
-					}
+		outputArchive.finish(cmd)
 
-					imageCount++
+		if imagesBoundsOutput != "" {
+			outputJson, _ := json.MarshalIndent(exportedBounds, "", "  ")
+			if imagesBoundsOutput == stdFilename {
+				cmd.Println(string(outputJson))
+			} else {
+				err = writeOutputFile(imagesBoundsOutput, append(outputJson, '\n'))
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not write bounds to %s: %w\n", imagesBoundsOutput, err), ExitCodeInvalidOutput)
+					return
 				}
 			}
 		}
 	},
 }
+
+// pageImageObject is an image object on a page, together with its position in
+// the object tree of the page.
+type pageImageObject struct {
+	object references.FPDF_PAGEOBJECT
+
+	// The matrix to transform coordinates of the object into page
+	// coordinates, this is the combined matrix of all the parent form objects.
+	matrix pdf.Matrix
+
+	// The 0-based index of the object for every level in the object tree,
+	// the first index is the index of the object on the page, the following
+	// indexes are the indexes within form objects.
+	indexes []int
+}
+
+// name returns a name that is stable and unique within the page, like 3 for
+// the third object of the page or 3-2 for the second object inside the third
+// object of the page.
+func (o pageImageObject) name() string {
+	parts := make([]string, len(o.indexes))
+	for i := range o.indexes {
+		parts[i] = strconv.Itoa(o.indexes[i] + 1)
+	}
+	return strings.Join(parts, "-")
+}
+
+// collectImageObjects returns all image objects of a page, including the image
+// objects inside form XObjects. Inline images are image objects as well in
+// pdfium, so they are included too.
+func collectImageObjects(page references.FPDF_PAGE) ([]pageImageObject, error) {
+	objectCount, err := pdf.PdfiumInstance.FPDFPage_CountObjects(&requests.FPDFPage_CountObjects{
+		Page: requests.Page{
+			ByReference: &page,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	imageObjects := []pageImageObject{}
+	for i := 0; i < objectCount.Count; i++ {
+		object, err := pdf.PdfiumInstance.FPDFPage_GetObject(&requests.FPDFPage_GetObject{
+			Page: requests.Page{
+				ByReference: &page,
+			},
+			Index: i,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		imageObjects, err = appendImageObjects(imageObjects, object.PageObject, pdf.IdentityMatrix, []int{i})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return imageObjects, nil
+}
+
+// appendImageObjects appends the given object when it's an image and descends
+// into form XObjects recursively.
+func appendImageObjects(imageObjects []pageImageObject, object references.FPDF_PAGEOBJECT, matrix pdf.Matrix, indexes []int) ([]pageImageObject, error) {
+	objectType, err := pdf.PdfiumInstance.FPDFPageObj_GetType(&requests.FPDFPageObj_GetType{
+		PageObject: object,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch objectType.Type {
+	case enums.FPDF_PAGEOBJ_IMAGE:
+		imageObjects = append(imageObjects, pageImageObject{
+			object:  object,
+			matrix:  matrix,
+			indexes: indexes,
+		})
+	case enums.FPDF_PAGEOBJ_FORM:
+		formMatrix, err := pdf.PdfiumInstance.FPDFPageObj_GetMatrix(&requests.FPDFPageObj_GetMatrix{
+			PageObject: object,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// The children are placed in the form, which is placed on its parent.
+		childMatrix := pdf.NewMatrix(formMatrix.Matrix).Multiply(matrix)
+
+		formObjectCount, err := pdf.PdfiumInstance.FPDFFormObj_CountObjects(&requests.FPDFFormObj_CountObjects{
+			PageObject: object,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 0; i < formObjectCount.Count; i++ {
+			childObject, err := pdf.PdfiumInstance.FPDFFormObj_GetObject(&requests.FPDFFormObj_GetObject{
+				PageObject: object,
+				Index:      uint64(i),
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			// Copy the indexes so that siblings don't share the same backing array.
+			childIndexes := append(append([]int{}, indexes...), i)
+			imageObjects, err = appendImageObjects(imageObjects, childObject.PageObject, childMatrix, childIndexes)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return imageObjects, nil
+}
+
+// hashImageBitmap returns a hash of the pixels of the rendered bitmap of an
+// image object, which is what is exported. The soft mask, the decode array and
+// the color space are applied in the bitmap, so images with the same image
+// data that look different don't result in the same hash.
+func hashImageBitmap(format enums.FPDF_BITMAP_FORMAT, width int, height int, stride int, buffer []byte) [sha256.Size]byte {
+	bytesPerPixel := 4
+	if format == enums.FPDF_BITMAP_FORMAT_BGR {
+		bytesPerPixel = 3
+	} else if format == enums.FPDF_BITMAP_FORMAT_GRAY {
+		bytesPerPixel = 1
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d:%dx%d:", format, width, height)
+
+	// Only hash the pixels, the padding at the end of the rows is not set.
+	for y := 0; y < height; y++ {
+		hash.Write(buffer[y*stride : y*stride+width*bytesPerPixel])
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], hash.Sum(nil))
+	return sum
+}
+
+// hashImageObject returns a hash of the raw (undecoded) image data of an image
+// object. Images that are shared between pages or form objects point to the
+// same image stream, so they result in the same hash.
+func hashImageObject(object references.FPDF_PAGEOBJECT) ([sha256.Size]byte, error) {
+	rawData, err := pdf.PdfiumInstance.FPDFImageObj_GetImageDataRaw(&requests.FPDFImageObj_GetImageDataRaw{
+		ImageObject: object,
+	})
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	pixelSize, err := pdf.PdfiumInstance.FPDFImageObj_GetImagePixelSize(&requests.FPDFImageObj_GetImagePixelSize{
+		ImageObject: object,
+	})
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%dx%d:", pixelSize.Width, pixelSize.Height)
+	if len(rawData.Data) > 0 {
+		hash.Write(rawData.Data)
+	} else {
+		// Without image data we can't tell whether images are identical,
+		// use the object reference so that it never matches another image.
+		hash.Write([]byte(object))
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], hash.Sum(nil))
+	return sum, nil
+}
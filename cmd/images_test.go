@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImagesBoundsToStdout(t *testing.T) {
+	folder := t.TempDir()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 20, 10))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.Set(5, 5, color.NRGBA{R: 255, A: 255})
+
+	var imageFile bytes.Buffer
+	if err := png.Encode(&imageFile, img); err != nil {
+		t.Fatal(err)
+	}
+
+	imagePath := filepath.Join(folder, "image.png")
+	if err := os.WriteFile(imagePath, imageFile.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inputPath := filepath.Join(folder, "input.pdf")
+	if exitCode, err := runCommandInProcess([]string{"img2pdf", imagePath, imagePath, inputPath}); exitCode != 0 {
+		t.Fatalf("could not create input PDF: %d, %v", exitCode, err)
+	}
+
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{"test folder output", filepath.Join(folder, "images")},
+		{"test zip output", filepath.Join(folder, "images.zip")},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			rootCmd.SetOut(&stdout)
+			rootCmd.SetErr(&stderr)
+			defer rootCmd.SetOut(nil)
+			defer rootCmd.SetErr(nil)
+
+			exitCode, err := runCommandInProcess([]string{"images", inputPath, tests[i].output, "--bounds", "-"})
+			if exitCode != 0 {
+				t.Fatalf("expected exit code 0 but got %d, %v", exitCode, err)
+			}
+
+			bounds := []struct {
+				Page int
+				File string
+			}{}
+			if err := json.Unmarshal(stdout.Bytes(), &bounds); err != nil {
+				t.Fatalf("expected the bounds as JSON on stdout but got %q: %v", stdout.String(), err)
+			}
+			if len(bounds) != 2 || bounds[0].Page != 1 || bounds[1].Page != 2 {
+				t.Errorf("expected the bounds of 2 images but got %+v", bounds)
+			}
+			if stderr.Len() == 0 {
+				t.Errorf("expected the progress on stderr")
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/spf13/cobra"
+)
+
+var (
+	img2pdfPageSize string
+	img2pdfMargin   float64
+	img2pdfDPI      int
+)
+
+func init() {
+	img2pdfCmd.Flags().StringVarP(&stdFileDelimiter, "std-file-delimiter", "", "--pdfium-cli-file-boundary", "The delimiter to use when having multiple files in your input.")
+	img2pdfCmd.Flags().StringVarP(&stdFormat, "std-format", "", string(pdf.StdFormatDelimiter), "The format of multiple images on stdin: delimiter (separated by the std-file-delimiter), tar, zip or ndjson-base64.")
+	img2pdfCmd.Flags().StringVarP(&img2pdfPageSize, "page-size", "", string(pdf.ImagePageSizeFit), "The size of the pages, fit (the size of the image with the margins), a4 or letter. Landscape images get landscape a4 and letter pages, images that don't fit within the margins are scaled down and images are centered on the page.")
+	img2pdfCmd.Flags().Float64VarP(&img2pdfMargin, "margin", "", 0, "The margin around the images in points (1/72 inch).")
+	img2pdfCmd.Flags().IntVarP(&img2pdfDPI, "dpi", "", 0, "The resolution of the images, which sets their size on the page. By default the resolution in the image file is used, or 72 DPI when it has none.")
+	rootCmd.AddCommand(img2pdfCmd)
+}
+
+var img2pdfCmd = &cobra.Command{
+	Use:   "img2pdf [input] ([input]...) [output]",
+	Short: "Create a PDF from images",
+	Long:  "Create a PDF from JPEG, PNG and TIFF images, with one page per image. JPEG images are embedded as they are, without encoding them again, PNG and TIFF images are decoded and compressed losslessly by pdfium, of TIFF images only the first page is used. The EXIF orientation of JPEG and TIFF images is applied, so photos are shown the right way up.\nAn [input] can either be a file path or - for stdin, - reads all images from stdin, in the std-format.\n[output] can either be a file path or - for stdout.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.MinimumNArgs(2)(cmd, args); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
+		}
+
+		for _, input := range args[:len(args)-1] {
+			if err := validFile(input); err != nil {
+				return fmt.Errorf("could not open input file %s: %w\n", input, newExitCodeError(err, ExitCodeInvalidInput))
+			}
+		}
+
+		pageSize := pdf.ImagePageSize(img2pdfPageSize)
+		if pageSize != pdf.ImagePageSizeFit && pageSize != pdf.ImagePageSizeA4 && pageSize != pdf.ImagePageSizeLetter {
+			return newExitCodeError(fmt.Errorf("invalid page size: %s\n", img2pdfPageSize), ExitCodeInvalidArguments)
+		}
+
+		if img2pdfMargin < 0 {
+			return newExitCodeError(fmt.Errorf("invalid margin %g, should be at least 0\n", img2pdfMargin), ExitCodeInvalidArguments)
+		}
+
+		if img2pdfDPI < 0 {
+			return newExitCodeError(fmt.Errorf("invalid DPI %d, should be at least 0\n", img2pdfDPI), ExitCodeInvalidArguments)
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		pageSize := pdf.ImagePageSize(img2pdfPageSize)
+
+		err := pdf.LoadPdfium()
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not load pdfium: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+		defer pdf.ClosePdfium()
+
+		newDocument, err := pdf.PdfiumInstance.FPDF_CreateNewDocument(&requests.FPDF_CreateNewDocument{})
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not create new document: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+		defer pdf.PdfiumInstance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{Document: newDocument.Document})
+
+		pageCount := 0
+		addImage := func(filename string, data []byte) {
+			info, err := pdf.ReadImageInfo(data)
+			if err != nil {
+				handleError(cmd, fmt.Errorf("could not read image %s: %w\n", filename, err), ExitCodeInvalidInput)
+				return
+			}
+
+			width, height := info.SizeInPoints(float64(img2pdfDPI))
+			pageWidth, pageHeight, rect, err := pdf.PlaceImage(pageSize, width, height, img2pdfMargin)
+			if err != nil {
+				handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidArguments)
+				return
+			}
+
+			err = addImagePage(newDocument.Document, pageCount, data, info, pageWidth, pageHeight, rect)
+			if err != nil {
+				handleError(cmd, fmt.Errorf("could not add image %s: %w\n", filename, newPdfiumError(err)), ExitCodePdfiumError)
+				return
+			}
+
+			pageCount++
+		}
+
+		for _, input := range args[:len(args)-1] {
+			if input != stdFilename {
+				data, err := readInputFile(input)
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not read input file %s: %w\n", input, err), ExitCodeInvalidInput)
+					return
+				}
+				addImage(input, data)
+				continue
+			}
+
+			for {
+				stdinDocument, err := nextStdinDocument()
+				if errors.Is(err, stdinNoMoreFiles) {
+					break
+				}
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not read input file %s: %w\n", input, err), ExitCodeInvalidInput)
+					return
+				}
+
+				data, err := readInputFile(stdFilename)
+				removeStdinDocument(stdinDocument)
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not read input file %s: %w\n", input, err), ExitCodeInvalidInput)
+					return
+				}
+
+				name := stdinDocument.Name()
+				if name == "" {
+					name = fmt.Sprintf("%d on stdin", pageCount+1)
+				}
+				addImage(name, data)
+			}
+		}
+
+		// Stdin can be empty, a PDF without pages can't be opened.
+		if pageCount == 0 {
+			handleError(cmd, fmt.Errorf("could not create PDF: no images found in the input\n"), ExitCodeInvalidInput)
+			return
+		}
+
+		fileWriter := &outputWriter{Writer: os.Stdout}
+		if args[len(args)-1] != stdFilename {
+			createdFile, err := createOutput(args[len(args)-1])
+			if err != nil {
+				handleError(cmd, fmt.Errorf("could not save document: %w\n", err), ExitCodeInvalidOutput)
+				return
+			}
+
+			defer closeOutputFile(cmd, createdFile, args[len(args)-1])
+			fileWriter.Writer = createdFile
+		}
+
+		_, err = pdf.PdfiumInstance.FPDF_SaveAsCopy(&requests.FPDF_SaveAsCopy{
+			Document:   newDocument.Document,
+			FileWriter: fileWriter,
+		})
+		if err != nil {
+			if fileWriter.err != nil {
+				handleError(cmd, fmt.Errorf("could not save new document: %w\n", fileWriter.err), ExitCodeInvalidOutput)
+				return
+			}
+			handleError(cmd, fmt.Errorf("could not save new document: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+
+		if args[len(args)-1] != stdFilename {
+			cmd.Printf("Created %s from %d images\n", args[len(args)-1], pageCount)
+		}
+	},
+}
+
+// readInputFile reads the whole input file, for stdin this is the last
+// document that was read.
+func readInputFile(filename string) ([]byte, error) {
+	file, _, err := openInputFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// addImagePage adds a page of the given size with the image in the
+// rectangle, turned by its orientation.
+func addImagePage(document references.FPDF_DOCUMENT, pageIndex int, data []byte, info pdf.ImageInfo, pageWidth float64, pageHeight float64, rect pdf.Rect) error {
+	newPage, err := pdf.PdfiumInstance.FPDFPage_New(&requests.FPDFPage_New{
+		Document:  document,
+		PageIndex: pageIndex,
+		Width:     pageWidth,
+		Height:    pageHeight,
+	})
+	if err != nil {
+		return err
+	}
+	defer pdf.PdfiumInstance.FPDF_ClosePage(&requests.FPDF_ClosePage{Page: newPage.Page})
+
+	imageObject, err := pdf.PdfiumInstance.FPDFPageObj_NewImageObj(&requests.FPDFPageObj_NewImageObj{
+		Document: document,
+	})
+	if err != nil {
+		return err
+	}
+
+	if info.Format == "jpeg" {
+		// The pages are not passed, pdfium only uses them to clear their
+		// cache and the WebAssembly version can't handle them.
+		_, err = pdf.PdfiumInstance.FPDFImageObj_LoadJpegFileInline(&requests.FPDFImageObj_LoadJpegFileInline{
+			ImageObject: imageObject.PageObject,
+			FileData:    data,
+		})
+	} else {
+		err = setImageObjectPixels(imageObject.PageObject, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	// An image fills the unit square, turn it and scale it onto the
+	// rectangle.
+	matrix := pdf.ImageOrientationMatrix(info.Orientation).Multiply(pdf.Matrix{
+		A: rect.Width(),
+		D: rect.Height(),
+		E: rect.Left,
+		F: rect.Bottom,
+	})
+	_, err = pdf.PdfiumInstance.FPDFImageObj_SetMatrix(&requests.FPDFImageObj_SetMatrix{
+		ImageObject: imageObject.PageObject,
+		Transform:   matrix.FSMatrix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = pdf.PdfiumInstance.FPDFPage_InsertObject(&requests.FPDFPage_InsertObject{
+		Page: requests.Page{
+			ByReference: &newPage.Page,
+		},
+		PageObject: imageObject.PageObject,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = pdf.PdfiumInstance.FPDFPage_GenerateContent(&requests.FPDFPage_GenerateContent{
+		Page: requests.Page{
+			ByReference: &newPage.Page,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// setImageObjectPixels decodes an image and sets its pixels on the image
+// object. Images with transparency keep their alpha channel, which pdfium
+// stores as a mask.
+func setImageObjectPixels(imageObject references.FPDF_PAGEOBJECT, data []byte) error {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	alpha := 1
+	if opaqueImage, ok := img.(interface{ Opaque() bool }); ok && opaqueImage.Opaque() {
+		alpha = 0
+	}
+
+	// The bitmap is created by pdfium, because the WebAssembly version can't
+	// use a buffer of Go.
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+	bitmap, err := pdf.PdfiumInstance.FPDFBitmap_Create(&requests.FPDFBitmap_Create{
+		Width:  width,
+		Height: height,
+		Alpha:  alpha,
+	})
+	if err != nil {
+		return err
+	}
+	defer pdf.PdfiumInstance.FPDFBitmap_Destroy(&requests.FPDFBitmap_Destroy{
+		Bitmap: bitmap.Bitmap,
+	})
+
+	stride, err := pdf.PdfiumInstance.FPDFBitmap_GetStride(&requests.FPDFBitmap_GetStride{
+		Bitmap: bitmap.Bitmap,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The buffer is the memory of the bitmap, so we can write the pixels
+	// into it.
+	buffer, err := pdf.PdfiumInstance.FPDFBitmap_GetBuffer(&requests.FPDFBitmap_GetBuffer{
+		Bitmap: bitmap.Bitmap,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(buffer.Buffer) < stride.Stride*(height-1)+width*4 {
+		return errors.New("bitmap buffer is too small")
+	}
+
+	// pdfium wants BGRA, the common image types are converted directly
+	// from their pixels, because converting every pixel through the color
+	// model is slow for large images.
+	for y := 0; y < height; y++ {
+		row := buffer.Buffer[y*stride.Stride:]
+		switch img := img.(type) {
+		case *image.NRGBA:
+			pixels := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			for x := 0; x < width; x++ {
+				row[x*4] = pixels[x*4+2]
+				row[x*4+1] = pixels[x*4+1]
+				row[x*4+2] = pixels[x*4]
+				row[x*4+3] = pixels[x*4+3]
+			}
+		case *image.RGBA:
+			// The colors are premultiplied with the alpha, they are divided
+			// by it in 16 bits like color.NRGBAModel does.
+			pixels := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			for x := 0; x < width; x++ {
+				a := uint32(pixels[x*4+3]) * 0x101
+				for c := 0; c < 3; c++ {
+					value := uint32(pixels[x*4+2-c]) * 0x101
+					if a != 0 && a != 0xffff {
+						value = value * 0xffff / a
+					}
+					row[x*4+c] = uint8(value >> 8)
+				}
+				row[x*4+3] = pixels[x*4+3]
+			}
+		case *image.Gray:
+			pixels := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			for x := 0; x < width; x++ {
+				row[x*4] = pixels[x]
+				row[x*4+1] = pixels[x]
+				row[x*4+2] = pixels[x]
+				row[x*4+3] = 0xff
+			}
+		default:
+			for x := 0; x < width; x++ {
+				pixel := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+				row[x*4] = pixel.B
+				row[x*4+1] = pixel.G
+				row[x*4+2] = pixel.R
+				row[x*4+3] = pixel.A
+			}
+		}
+	}
+
+	_, err = pdf.PdfiumInstance.FPDFImageObj_SetBitmap(&requests.FPDFImageObj_SetBitmap{
+		ImageObject: imageObject,
+		Bitmap:      bitmap.Bitmap,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
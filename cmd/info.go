@@ -3,7 +3,6 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/klippa-app/pdfium-cli/pdf"
@@ -42,13 +41,13 @@ var infoCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		// Second argument is the output file.
 		if len(args) > 1 && args[1] != stdFilename {
-			createdFile, err := os.Create(args[1])
+			createdFile, err := createOutput(args[1])
 			if err != nil {
 				handleError(cmd, fmt.Errorf("could not create file: %w", err), ExitCodeInvalidOutput)
 				return
 			}
 
-			defer createdFile.Close()
+			defer closeOutputFile(cmd, createdFile, args[1])
 			cmd.SetOut(createdFile)
 		}
 
@@ -2,8 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"path"
 
 	"github.com/klippa-app/pdfium-cli/pdf"
 
@@ -13,13 +11,15 @@ import (
 
 func init() {
 	addGenericPDFOptions(javascriptsCmd)
+	addArchiveOption(javascriptsCmd)
+	addOutputTemplateOption(javascriptsCmd, "{name}.{ext}")
 	rootCmd.AddCommand(javascriptsCmd)
 }
 
 var javascriptsCmd = &cobra.Command{
 	Use:   "javascripts [input] [output-folder]",
 	Short: "Extract the javascripts of a PDF",
-	Long:  "Extract the javascripts of a PDF and store them as file.\n[input] can either be a file path or - for stdin.\n[output-folder] can be either a folder or - for stdout. In the case of stdout, multiple files will be delimited by the value of the std-file-delimiter, with a newline before and after it.",
+	Long:  "Extract the javascripts of a PDF and store them as file.\n[input] can either be a file path or - for stdin.\n[output-folder] can be either a folder or - for stdout. The folder will be created when it doesn't exist. In the case of stdout, multiple files are written in the std-format, by default delimited by the value of the std-file-delimiter, with a newline before and after it. When the output ends with .zip, the files are written into a zip archive with a manifest.json.",
 	Args: func(cmd *cobra.Command, args []string) error {
 		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
 			return newExitCodeError(err, ExitCodeInvalidArguments)
@@ -29,15 +29,12 @@ var javascriptsCmd = &cobra.Command{
 			return fmt.Errorf("could not open input file %s: %w\n", args[0], newExitCodeError(err, ExitCodeInvalidInput))
 		}
 
-		if args[1] != stdFilename {
-			folderStat, err := os.Stat(args[1])
-			if err != nil {
-				return fmt.Errorf("could not open output folder %s: %w\n", args[1], newExitCodeError(err, ExitCodeInvalidOutput))
-			}
+		if err := validOutputFolder(args[1]); err != nil {
+			return err
+		}
 
-			if !folderStat.IsDir() {
-				return newExitCodeError(fmt.Errorf("output folder %s is not a folder\n", args[1]), ExitCodeInvalidOutput)
-			}
+		if err := validOutputTemplate(getOutputTemplate(cmd)); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
 		}
 
 		return nil
@@ -65,29 +62,53 @@ var javascriptsCmd = &cobra.Command{
 			return
 		}
 
-		if len(javascripts.JavaScriptActions) > 0 {
-			for i := 0; i < len(javascripts.JavaScriptActions); i++ {
-				if args[1] != stdFilename {
-					filePath := path.Join(args[1], fmt.Sprintf("%s.js", javascripts.JavaScriptActions[i].Name))
-					outFile, err := os.Create(filePath)
-					if err != nil {
-						handleError(cmd, fmt.Errorf("could not create output file for javascript %d for PDF %s: %w\n", i, args[0], err), ExitCodeInvalidOutput)
-						return
-					}
-
-					outFile.Write([]byte(javascripts.JavaScriptActions[i].Script))
-					outFile.Close()
-
-					cmd.Printf("Exported javascript %d into %s\n", i+1, filePath)
-				} else {
-					if i > 0 {
-						os.Stdout.WriteString("\n")
-						os.Stdout.WriteString(stdFileDelimiter)
-						os.Stdout.WriteString("\n")
-					}
-					os.Stdout.Write([]byte(javascripts.JavaScriptActions[i].Script))
+		pageCount, err := pdf.PdfiumInstance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+			Document: document.Document,
+		})
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not get page count for PDF %s: %w\n", args[0], newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+
+		outputArchive, err := newOutputArchive(cmd, args[1], args[0], pageCount.PageCount)
+		if err != nil {
+			handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidArguments)
+			return
+		}
+
+		for i := 0; i < len(javascripts.JavaScriptActions); i++ {
+			fileName, err := pdf.ExpandOutputTemplate(getOutputTemplate(cmd), pdf.OutputTemplateValues{
+				Input: pdf.OutputTemplateInputName(args[0]),
+				Index: i + 1,
+				Name:  javascripts.JavaScriptActions[i].Name,
+				Ext:   "js",
+			})
+			if err != nil {
+				handleError(cmd, fmt.Errorf("could not create output path for javascript %d for PDF %s: %w\n", i, args[0], err), ExitCodeInvalidArguments)
+				return
+			}
+
+			if outputArchive == nil {
+				filePath := joinOutputPath(args[1], fileName)
+				outFile, err := createOutputFile(filePath)
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not create output file for javascript %d for PDF %s: %w\n", i, args[0], err), ExitCodeInvalidOutput)
+					return
+				}
+
+				outFile.Write([]byte(javascripts.JavaScriptActions[i].Script))
+				closeOutputFile(cmd, outFile, filePath)
+
+				cmd.Printf("Exported javascript %d into %s\n", i+1, filePath)
+			} else {
+				err = outputArchive.WriteFile(newStdFile(fileName, i+1, 0), []byte(javascripts.JavaScriptActions[i].Script))
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not write javascript %d for PDF %s: %w\n", i, args[0], err), ExitCodeInvalidOutput)
+					return
 				}
 			}
 		}
+
+		outputArchive.finish(cmd)
 	},
 }
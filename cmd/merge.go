@@ -3,7 +3,6 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"strings"
 
@@ -38,7 +37,7 @@ func init() {
 var mergeCmd = &cobra.Command{
 	Use:   "merge [input] ([input]...) [output]",
 	Short: "Merge multiple PDFs into a single PDF",
-	Long:  "Merge multiple PDFs into a single PDF.\n[output] can either be a file path or - for stdout.\nEach [input] can optionally include a page range using the syntax filename.pdf[{pagerange}],\nfor example invoice.pdf[1-3] to include only pages 1, 2 and 3.",
+	Long:  "Merge multiple PDFs into a single PDF.\nAn [input] can be - for stdin, every - reads the next document from stdin, in the std-format.\n[output] can either be a file path or - for stdout.\nEach [input] can optionally include a page range using the syntax filename.pdf[{pagerange}],\nfor example invoice.pdf[1-3] to include only pages 1, 2 and 3.",
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
 			return newExitCodeError(errors.New("no input given"), ExitCodeInvalidArguments)
@@ -54,7 +53,7 @@ var mergeCmd = &cobra.Command{
 
 			for i := 0; i < len(args)-1; i++ {
 				filename, _ := parseFileWithPageRange(args[i])
-				if _, err := os.Stat(filename); err != nil {
+				if err := validFile(filename); err != nil {
 					return fmt.Errorf("could not open input file %s: %w", filename, newExitCodeError(err, ExitCodeInvalidInput))
 				}
 			}
@@ -149,18 +148,16 @@ var mergeCmd = &cobra.Command{
 			i++
 		}
 
-		var fileWriter io.Writer
-		if args[len(args)-1] == stdFilename {
-			fileWriter = os.Stdout
-		} else {
-			createdFile, err := os.Create(args[len(args)-1])
+		fileWriter := &outputWriter{Writer: os.Stdout}
+		if args[len(args)-1] != stdFilename {
+			createdFile, err := createOutput(args[len(args)-1])
 			if err != nil {
 				handleError(cmd, fmt.Errorf("could not save document: %w", err), ExitCodeInvalidOutput)
 				return
 			}
 
-			defer createdFile.Close()
-			fileWriter = createdFile
+			defer closeOutputFile(cmd, createdFile, args[len(args)-1])
+			fileWriter.Writer = createdFile
 		}
 
 		_, err = pdf.PdfiumInstance.FPDF_SaveAsCopy(&requests.FPDF_SaveAsCopy{
@@ -168,6 +165,10 @@ var mergeCmd = &cobra.Command{
 			FileWriter: fileWriter,
 		})
 		if err != nil {
+			if fileWriter.err != nil {
+				handleError(cmd, fmt.Errorf("could not save new document: %w\n", fileWriter.err), ExitCodeInvalidOutput)
+				return
+			}
 			handleError(cmd, fmt.Errorf("could not save new document: %w", newPdfiumError(err)), ExitCodePdfiumError)
 			return
 		}
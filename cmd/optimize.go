@@ -0,0 +1,421 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"image/jpeg"
+	"io"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/spf13/cobra"
+)
+
+var (
+	optimizeDPI          int
+	optimizeQuality      int
+	optimizeReportOutput string
+)
+
+func init() {
+	addGenericPDFOptions(optimizeCmd)
+	optimizeCmd.Flags().StringVarP(&outputType, "output-type", "", "text", "The file type of the report, text or json")
+	optimizeCmd.Flags().IntVarP(&optimizeDPI, "dpi", "", 150, "The target DPI, images that are displayed in a higher resolution are downsampled to this DPI.")
+	optimizeCmd.Flags().IntVarP(&optimizeQuality, "quality", "", 75, "The JPEG quality (1-100) to recompress the images in.")
+	optimizeCmd.Flags().StringVarP(&optimizeReportOutput, "report", "", "", "Write the report into this file instead of stdout. When the output is stdout, the report is written to stderr by default.")
+	rootCmd.AddCommand(optimizeCmd)
+}
+
+var optimizeCmd = &cobra.Command{
+	Use:   "optimize [input] [output]",
+	Short: "Make a PDF smaller by downsampling and recompressing images",
+	Long:  "Make a PDF smaller by downsampling the images that are displayed in a resolution above the target DPI and recompressing the images as JPEG. Images are only replaced when the JPEG is smaller. Images with transparency, black and white images (CCITT, JBIG2 or 1 bit per pixel) and images inside form objects are kept, because JPEG would lose the transparency or make them larger and pdfium can't change form objects. The content of the pages with replaced images is generated again by pdfium. The document is saved completely again, without the original images and the older versions of objects of incremental updates, everything else like bookmarks, attachments, the form and the metadata is kept. When the result is not smaller than the input, the input is written. A report with the size before and after and the result of every image is written to stdout.\n[input] can either be a file path or - for stdin.\n[output] can either be a file path or - for stdout.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
+		}
+
+		if err := validFile(args[0]); err != nil {
+			return fmt.Errorf("could not open input file %s: %w\n", args[0], newExitCodeError(err, ExitCodeInvalidInput))
+		}
+
+		if outputType != "text" && outputType != "json" {
+			return newExitCodeError(fmt.Errorf("invalid output type %s, should be text or json\n", outputType), ExitCodeInvalidArguments)
+		}
+
+		if optimizeDPI < 1 {
+			return newExitCodeError(fmt.Errorf("invalid DPI %d, should be at least 1\n", optimizeDPI), ExitCodeInvalidArguments)
+		}
+
+		if optimizeQuality < 1 || optimizeQuality > 100 {
+			return newExitCodeError(fmt.Errorf("invalid quality %d, should be between 1 and 100\n", optimizeQuality), ExitCodeInvalidArguments)
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pdf.LoadPdfium()
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not load pdfium: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+		defer pdf.ClosePdfium()
+
+		document, closeFile, err := openFile(args[0])
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not open input file %s: %w\n", args[0], err), ExitCodeInvalidInput)
+			return
+		}
+		defer closeFile()
+
+		report := pdf.OptimizeReport{
+			File:   args[0],
+			Output: args[1],
+			Images: []pdf.OptimizeImage{},
+		}
+
+		pageCount, err := pdf.PdfiumInstance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+			Document: document.Document,
+		})
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not get page count for PDF %s: %w\n", args[0], newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+
+		// Images that are shared between pages are replaced everywhere at
+		// once, remember the new images to not optimize them again.
+		optimizedImages := map[[sha256.Size]byte]string{}
+
+		for i := 0; i < pageCount.PageCount; i++ {
+			page, err := pdf.PdfiumInstance.FPDF_LoadPage(&requests.FPDF_LoadPage{
+				Document: document.Document,
+				Index:    i,
+			})
+			if err != nil {
+				handleError(cmd, fmt.Errorf("could not load page %d for PDF %s: %w\n", i+1, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+				return
+			}
+
+			closePageFunc := func() {
+				pdf.PdfiumInstance.FPDF_ClosePage(&requests.FPDF_ClosePage{
+					Page: page.Page,
+				})
+			}
+
+			imageObjects, err := collectImageObjects(page.Page)
+			if err != nil {
+				closePageFunc()
+				handleError(cmd, fmt.Errorf("could not get image objects for page %d for PDF %s: %w\n", i+1, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+				return
+			}
+
+			pageChanged := false
+			for _, imageObject := range imageObjects {
+				result, changed, err := optimizeImage(document.Document, page.Page, imageObject, optimizedImages)
+				if err != nil {
+					closePageFunc()
+					handleError(cmd, fmt.Errorf("could not optimize image %s for page %d for PDF %s: %w\n", imageObject.name(), i+1, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+					return
+				}
+
+				result.Page = i + 1
+				if result.Optimized {
+					imageHash, err := hashImageObject(imageObject.object)
+					if err == nil {
+						optimizedImages[imageHash] = fmt.Sprintf("image %s on page %d", result.Name, result.Page)
+					}
+				}
+				pageChanged = pageChanged || changed
+				report.Images = append(report.Images, result)
+			}
+
+			// A replaced image is a new object, the content of the page has to
+			// be generated again to use it.
+			if pageChanged {
+				_, err = pdf.PdfiumInstance.FPDFPage_GenerateContent(&requests.FPDFPage_GenerateContent{
+					Page: requests.Page{
+						ByReference: &page.Page,
+					},
+				})
+				if err != nil {
+					closePageFunc()
+					handleError(cmd, fmt.Errorf("could not generate content for page %d for PDF %s: %w\n", i+1, args[0], newPdfiumError(err)), ExitCodePdfiumError)
+					return
+				}
+			}
+
+			closePageFunc()
+		}
+
+		// Save without incremental updates, so that only the current version
+		// of every object is written and the replaced images are left out.
+		optimizedFile := &bytes.Buffer{}
+		_, err = pdf.PdfiumInstance.FPDF_SaveAsCopy(&requests.FPDF_SaveAsCopy{
+			Document:   document.Document,
+			Flags:      requests.SaveFlagNoIncremental,
+			FileWriter: optimizedFile,
+		})
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not save optimized document: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+
+		inputFile, inputSize, err := openInputFile(args[0])
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not open input file %s: %w\n", args[0], err), ExitCodeInvalidInput)
+			return
+		}
+		defer inputFile.Close()
+
+		report.OriginalSize = inputSize
+		report.Finish(int64(optimizedFile.Len()))
+
+		outputBytes := optimizedFile.Bytes()
+		if report.KeptOriginal {
+			outputBytes, err = io.ReadAll(inputFile)
+			if err != nil {
+				handleError(cmd, fmt.Errorf("could not read input file %s: %w\n", args[0], err), ExitCodeInvalidInput)
+				return
+			}
+		}
+
+		if args[1] == stdFilename {
+			cmd.OutOrStdout().Write(outputBytes)
+		} else {
+			if err := writeOutputFile(args[1], outputBytes); err != nil {
+				handleError(cmd, fmt.Errorf("could not write optimized document into %s: %w\n", args[1], err), ExitCodeInvalidOutput)
+				return
+			}
+		}
+
+		reportBuffer := &bytes.Buffer{}
+		if outputType == "json" {
+			outputJson, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Fprintln(reportBuffer, string(outputJson))
+		} else {
+			writeOptimizeReport(reportBuffer, report)
+		}
+
+		if optimizeReportOutput != "" && optimizeReportOutput != stdFilename {
+			if err := writeOutputFile(optimizeReportOutput, reportBuffer.Bytes()); err != nil {
+				handleError(cmd, fmt.Errorf("could not write report into %s: %w\n", optimizeReportOutput, err), ExitCodeInvalidOutput)
+				return
+			}
+		} else if args[1] == stdFilename && optimizeReportOutput == "" {
+			cmd.PrintErr(reportBuffer.String())
+		} else {
+			cmd.Print(reportBuffer.String())
+		}
+	},
+}
+
+// optimizeImage downsamples an image object when it's displayed in a higher
+// resolution than the target DPI and replaces it with a JPEG when that is
+// smaller. Returns whether the image object uses a new image, which can also
+// be an image that was replaced on another page.
+func optimizeImage(document references.FPDF_DOCUMENT, page references.FPDF_PAGE, imageObject pageImageObject, optimizedImages map[[sha256.Size]byte]string) (pdf.OptimizeImage, bool, error) {
+	result := pdf.OptimizeImage{Name: imageObject.name()}
+
+	pixelSize, err := pdf.PdfiumInstance.FPDFImageObj_GetImagePixelSize(&requests.FPDFImageObj_GetImagePixelSize{
+		ImageObject: imageObject.object,
+	})
+	if err != nil {
+		return result, false, err
+	}
+	result.Width = int(pixelSize.Width)
+	result.Height = int(pixelSize.Height)
+
+	objectMatrix, err := pdf.PdfiumInstance.FPDFPageObj_GetMatrix(&requests.FPDFPageObj_GetMatrix{
+		PageObject: imageObject.object,
+	})
+	if err != nil {
+		return result, false, err
+	}
+
+	// The image is placed in its parent forms, which are placed on the page.
+	matrix := pdf.NewMatrix(objectMatrix.Matrix).Multiply(imageObject.matrix)
+	result.DPI = pdf.ImageDPI(matrix, result.Width, result.Height)
+
+	rawData, err := pdf.PdfiumInstance.FPDFImageObj_GetImageDataRaw(&requests.FPDFImageObj_GetImageDataRaw{
+		ImageObject: imageObject.object,
+	})
+	if err != nil {
+		return result, false, err
+	}
+	result.OriginalSize = len(rawData.Data)
+
+	// pdfium can't generate the content of form objects, so a new image
+	// would not be used.
+	if len(imageObject.indexes) > 1 {
+		result.Skipped = "image inside a form object"
+		return result, false, nil
+	}
+
+	imageHash, err := hashImageObject(imageObject.object)
+	if err != nil {
+		return result, false, err
+	}
+	if optimizedImage, ok := optimizedImages[imageHash]; ok {
+		result.Skipped = fmt.Sprintf("same image as %s", optimizedImage)
+		return result, true, nil
+	}
+
+	if result.DPI == 0 {
+		result.Skipped = "not visible"
+		return result, false, nil
+	}
+
+	filterCount, err := pdf.PdfiumInstance.FPDFImageObj_GetImageFilterCount(&requests.FPDFImageObj_GetImageFilterCount{
+		ImageObject: imageObject.object,
+	})
+	if err != nil {
+		return result, false, err
+	}
+
+	for i := 0; i < filterCount.Count; i++ {
+		filter, err := pdf.PdfiumInstance.FPDFImageObj_GetImageFilter(&requests.FPDFImageObj_GetImageFilter{
+			ImageObject: imageObject.object,
+			Index:       i,
+		})
+		if err != nil {
+			return result, false, err
+		}
+
+		if filter.ImageFilter == "CCITTFaxDecode" || filter.ImageFilter == "JBIG2Decode" {
+			result.Skipped = "black and white image"
+			return result, false, nil
+		}
+	}
+
+	metadata, err := pdf.PdfiumInstance.FPDFImageObj_GetImageMetadata(&requests.FPDFImageObj_GetImageMetadata{
+		ImageObject: imageObject.object,
+		Page: requests.Page{
+			ByReference: &page,
+		},
+	})
+	if err != nil {
+		return result, false, err
+	}
+	if metadata.ImageMetadata.BitsPerPixel == 1 {
+		result.Skipped = "black and white image"
+		return result, false, nil
+	}
+
+	transparent, err := hasImageTransparency(document, page, imageObject.object)
+	if err != nil {
+		return result, false, err
+	}
+	if transparent {
+		result.Skipped = "image with transparency"
+		return result, false, nil
+	}
+
+	newWidth, newHeight, downsample := pdf.DownsampleSize(result.Width, result.Height, result.DPI, float64(optimizeDPI))
+
+	imageBitmap, err := pdf.PdfiumInstance.FPDFImageObj_GetBitmap(&requests.FPDFImageObj_GetBitmap{
+		ImageObject: imageObject.object,
+	})
+	if err != nil {
+		return result, false, err
+	}
+	defer pdf.PdfiumInstance.FPDFBitmap_Destroy(&requests.FPDFBitmap_Destroy{
+		Bitmap: imageBitmap.Bitmap,
+	})
+
+	img, err := getBitmapImage(pdf.PdfiumInstance, imageBitmap.Bitmap)
+	if err != nil {
+		return result, false, err
+	}
+
+	if downsample {
+		img = pdf.Downsample(img, newWidth, newHeight)
+	}
+
+	jpegFile := &bytes.Buffer{}
+	if err := jpeg.Encode(jpegFile, img, &jpeg.Options{Quality: optimizeQuality}); err != nil {
+		return result, false, err
+	}
+
+	if jpegFile.Len() >= result.OriginalSize {
+		result.Skipped = "not smaller as JPEG"
+		return result, false, nil
+	}
+
+	// The pages are not passed, pdfium only uses them to clear their cache
+	// and the WebAssembly version can't handle them.
+	_, err = pdf.PdfiumInstance.FPDFImageObj_LoadJpegFileInline(&requests.FPDFImageObj_LoadJpegFileInline{
+		ImageObject: imageObject.object,
+		FileData:    jpegFile.Bytes(),
+	})
+	if err != nil {
+		return result, false, err
+	}
+
+	result.Optimized = true
+	result.NewSize = jpegFile.Len()
+	result.NewWidth = img.Bounds().Dx()
+	result.NewHeight = img.Bounds().Dy()
+	return result, true, nil
+}
+
+// hasImageTransparency returns whether an image has a mask, by rendering it
+// with its mask and looking for pixels that are not opaque.
+func hasImageTransparency(document references.FPDF_DOCUMENT, page references.FPDF_PAGE, object references.FPDF_PAGEOBJECT) (bool, error) {
+	renderedBitmap, err := pdf.PdfiumInstance.FPDFImageObj_GetRenderedBitmap(&requests.FPDFImageObj_GetRenderedBitmap{
+		Document: document,
+		Page: requests.Page{
+			ByReference: &page,
+		},
+		ImageObject: object,
+	})
+	if err != nil {
+		return false, err
+	}
+	defer pdf.PdfiumInstance.FPDFBitmap_Destroy(&requests.FPDFBitmap_Destroy{
+		Bitmap: renderedBitmap.Bitmap,
+	})
+
+	img, err := getBitmapImage(pdf.PdfiumInstance, renderedBitmap.Bitmap)
+	if err != nil {
+		return false, err
+	}
+
+	bgra, ok := img.(*BGRA)
+	if !ok {
+		return false, nil
+	}
+
+	for y := 0; y < bgra.Rect.Dy(); y++ {
+		row := bgra.Pix[y*bgra.Stride : y*bgra.Stride+bgra.Rect.Dx()*4]
+		for x := 3; x < len(row); x += 4 {
+			if row[x] != 255 {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func writeOptimizeReport(writer io.Writer, report pdf.OptimizeReport) {
+	fmt.Fprintf(writer, "Optimized %s into %s\n", report.File, report.Output)
+
+	for _, optimizedImage := range report.Images {
+		if optimizedImage.Optimized {
+			fmt.Fprintf(writer, " - Page %d, image %s: %dx%d at %.0f DPI, %d bytes -> %dx%d, %d bytes\n", optimizedImage.Page, optimizedImage.Name, optimizedImage.Width, optimizedImage.Height, optimizedImage.DPI, optimizedImage.OriginalSize, optimizedImage.NewWidth, optimizedImage.NewHeight, optimizedImage.NewSize)
+		} else {
+			fmt.Fprintf(writer, " - Page %d, image %s: %dx%d at %.0f DPI, %d bytes, kept: %s\n", optimizedImage.Page, optimizedImage.Name, optimizedImage.Width, optimizedImage.Height, optimizedImage.DPI, optimizedImage.OriginalSize, optimizedImage.Skipped)
+		}
+	}
+
+	fmt.Fprintf(writer, "Optimized %d of %d images\n", report.OptimizedImages, report.ImageCount)
+	if report.KeptOriginal {
+		fmt.Fprintf(writer, "Size: %d bytes, the optimized PDF was not smaller, the input is kept\n", report.OriginalSize)
+	} else {
+		fmt.Fprintf(writer, "Size: %d bytes -> %d bytes (%.1f%% smaller)\n", report.OriginalSize, report.OptimizedSize, report.Reduction())
+	}
+}
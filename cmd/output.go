@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/spf13/cobra"
+)
+
+const outputTemplateTokensHelp = "Available tokens are {input} (the input filename without extension), {page} (the page number), {index} (the number of the output file), {label} (the page label), {name} (the name of the item) and {ext} (the file extension). {page} and {index} can be zero-padded by giving a width, like {page:04}."
+
+func addOutputTemplateOption(command *cobra.Command, defaultTemplate string) {
+	command.Flags().StringP("output-template", "", defaultTemplate, "The template for the filenames in the output folder, may contain folders that will be created when missing. "+outputTemplateTokensHelp)
+}
+
+var archiveFormat string
+
+func addArchiveOption(command *cobra.Command) {
+	command.Flags().StringVarP(&archiveFormat, "archive", "", "", "Write the output files into an archive, the output is then the path of the archive or - for stdout. Only zip is supported. Outputs that end with .zip are written as zip archive without this option. The files are named like they would be in a folder, files of render and explode are named like {input}-{page}.{ext}. The archive contains a manifest.json with the source and the name, page and content type of every file.")
+}
+
+// isOutputArchive returns whether the output files are written into an
+// archive.
+func isOutputArchive(output string) bool {
+	return archiveFormat != "" || strings.EqualFold(filepath.Ext(output), ".zip")
+}
+
+// outputArchiveWriter writes multiple output files into one stream, an
+// archive or stdout in the std-format.
+type outputArchiveWriter struct {
+	*pdf.StdFileWriter
+	output string
+	file   io.WriteCloser
+
+	// Whether finish reports to stderr, because stdout is used for other
+	// output.
+	reportToStderr bool
+}
+
+// newOutputArchive returns a writer for the output files when they are
+// written into an archive or to stdout. Returns nil when every output file is
+// written into its own file.
+func newOutputArchive(cmd *cobra.Command, output string, input string, pageCount int) (*outputArchiveWriter, error) {
+	if archiveFormat != "" && archiveFormat != "zip" {
+		return nil, newExitCodeError(fmt.Errorf("invalid archive format %s, only zip is supported", archiveFormat), ExitCodeInvalidArguments)
+	}
+
+	if !isOutputArchive(output) {
+		if output != stdFilename {
+			return nil, nil
+		}
+
+		format, err := pdf.ParseStdFormat(stdFormat)
+		if err != nil {
+			return nil, newExitCodeError(err, ExitCodeInvalidArguments)
+		}
+
+		return &outputArchiveWriter{StdFileWriter: pdf.NewStdFileWriter(os.Stdout, format, stdFileDelimiter), output: output}, nil
+	}
+
+	archiveWriter := &outputArchiveWriter{output: output}
+	var writer io.Writer = os.Stdout
+	if output != stdFilename {
+		file, err := createOutputFile(output)
+		if err != nil {
+			return nil, newExitCodeError(fmt.Errorf("could not create output archive %s: %w", output, err), ExitCodeInvalidOutput)
+		}
+		archiveWriter.file = file
+		writer = file
+	}
+
+	archiveWriter.StdFileWriter = pdf.NewStdFileWriter(writer, pdf.StdFormatZip, "")
+	archiveWriter.SetManifest(&pdf.ArchiveManifest{
+		Command: cmd.Name(),
+		Source: pdf.ArchiveSource{
+			File:      input,
+			PageCount: pageCount,
+		},
+	})
+
+	return archiveWriter, nil
+}
+
+// finish closes the archive and reports what was written into it. Does
+// nothing for a nil writer.
+func (w *outputArchiveWriter) finish(cmd *cobra.Command) {
+	if w == nil {
+		return
+	}
+
+	err := w.Close()
+	if w.file != nil {
+		if closeErr := w.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if err != nil {
+		handleError(cmd, fmt.Errorf("could not write output %s: %w\n", w.output, err), ExitCodeInvalidOutput)
+		return
+	}
+
+	if w.file != nil {
+		if w.reportToStderr {
+			cmd.PrintErrf("Wrote %d file(s) into %s\n", w.Count(), w.output)
+		} else {
+			cmd.Printf("Wrote %d file(s) into %s\n", w.Count(), w.output)
+		}
+	}
+}
+
+// getOutputTemplate returns the output template option of the command. Every
+// command has its own default, so we can't share a flag variable.
+func getOutputTemplate(cmd *cobra.Command) string {
+	return cmd.Flag("output-template").Value.String()
+}
+
+// validOutputTemplate checks whether the output template can be expanded.
+func validOutputTemplate(template string) error {
+	_, err := pdf.ExpandOutputTemplate(template, pdf.OutputTemplateValues{})
+	return err
+}
+
+// validOutputFolder checks whether the output folder is usable, a missing
+// folder is fine because we will create it.
+func validOutputFolder(folder string) error {
+	if folder == stdFilename || isOutputArchive(folder) {
+		return nil
+	}
+
+	// Remote folders don't have to exist.
+	if pdf.IsLocation(folder) {
+		if err := pdf.ValidLocation(folder, true); err != nil {
+			return newExitCodeError(fmt.Errorf("%w\n", err), ExitCodeInvalidOutput)
+		}
+		return nil
+	}
+
+	folderStat, err := os.Stat(folder)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not open output folder %s: %w\n", folder, newExitCodeError(err, ExitCodeInvalidOutput))
+	}
+
+	if !folderStat.IsDir() {
+		return newExitCodeError(fmt.Errorf("output folder %s is not a folder\n", folder), ExitCodeInvalidOutput)
+	}
+
+	return nil
+}
+
+// createOutputFolders creates the missing parent folders of a file path.
+func createOutputFolders(filePath string) error {
+	return os.MkdirAll(filepath.Dir(filePath), 0755)
+}
+
+// createOutputFile creates a file and its missing parent folders, or the
+// file at a remote location like s3://bucket/file.pdf.
+func createOutputFile(filePath string) (io.WriteCloser, error) {
+	if pdf.IsLocation(filePath) {
+		return pdf.CreateLocation(filePath)
+	}
+
+	if err := createOutputFolders(filePath); err != nil {
+		return nil, err
+	}
+
+	return os.Create(filePath)
+}
+
+// createOutput creates an output file without creating folders, or the file
+// at a remote location like s3://bucket/file.pdf.
+func createOutput(filePath string) (io.WriteCloser, error) {
+	if pdf.IsLocation(filePath) {
+		return pdf.CreateLocation(filePath)
+	}
+
+	return os.Create(filePath)
+}
+
+// writeOutputFile writes data into a file, like os.WriteFile, or into the
+// file at a remote location.
+func writeOutputFile(filePath string, data []byte) error {
+	if !pdf.IsLocation(filePath) {
+		return os.WriteFile(filePath, data, 0644)
+	}
+
+	file, err := pdf.CreateLocation(filePath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// closeOutputFile closes an output file and handles the error, for remote
+// files the upload is only finished when the file is closed.
+func closeOutputFile(cmd *cobra.Command, file io.Closer, filePath string) {
+	if err := file.Close(); err != nil {
+		handleError(cmd, fmt.Errorf("could not write output file %s: %w\n", filePath, err), ExitCodeInvalidOutput)
+	}
+}
+
+// outputWriter remembers the first error of writing an output file, because
+// pdfium only tells us that saving failed.
+type outputWriter struct {
+	io.Writer
+	err error
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// joinOutputPath joins the output folder and the name of an output file.
+func joinOutputPath(folder string, name string) string {
+	if pdf.IsLocation(folder) {
+		return pdf.JoinLocation(folder, name)
+	}
+
+	return path.Join(folder, name)
+}
+
+// getPageLabel returns the label of a page, or the page number when the page
+// has no label.
+func getPageLabel(instance pdfium.Pdfium, document references.FPDF_DOCUMENT, index int) string {
+	pageLabel, err := instance.FPDF_GetPageLabel(&requests.FPDF_GetPageLabel{
+		Document: document,
+		Page:     index,
+	})
+	if err != nil || pageLabel.Label == "" {
+		return strconv.Itoa(index + 1)
+	}
+
+	return pageLabel.Label
+}
+
+// getContentType returns the media type of a file by its extension.
+func getContentType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt":
+		return "text/plain; charset=utf-8"
+	case ".tif", ".tiff":
+		return "image/tiff"
+	case ".hocr":
+		return "text/html; charset=utf-8"
+	}
+
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
@@ -1,15 +1,16 @@
 package cmd
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/klippa-app/pdfium-cli/pdf"
 
+	"github.com/klippa-app/go-pdfium"
 	"github.com/klippa-app/go-pdfium/requests"
 	"github.com/klippa-app/go-pdfium/responses"
 	"github.com/spf13/cobra"
@@ -19,6 +20,7 @@ var (
 	// Used for flags.
 	password           string
 	stdFileDelimiter   string
+	stdFormat          string
 	pages              string
 	ignoreInvalidPages bool
 )
@@ -26,6 +28,7 @@ var (
 func addGenericPDFOptions(command *cobra.Command) {
 	command.Flags().StringVarP(&password, "password", "p", "", "Password on the input PDF file(s).")
 	command.Flags().StringVarP(&stdFileDelimiter, "std-file-delimiter", "", "--pdfium-cli-file-boundary", "The delimiter to use when having multiple files in your input and/or output.")
+	command.Flags().StringVarP(&stdFormat, "std-format", "", string(pdf.StdFormatDelimiter), "The format of multiple files on stdin and stdout: delimiter (separated by the std-file-delimiter), tar, zip or ndjson-base64 (a JSON object with the Name and the base64 encoded Data per line). The entries of tar, zip and ndjson-base64 output have the filename, index, page and content type of every file, in the extended attributes user.pdfiumcli.*, the comment as JSON, or in the JSON object.")
 }
 
 func addPagesOption(intro string, command *cobra.Command) {
@@ -38,44 +41,81 @@ func addIgnoreInvalidPagesOption(command *cobra.Command) {
 }
 
 func isExperimentalError(err error) bool {
-	return strings.Contains(err.Error(), "pdfium_experimental")
+	return pdf.IsExperimentalError(err)
 }
 
 const stdFilename = "-"
 
-var stdinDocuments [][]byte
+// stdinSpoolSize is the size from which a document from stdin is written into
+// a temporary file instead of kept in memory.
+const stdinSpoolSize = 32 * 1024 * 1024
+
+var stdinDocuments pdf.DocumentStream
 var stdinNoMoreFiles = errors.New("no more files on stdin")
 
+// lastStdinDocument is the document from stdin that was opened last, so that
+// it can be opened again by other pdfium instances.
+var lastStdinDocument *pdf.StreamDocument
+
+// spooledStdinDocuments are the documents from stdin in temporary files, they
+// are removed when the document is closed or when we exit.
+var spooledStdinDocuments []*pdf.StreamDocument
+
 func openFile(filename string) (*responses.OpenDocument, func(), error) {
+	return openFileWithInstance(pdf.PdfiumInstance, filename, false)
+}
+
+// reopenFile opens the last opened file again in another pdfium instance, for
+// stdin this is the last document that was read.
+func reopenFile(instance pdfium.Pdfium, filename string) (*responses.OpenDocument, func(), error) {
+	return openFileWithInstance(instance, filename, true)
+}
+
+func openFileWithInstance(instance pdfium.Pdfium, filename string, reopen bool) (*responses.OpenDocument, func(), error) {
 	openDocumentRequest := &requests.OpenDocument{}
 
 	closeFile := func() {}
 
 	// Support opening file from stdin.
 	if filename == stdFilename {
-		if stdinDocuments == nil {
-			// @todo: possible improve this by reading up to the delimiter
-			//   for every document and not all at once.
-			readStdin, err := io.ReadAll(os.Stdin)
+		stdinDocument := lastStdinDocument
+		if !reopen {
+			var err error
+			stdinDocument, err = nextStdinDocument()
 			if err != nil {
 				return nil, nil, err
 			}
-
-			stdinDocuments = bytes.Split(readStdin, []byte("\n"+stdFileDelimiter+"\n"))
 		}
 
-		if len(stdinDocuments) == 0 {
-			return nil, nil, stdinNoMoreFiles
+		reader, err := stdinDocument.Open()
+		if err != nil {
+			return nil, nil, err
 		}
 
-		stdinDocument := stdinDocuments[len(stdinDocuments)-1]
-		stdinDocuments = stdinDocuments[:len(stdinDocuments)-1]
+		closeFile = func() {
+			reader.Close()
+			if !reopen {
+				removeStdinDocument(stdinDocument)
+			}
+		}
 
-		// For stdin we need to read the full thing because pdfium doesn't
-		// support streaming when it doesn't know the size of the file.
-		reader := bytes.NewReader(stdinDocument)
+		// Pdfium doesn't support streaming when it doesn't know the size of
+		// the file, so stdin is read up to the end of the document.
 		openDocumentRequest.FileReader = reader
-		openDocumentRequest.FileReaderSize = reader.Size()
+		openDocumentRequest.FileReaderSize = stdinDocument.Size()
+	} else if pdf.IsLocation(filename) {
+		// Remote files are fetched in parts when pdfium reads them.
+		file, err := pdf.OpenLocation(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		closeFile = func() {
+			file.Close()
+		}
+
+		openDocumentRequest.FileReader = file
+		openDocumentRequest.FileReaderSize = file.Size()
 	} else {
 		file, err := os.Open(filename)
 		if err != nil {
@@ -90,7 +130,7 @@ func openFile(filename string) (*responses.OpenDocument, func(), error) {
 		// which it will need to do proper seeking in the file.
 		fileStat, err := file.Stat()
 		if err != nil {
-			return nil, closeFile, nil
+			return nil, closeFile, err
 		}
 
 		openDocumentRequest.FileReader = file
@@ -101,23 +141,133 @@ func openFile(filename string) (*responses.OpenDocument, func(), error) {
 		openDocumentRequest.Password = &password
 	}
 
-	openedDocument, err := pdf.PdfiumInstance.OpenDocument(openDocumentRequest)
+	openedDocument, err := instance.OpenDocument(openDocumentRequest)
 	if err != nil {
 		return nil, closeFile, fmt.Errorf("could not open file with pdfium: %w", newPdfiumError(err))
 	}
 
 	originalCloseFile := closeFile
 	closeFile = func() {
-		pdf.PdfiumInstance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{Document: openedDocument.Document})
+		instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{Document: openedDocument.Document})
 		originalCloseFile()
 	}
 
 	return openedDocument, closeFile, nil
 }
 
+// nextStdinDocument reads the next document from stdin, which becomes the
+// last stdin document.
+func nextStdinDocument() (*pdf.StreamDocument, error) {
+	if stdinDocuments == nil {
+		format, err := pdf.ParseStdFormat(stdFormat)
+		if err != nil {
+			return nil, err
+		}
+		stdinDocuments = pdf.NewStdDocumentStream(os.Stdin, format, stdFileDelimiter, stdinSpoolSize)
+	}
+
+	stdinDocument, err := stdinDocuments.Next()
+
+	// A zip archive on stdin can be in a temporary file as well, it has to be
+	// removed when we exit before all its documents have been read.
+	if archiveStream, ok := stdinDocuments.(pdf.ArchiveDocumentStream); ok {
+		archive := archiveStream.Archive()
+		if archive != nil && archive.IsSpooled() && !slices.Contains(spooledStdinDocuments, archive) {
+			spooledStdinDocuments = append(spooledStdinDocuments, archive)
+		}
+	}
+
+	if errors.Is(err, io.EOF) {
+		return nil, stdinNoMoreFiles
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read stdin: %w", err)
+	}
+
+	lastStdinDocument = stdinDocument
+	if stdinDocument.IsSpooled() {
+		spooledStdinDocuments = append(spooledStdinDocuments, stdinDocument)
+	}
+
+	return stdinDocument, nil
+}
+
+// openInputFile opens the bytes of an input file without pdfium and returns
+// its size, for stdin this is the last document that was read by openFile.
+func openInputFile(filename string) (io.ReadCloser, int64, error) {
+	if filename == stdFilename {
+		if lastStdinDocument == nil {
+			return nil, 0, stdinNoMoreFiles
+		}
+
+		reader, err := lastStdinDocument.Open()
+		if err != nil {
+			return nil, 0, err
+		}
+		return reader, lastStdinDocument.Size(), nil
+	}
+
+	if pdf.IsLocation(filename) {
+		file, err := pdf.OpenLocation(filename)
+		if err != nil {
+			return nil, 0, err
+		}
+		return file, file.Size(), nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	fileStat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	return file, fileStat.Size(), nil
+}
+
+// newStdFile returns the metadata of an output file on stdout.
+func newStdFile(name string, index int, page int) pdf.StdFile {
+	return pdf.StdFile{
+		Name:        name,
+		Index:       index,
+		Page:        page,
+		ContentType: getContentType(name),
+	}
+}
+
+// removeStdinDocument removes the temporary file of a document from stdin.
+func removeStdinDocument(stdinDocument *pdf.StreamDocument) {
+	stdinDocument.Remove()
+	for i := range spooledStdinDocuments {
+		if spooledStdinDocuments[i] == stdinDocument {
+			spooledStdinDocuments = append(spooledStdinDocuments[:i], spooledStdinDocuments[i+1:]...)
+			break
+		}
+	}
+}
+
+// removeStdinDocuments removes the temporary files of the documents from
+// stdin that are still open.
+func removeStdinDocuments() {
+	for _, stdinDocument := range spooledStdinDocuments {
+		stdinDocument.Remove()
+	}
+	spooledStdinDocuments = nil
+}
+
 func validFile(filename string) error {
 	if filename == stdFilename {
-		return nil
+		_, err := pdf.ParseStdFormat(stdFormat)
+		return err
+	}
+
+	// Remote files are only checked when they are opened.
+	if pdf.IsLocation(filename) {
+		return pdf.ValidLocation(filename, false)
 	}
 
 	if _, err := os.Stat(filename); err != nil {
@@ -189,5 +339,12 @@ func handleError(cmd *cobra.Command, err error, defaultCode int) {
 		}
 	}
 
+	// Commands that run in process are stopped instead of the process, so
+	// that the process can continue, like with the next input of a batch.
+	if runningInProcess {
+		panic(&inProcessCommandError{err: err, exitCode: errorCode})
+	}
+
+	removeStdinDocuments()
 	os.Exit(errorCode)
 }
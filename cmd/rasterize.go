@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/klippa-app/go-pdfium/enums"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rasterizeDPI             int
+	rasterizeQuality         int
+	rasterizeText            bool
+	rasterizeSkipAnnotations bool
+	rasterizeSkipForm        bool
+)
+
+func init() {
+	addGenericPDFOptions(rasterizeCmd)
+	addPagesOption("The pages to rasterize", rasterizeCmd)
+	rasterizeCmd.Flags().IntVarP(&rasterizeDPI, "dpi", "", 150, "The DPI to render the pages in.")
+	rasterizeCmd.Flags().IntVarP(&rasterizeQuality, "quality", "", 85, "The JPEG quality (1-100) of the rendered pages.")
+	rasterizeCmd.Flags().BoolVarP(&rasterizeText, "text", "", false, "Add the text of the original pages as invisible text on top of the images, so that the text can still be selected and searched. The text is written in the standard font Helvetica, characters that it doesn't have can't be searched.")
+	rasterizeCmd.Flags().BoolVarP(&rasterizeSkipAnnotations, "skip-annotations", "", false, "Don't render the annotations that are embedded in the PDF into the images, they are left out of the new document.")
+	rasterizeCmd.Flags().BoolVarP(&rasterizeSkipForm, "skip-form", "", false, "Don't render the form fields that are embedded in the PDF into the images, they are left out of the new document.")
+	rootCmd.AddCommand(rasterizeCmd)
+}
+
+var rasterizeCmd = &cobra.Command{
+	Use:   "rasterize [input] [output]",
+	Short: "Turn a PDF into a PDF with only images",
+	Long:  "Turn a PDF into a PDF with only images. Every page is rendered into a JPEG, which becomes the only content of a new page with the same size, so that the content can't be edited or extracted anymore. The annotations and form fields are rendered into the images, unless skip-annotations or skip-form is given. Only the pages are copied, the new document has no bookmarks, attachments, form or metadata. Optionally the text of the original pages is added as invisible text on top of the images, every word is stretched over the position of the original word.\n[input] can either be a file path or - for stdin.\n[output] can either be a file path or - for stdout.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
+		}
+
+		if err := validFile(args[0]); err != nil {
+			return fmt.Errorf("could not open input file %s: %w\n", args[0], newExitCodeError(err, ExitCodeInvalidInput))
+		}
+
+		if rasterizeDPI < 1 {
+			return newExitCodeError(fmt.Errorf("invalid DPI %d, should be at least 1\n", rasterizeDPI), ExitCodeInvalidArguments)
+		}
+
+		if rasterizeQuality < 1 || rasterizeQuality > 100 {
+			return newExitCodeError(fmt.Errorf("invalid quality %d, should be between 1 and 100\n", rasterizeQuality), ExitCodeInvalidArguments)
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pdf.LoadPdfium()
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not load pdfium: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+		defer pdf.ClosePdfium()
+
+		document, closeFile, err := openFile(args[0])
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not open input file %s: %w\n", args[0], err), ExitCodeInvalidInput)
+			return
+		}
+		defer closeFile()
+
+		pageCount, err := pdf.PdfiumInstance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+			Document: document.Document,
+		})
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not get page count for PDF %s: %w\n", args[0], newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+
+		pageRange := "first-last"
+		if pages != "" {
+			pageRange = pages
+		}
+
+		parsedPageRange, _, err := pdf.NormalizePageRange(pageCount.PageCount, pageRange, ignoreInvalidPages)
+		if err != nil {
+			handleError(cmd, fmt.Errorf("invalid page range '%s': %w\n", pageRange, err), ExitCodeInvalidPageRange)
+			return
+		}
+
+		newDocument, err := pdf.PdfiumInstance.FPDF_CreateNewDocument(&requests.FPDF_CreateNewDocument{})
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not create new document: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+		defer pdf.PdfiumInstance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{Document: newDocument.Document})
+
+		for i, page := range strings.Split(*parsedPageRange, ",") {
+			pageInt, _ := strconv.Atoi(page)
+			if err := rasterizePage(document.Document, newDocument.Document, pageInt-1, i); err != nil {
+				handleError(cmd, fmt.Errorf("could not rasterize page %d: %w\n", pageInt, newPdfiumError(err)), ExitCodePdfiumError)
+				return
+			}
+		}
+
+		fileWriter := &outputWriter{Writer: os.Stdout}
+		if args[1] != stdFilename {
+			createdFile, err := createOutput(args[1])
+			if err != nil {
+				handleError(cmd, fmt.Errorf("could not save document: %w\n", err), ExitCodeInvalidOutput)
+				return
+			}
+
+			defer closeOutputFile(cmd, createdFile, args[1])
+			fileWriter.Writer = createdFile
+		}
+
+		_, err = pdf.PdfiumInstance.FPDF_SaveAsCopy(&requests.FPDF_SaveAsCopy{
+			Document:   newDocument.Document,
+			FileWriter: fileWriter,
+		})
+		if err != nil {
+			if fileWriter.err != nil {
+				handleError(cmd, fmt.Errorf("could not save rasterized document: %w\n", fileWriter.err), ExitCodeInvalidOutput)
+				return
+			}
+			handleError(cmd, fmt.Errorf("could not save rasterized document: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+
+		if args[1] != stdFilename {
+			cmd.Printf("Rasterized pages %s into %s\n", *parsedPageRange, args[1])
+		}
+	},
+}
+
+// rasterizePage renders a page into a JPEG and adds a page with the same size
+// to the new document that only contains the image, and the invisible text of
+// the page when enabled.
+func rasterizePage(document references.FPDF_DOCUMENT, newDocument references.FPDF_DOCUMENT, pageIndex int, newPageIndex int) error {
+	page := requests.Page{
+		ByIndex: &requests.PageByIndex{
+			Document: document,
+			Index:    pageIndex,
+		},
+	}
+
+	renderFlags := enums.FPDF_RENDER_FLAG(0)
+	if !rasterizeSkipAnnotations {
+		renderFlags |= enums.FPDF_RENDER_FLAG_ANNOT
+	}
+
+	rendered, err := pdf.PdfiumInstance.RenderToFile(&requests.RenderToFile{
+		RenderPageInDPI: &requests.RenderPageInDPI{
+			Page:        page,
+			DPI:         rasterizeDPI,
+			RenderFlags: renderFlags,
+			RenderForm:  !rasterizeSkipForm,
+			Document:    &document,
+		},
+		OutputFormat:  requests.RenderToFileOutputFormatJPG,
+		OutputTarget:  requests.RenderToFileOutputTargetBytes,
+		OutputQuality: rasterizeQuality,
+	})
+	if err != nil {
+		return err
+	}
+
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(*rendered.ImageBytes))
+	if err != nil {
+		return err
+	}
+	imageSize := image.Point{X: imageConfig.Width, Y: imageConfig.Height}
+
+	// The width and height are of the page as it's displayed, so with the
+	// rotation applied, like the rendered image.
+	pageWidth, err := pdf.PdfiumInstance.FPDF_GetPageWidthF(&requests.FPDF_GetPageWidthF{
+		Page: page,
+	})
+	if err != nil {
+		return err
+	}
+
+	pageHeight, err := pdf.PdfiumInstance.FPDF_GetPageHeightF(&requests.FPDF_GetPageHeightF{
+		Page: page,
+	})
+	if err != nil {
+		return err
+	}
+
+	width := float64(pageWidth.PageWidth)
+	height := float64(pageHeight.PageHeight)
+
+	newPage, err := pdf.PdfiumInstance.FPDFPage_New(&requests.FPDFPage_New{
+		Document:  newDocument,
+		PageIndex: newPageIndex,
+		Width:     width,
+		Height:    height,
+	})
+	if err != nil {
+		return err
+	}
+	defer pdf.PdfiumInstance.FPDF_ClosePage(&requests.FPDF_ClosePage{Page: newPage.Page})
+
+	imageObject, err := pdf.PdfiumInstance.FPDFPageObj_NewImageObj(&requests.FPDFPageObj_NewImageObj{
+		Document: newDocument,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The pages are not passed, pdfium only uses them to clear their cache
+	// and the WebAssembly version can't handle them.
+	_, err = pdf.PdfiumInstance.FPDFImageObj_LoadJpegFileInline(&requests.FPDFImageObj_LoadJpegFileInline{
+		ImageObject: imageObject.PageObject,
+		FileData:    *rendered.ImageBytes,
+	})
+	if err != nil {
+		return err
+	}
+
+	// An image fills the unit square, scale it to cover the whole page.
+	_, err = pdf.PdfiumInstance.FPDFImageObj_SetMatrix(&requests.FPDFImageObj_SetMatrix{
+		ImageObject: imageObject.PageObject,
+		Transform:   pdf.Matrix{A: width, D: height}.FSMatrix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = pdf.PdfiumInstance.FPDFPage_InsertObject(&requests.FPDFPage_InsertObject{
+		Page: requests.Page{
+			ByReference: &newPage.Page,
+		},
+		PageObject: imageObject.PageObject,
+	})
+	if err != nil {
+		return err
+	}
+
+	if rasterizeText {
+		textLayerPage, err := getTextLayerPage(pdf.PdfiumInstance, page, imageSize, nil, "")
+		if err != nil {
+			return err
+		}
+
+		for _, line := range textLayerPage.Lines {
+			for _, word := range line.Words {
+				err = addInvisibleText(newDocument, newPage.Page, word.Text, word.Box.PageRect(imageSize.X, imageSize.Y, width, height))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	_, err = pdf.PdfiumInstance.FPDFPage_GenerateContent(&requests.FPDFPage_GenerateContent{
+		Page: requests.Page{
+			ByReference: &newPage.Page,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// addInvisibleText adds a text object that isn't drawn to the page, the text
+// is stretched to fill the rectangle.
+func addInvisibleText(document references.FPDF_DOCUMENT, page references.FPDF_PAGE, text string, rect pdf.Rect) error {
+	textObject, err := pdf.PdfiumInstance.FPDFPageObj_NewTextObj(&requests.FPDFPageObj_NewTextObj{
+		Document: document,
+		Font:     "Helvetica",
+		FontSize: 1,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = pdf.PdfiumInstance.FPDFText_SetText(&requests.FPDFText_SetText{
+		PageObject: textObject.PageObject,
+		Text:       text,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = pdf.PdfiumInstance.FPDFTextObj_SetTextRenderMode(&requests.FPDFTextObj_SetTextRenderMode{
+		PageObject:     textObject.PageObject,
+		TextRenderMode: enums.FPDF_TEXTRENDERMODE_INVISIBLE,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Measure the text in font size 1 to know how much it has to be scaled.
+	bounds, err := pdf.PdfiumInstance.FPDFPageObj_GetBounds(&requests.FPDFPageObj_GetBounds{
+		PageObject: textObject.PageObject,
+	})
+	if err != nil {
+		return err
+	}
+
+	matrix, ok := pdf.FitMatrix(pdf.Rect{
+		Left:   float64(bounds.Left),
+		Bottom: float64(bounds.Bottom),
+		Right:  float64(bounds.Right),
+		Top:    float64(bounds.Top),
+	}, rect)
+	if !ok || rect.Width() <= 0 || rect.Height() <= 0 {
+		// Text without a size, like text that only has characters that the
+		// font doesn't have.
+		pdf.PdfiumInstance.FPDFPageObj_Destroy(&requests.FPDFPageObj_Destroy{PageObject: textObject.PageObject})
+		return nil
+	}
+
+	_, err = pdf.PdfiumInstance.FPDFPageObj_Transform(&requests.FPDFPageObj_Transform{
+		PageObject: textObject.PageObject,
+		Transform:  matrix.FSMatrix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = pdf.PdfiumInstance.FPDFPage_InsertObject(&requests.FPDFPage_InsertObject{
+		Page: requests.Page{
+			ByReference: &page,
+		},
+		PageObject: textObject.PageObject,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
-	"os"
+	"image"
+	"image/color"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/klippa-app/pdfium-cli/pdf"
 
+	"github.com/klippa-app/go-pdfium"
 	"github.com/klippa-app/go-pdfium/enums"
 	"github.com/klippa-app/go-pdfium/requests"
 	"github.com/spf13/cobra"
@@ -26,23 +30,62 @@ var (
 	progressive       bool
 	renderAnnotations bool
 	renderForm        bool
+	tiffCompression   string
+	multiPage         bool
+	colorMode         string
+	bwThreshold       int
+	bwDither          bool
+	region            string
+	background        string
+	renderFlagsOption string
+	colorScheme       string
+	layout            string
+	columns           int
+	tileLabels        string
+	maxCombinedWidth  int
+	maxCombinedHeight int
+	textLayer         string
+	workers           int
+
+	// Parsed from the flags.
+	renderRegion      *pdf.Region
+	renderBackground  color.NRGBA
+	parsedRenderFlags enums.FPDF_RENDER_FLAG
+	renderColorScheme *pdf.ColorScheme
 )
 
 func init() {
 	addGenericPDFOptions(renderCmd)
+	addArchiveOption(renderCmd)
 	addPagesOption("The pages or page ranges to render", renderCmd)
 
 	renderCmd.Flags().IntVarP(&dpi, "dpi", "", 200, "The DPI to render the image in")
-	renderCmd.Flags().StringVarP(&fileType, "file-type", "", "jpeg", "The file type to render in, jpeg or png")
-	renderCmd.Flags().Int64VarP(&maxFileSize, "max-file-size", "", 0, "The maximum file size in bytes for the image, if the rendered image will be larger than this, we will try to compress it until it fits")
+	renderCmd.Flags().StringVarP(&fileType, "file-type", "", "jpeg", "The file type to render in, jpeg, png, webp, tiff or svg. WebP images are lossless. SVG images are converted from the paths, text and images of the page, which keeps them sharp at every zoom level, but the result is an approximation: text is drawn in the fonts of the viewer and shadings, annotations and form fields are not included. For svg the DPI and max width/height only set the display size.")
+	renderCmd.Flags().Int64VarP(&maxFileSize, "max-file-size", "", 0, "The maximum file size in bytes for the image, if the rendered image will be larger than this, we will try to compress it until it fits. For jpeg the quality is lowered. png, webp and tiff are lossless and can't be compressed further, the render fails when they are larger.")
 	renderCmd.Flags().BoolVarP(&combinePages, "combine-pages", "", false, "Combine pages in one image")
 	renderCmd.Flags().IntVarP(&maxWidth, "max-width", "", 0, "The maximum width of the resulting image, this will disable the DPI option. The aspect ratio will be kept. When only the width is given, the height will be calculated automatically.")
 	renderCmd.Flags().IntVarP(&maxHeight, "max-height", "", 0, "The maximum height of the resulting image, this will disable the DPI option. The aspect ratio will be kept. When only the height is given, the width will be calculated automatically.")
 	renderCmd.Flags().IntVarP(&padding, "padding", "", 0, "The padding in pixels between pages when combining pages.")
+	renderCmd.Flags().StringVarP(&layout, "layout", "", "vertical", "The layout when combining pages, vertical (below each other), horizontal (next to each other) or grid.")
+	renderCmd.Flags().IntVarP(&columns, "columns", "", 0, "The amount of columns for the grid layout, by default the grid is about square.")
+	renderCmd.Flags().StringVarP(&tileLabels, "tile-labels", "", "none", "Draw a label under every page when combining pages, none, number (the page number) or label (the page label).")
+	renderCmd.Flags().IntVarP(&maxCombinedWidth, "max-combined-width", "", 0, "The maximum width of the combined image, it will be scaled down when it's wider. Can be combined with max-width and max-height, which are applied to the pages.")
+	renderCmd.Flags().IntVarP(&maxCombinedHeight, "max-combined-height", "", 0, "The maximum height of the combined image, it will be scaled down when it's higher.")
 	renderCmd.Flags().IntVarP(&quality, "quality", "", 95, "The quality to render the image in, only used for jpeg. The option max-file-size may lower this if necessary.")
 	renderCmd.Flags().BoolVarP(&progressive, "progressive", "", false, "Create progressive images, only used for jpeg.")
 	renderCmd.Flags().BoolVarP(&renderAnnotations, "render-annotations", "", false, "Render annotations that are embedded in the PDF.")
 	renderCmd.Flags().BoolVarP(&renderForm, "render-form", "", false, "Render form fields that are embedded in the PDF.")
+	renderCmd.Flags().StringVarP(&tiffCompression, "tiff-compression", "", "lzw", "The compression to use for tiff, none, lzw, deflate or ccitt-g4. ccitt-g4 converts the image to black and white, which is ideal for fax and scanned documents.")
+	renderCmd.Flags().StringVarP(&colorMode, "color-mode", "", "color", "The color mode to render in, color, gray (8-bit grayscale) or bw (1-bit black and white). png and tiff store bw images as 1-bit images.")
+	renderCmd.Flags().IntVarP(&bwThreshold, "bw-threshold", "", 128, "The gray value (0-255) below which pixels become black, only used for color mode bw.")
+	renderCmd.Flags().BoolVarP(&bwDither, "bw-dither", "", false, "Use Floyd-Steinberg dithering for color mode bw, which keeps the impression of gray areas like photos.")
+	renderCmd.Flags().StringVarP(&region, "region", "", "", "Only render a region of the pages, in the format x,y,w,h. The origin is the top left corner of the page. The values are in points (1/72 inch), or fractions of the page size when all values are between 0 and 1, e.g. 0,0.5,1,0.5 for the bottom half. The region is rendered in the given DPI or max width/height, without rendering the rest of the page.")
+	renderCmd.Flags().StringVarP(&background, "background", "", "#ffffff", "The background color of the pages, as hex color (#RRGGBB or #RRGGBBAA) or transparent. Backgrounds with transparency are only supported for png and webp in color mode color.")
+	renderCmd.Flags().StringVarP(&renderFlagsOption, "render-flags", "", "", "Comma separated list of pdfium render flags, options: "+strings.Join(pdf.RenderFlagNames(), ", ")+". For example lcd-text,no-smooth-image or print.")
+	renderCmd.Flags().StringVarP(&colorScheme, "color-scheme", "", "", "Force the colors of paths and text, for example for a dark mode or high contrast view. Images keep their colors. Use a preset (dark or high-contrast), colors (path-fill, path-stroke, text-fill, text-stroke and background), or a preset with colors to override, e.g. dark,text-fill=#ffcc00. The background of the color scheme is used unless the background option is given. Combine with --render-flags convert-fill-to-stroke to keep the boundaries of filled paths visible.")
+	renderCmd.Flags().StringVarP(&textLayer, "text-layer", "", "none", "Write the text of the pages with the position of every word and line in pixels of the rendered image next to every image, none, hocr (hOCR, in a .hocr file) or alto (ALTO XML, in a .xml file). This gives image based viewers a text layer to select and search text. Not available for stdout without archive and combine-pages, in an archive the text layers are files next to the images, with multi-page all pages are in one file.")
+	renderCmd.Flags().IntVarP(&workers, "workers", "", 1, "The amount of workers to render pages in parallel, every worker uses its own pdfium instance and opens the document itself. The output files are still written in page order. When combining pages or writing a multi-page file there is only one output file, so only one worker is used. In builds with CGO, pdfium can only do one thing at a time, so only the image encoding runs in parallel.")
+	renderCmd.Flags().BoolVarP(&multiPage, "multi-page", "", false, "Render all pages as separate pages into one multi-page file, only supported for tiff. The output filename does not need a page placeholder.")
 
 	rootCmd.AddCommand(renderCmd)
 }
@@ -50,7 +93,7 @@ func init() {
 var renderCmd = &cobra.Command{
 	Use:   "render [input] [output]",
 	Short: "Render a PDF into images",
-	Long:  "Render a PDF into images.\n[input] can either be a file path or - for stdin.\n[output] can either be a file path or - for stdout.  or - for stdout. In the case of stdout, multiple files will be delimited by the value of the std-file-delimiter, with a newline before and after it. The output filename should contain a \"%d\" placeholder for the page number when rendering more than one page and when not using the combine-pages option, e.g. render invoice.pdf invoice-%d.jpg, the result for a 2-page PDF will be invoice-1.jpg and invoice-2.jpg.",
+	Long:  "Render a PDF into images.\n[input] can either be a file path or - for stdin.\n[output] can either be a file path or - for stdout. In the case of stdout, multiple files are written in the std-format, by default delimited by the value of the std-file-delimiter, with a newline before and after it. When the output ends with .zip, the files are written into a zip archive with a manifest.json. The output filename should contain a {page} (or \"%d\") or {index} placeholder when rendering more than one page and when not using the combine-pages option, e.g. render invoice.pdf invoice-{page}.jpg, the result for a 2-page PDF will be invoice-1.jpg and invoice-2.jpg. A {label} placeholder is not enough, because page labels don't have to be unique. Missing folders in the output path will be created. " + outputTemplateTokensHelp,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
 			return newExitCodeError(err, ExitCodeInvalidArguments)
@@ -60,10 +103,26 @@ var renderCmd = &cobra.Command{
 			return fmt.Errorf("could not open input file %s: %w", args[0], newExitCodeError(err, ExitCodeInvalidInput))
 		}
 
+		if err := validOutputTemplate(args[1]); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
+		}
+
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		err := pdf.LoadPdfium()
+		if workers < 1 {
+			handleError(cmd, fmt.Errorf("invalid amount of workers %d, should be at least 1\n", workers), ExitCodeInvalidArguments)
+			return
+		}
+
+		// Every worker gets its own instance, next to the instance that
+		// opens the document to validate the options.
+		instances := 1
+		if workers > 1 {
+			instances = workers + 1
+		}
+
+		err := pdf.LoadPdfiumPool(instances)
 		if err != nil {
 			handleError(cmd, fmt.Errorf("could not load pdfium: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
 			return
@@ -99,9 +158,9 @@ var renderCmd = &cobra.Command{
 		renderPages := []requests.Page{}
 		splitPages := strings.Split(*parsedPageRange, ",")
 
-		if len(splitPages) > 1 && !combinePages {
-			if args[1] != stdFilename && !strings.Contains(args[1], "%d") {
-				handleError(cmd, fmt.Errorf("output string %s should contain page pattern %%d\n", args[1]), ExitCodeInvalidArguments)
+		if len(splitPages) > 1 && !combinePages && !multiPage {
+			if args[1] != stdFilename && !isOutputArchive(args[1]) && !pdf.OutputTemplateHasToken(args[1], pdf.OutputTemplatePageTokens...) {
+				handleError(cmd, fmt.Errorf("output string %s should contain page pattern {page}, {index} or %%d\n", args[1]), ExitCodeInvalidArguments)
 				return
 			}
 		}
@@ -116,149 +175,399 @@ var renderCmd = &cobra.Command{
 			})
 		}
 
-		outputFormat := requests.RenderToFileOutputFormatJPG
-		if fileType == "jpeg" {
-			outputFormat = requests.RenderToFileOutputFormatJPG
-		} else if fileType == "png" {
-			outputFormat = requests.RenderToFileOutputFormatPNG
-		} else {
+		ext := ""
+		switch fileType {
+		case "jpeg":
+			ext = "jpg"
+		case "png", "webp":
+			ext = fileType
+		case "tiff":
+			ext = "tif"
+		case "svg":
+			ext = "svg"
+		default:
 			handleError(cmd, fmt.Errorf("invalid file type: %s\n", fileType), ExitCodeInvalidArguments)
 			return
 		}
 
-		renderFlags := enums.FPDF_RENDER_FLAG(0)
-		if renderAnnotations {
-			renderFlags = enums.FPDF_RENDER_FLAG_ANNOT
+		if _, err := pdf.NewTIFFWriter(pdf.TIFFCompression(tiffCompression)); err != nil {
+			handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidArguments)
+			return
 		}
 
-		if combinePages {
-			renderRequest := &requests.RenderToFile{
-				OutputFormat:  outputFormat,
-				MaxFileSize:   maxFileSize,
-				OutputQuality: quality,
-				Progressive:   progressive,
+		if colorMode != "color" && colorMode != "gray" && colorMode != "bw" {
+			handleError(cmd, fmt.Errorf("invalid color mode: %s\n", colorMode), ExitCodeInvalidArguments)
+			return
+		}
+
+		if bwThreshold < 0 || bwThreshold > 255 {
+			handleError(cmd, fmt.Errorf("invalid bw threshold %d, should be between 0 and 255\n", bwThreshold), ExitCodeInvalidArguments)
+			return
+		}
+
+		renderRegion = nil
+		if region != "" {
+			renderRegion, err = pdf.ParseRegion(region)
+			if err != nil {
+				handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidArguments)
+				return
 			}
+		}
 
-			if args[1] == stdFilename {
-				renderRequest.OutputTarget = requests.RenderToFileOutputTargetBytes
-			} else {
-				renderRequest.OutputTarget = requests.RenderToFileOutputTargetFile
-				renderRequest.TargetFilePath = args[1]
+		renderBackground, err = pdf.ParseColor(background)
+		if err != nil {
+			handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidArguments)
+			return
+		}
+
+		renderColorScheme = nil
+		if colorScheme != "" {
+			renderColorScheme, err = pdf.ParseColorScheme(colorScheme)
+			if err != nil {
+				handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidArguments)
+				return
 			}
 
-			if maxWidth > 0 || maxHeight > 0 {
-				renderPagesInPixels := []requests.RenderPageInPixels{}
-				for _, renderPage := range renderPages {
-					renderPagesInPixels = append(renderPagesInPixels, requests.RenderPageInPixels{
-						Page:        renderPage,
-						Width:       maxWidth,
-						Height:      maxHeight,
-						RenderFlags: renderFlags,
-						RenderForm:  renderForm,
-					})
-				}
-				renderRequest.RenderPagesInPixels = &requests.RenderPagesInPixels{
-					Pages:   renderPagesInPixels,
-					Padding: padding,
-				}
-			} else {
-				renderPagesInDPI := []requests.RenderPageInDPI{}
-				for _, renderPage := range renderPages {
-					renderPagesInDPI = append(renderPagesInDPI, requests.RenderPageInDPI{
-						Page:        renderPage,
-						DPI:         dpi,
-						RenderFlags: renderFlags,
-						RenderForm:  renderForm,
-					})
-				}
-				renderRequest.RenderPagesInDPI = &requests.RenderPagesInDPI{
-					Pages:   renderPagesInDPI,
-					Padding: padding,
-				}
+			if !cmd.Flags().Changed("background") {
+				renderBackground = renderColorScheme.Background
+			}
+		}
+
+		if renderBackground.A < 255 && (colorMode != "color" || (fileType != "png" && fileType != "webp" && fileType != "svg")) {
+			handleError(cmd, fmt.Errorf("a background with transparency is only supported for file type png, webp and svg in color mode color\n"), ExitCodeInvalidArguments)
+			return
+		}
+
+		parsedRenderFlags, err = pdf.ParseRenderFlags(renderFlagsOption)
+		if err != nil {
+			handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidArguments)
+			return
+		}
+
+		if layout != string(pdf.LayoutVertical) && layout != string(pdf.LayoutHorizontal) && layout != string(pdf.LayoutGrid) {
+			handleError(cmd, fmt.Errorf("invalid layout: %s\n", layout), ExitCodeInvalidArguments)
+			return
+		}
+
+		if tileLabels != "none" && tileLabels != "number" && tileLabels != "label" {
+			handleError(cmd, fmt.Errorf("invalid tile labels: %s\n", tileLabels), ExitCodeInvalidArguments)
+			return
+		}
+
+		if multiPage && fileType != "tiff" {
+			handleError(cmd, fmt.Errorf("the option multi-page is only supported for file type tiff\n"), ExitCodeInvalidArguments)
+			return
+		}
+
+		if fileType == "svg" && combinePages {
+			handleError(cmd, fmt.Errorf("the option combine-pages is not supported for file type svg\n"), ExitCodeInvalidArguments)
+			return
+		}
+
+		if fileType == "svg" && colorMode != "color" {
+			handleError(cmd, fmt.Errorf("the color modes gray and bw are not supported for file type svg\n"), ExitCodeInvalidArguments)
+			return
+		}
+
+		if textLayer != "none" && pdf.TextLayerFormat(textLayer) != pdf.TextLayerFormatHOCR && pdf.TextLayerFormat(textLayer) != pdf.TextLayerFormatALTO {
+			handleError(cmd, fmt.Errorf("invalid text layer: %s\n", textLayer), ExitCodeInvalidArguments)
+			return
+		}
+
+		if textLayer != "none" && ((args[1] == stdFilename && !isOutputArchive(args[1])) || combinePages) {
+			handleError(cmd, fmt.Errorf("the option text-layer can't be used with stdout without archive or combine-pages\n"), ExitCodeInvalidArguments)
+			return
+		}
+
+		if multiPage && combinePages {
+			handleError(cmd, fmt.Errorf("the options multi-page and combine-pages can't be combined\n"), ExitCodeInvalidArguments)
+			return
+		}
+
+		outputTemplateValues := func(index int, pageIndex int) pdf.OutputTemplateValues {
+			values := pdf.OutputTemplateValues{
+				Input: pdf.OutputTemplateInputName(args[0]),
+				Page:  pageIndex + 1,
+				Index: index + 1,
+				Ext:   ext,
+			}
+			if pdf.OutputTemplateHasToken(args[1], "label") {
+				values.Label = getPageLabel(pdf.PdfiumInstance, document.Document, pageIndex)
+			}
+			return values
+		}
+
+		// Every output file contains one page, unless the pages are combined
+		// into one image or written into one multi-page file.
+		outputs := [][]requests.Page{}
+		if combinePages || multiPage {
+			outputs = append(outputs, renderPages)
+		} else {
+			for _, renderPage := range renderPages {
+				outputs = append(outputs, []requests.Page{renderPage})
+			}
+		}
+
+		outputDescription := func(outputPages []requests.Page) string {
+			if len(outputPages) > 1 {
+				return "pages " + *parsedPageRange
 			}
+			return "page " + strconv.Itoa(outputPages[0].ByIndex.Index+1)
+		}
+
+		// The files on stdout and in archives are named by a fixed template.
+		outputTemplate := args[1]
+		outputArchive, err := newOutputArchive(cmd, args[1], args[0], pageCount.PageCount)
+		if err != nil {
+			handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidArguments)
+			return
+		}
+		if outputArchive != nil {
+			outputTemplate = "{input}-{page}.{ext}"
+			if len(outputs) == 1 && len(outputs[0]) > 1 {
+				outputTemplate = "{input}.{ext}"
+			}
+		}
 
-			result, err := pdf.PdfiumInstance.RenderToFile(renderRequest)
+		outputPaths := []string{}
+		for i, outputPages := range outputs {
+			newFilePath, err := pdf.ExpandOutputTemplate(outputTemplate, outputTemplateValues(i, outputPages[0].ByIndex.Index))
 			if err != nil {
-				handleError(cmd, fmt.Errorf("could not render pages %s into image: %w\n", *parsedPageRange, newPdfiumError(err)), ExitCodePdfiumError)
+				handleError(cmd, fmt.Errorf("could not create output path for %s: %w\n", outputDescription(outputPages), err), ExitCodeInvalidArguments)
 				return
 			}
+			outputPaths = append(outputPaths, newFilePath)
+		}
+
+		// Render the outputs in parallel when there are multiple workers, the
+		// results are still written in page order.
+		var workerResults []chan *renderedOutput
+		var workerDone func()
+		if workers > 1 && len(outputs) > 1 {
+			var stopWorkers func()
+			workerResults, workerDone, stopWorkers = startRenderWorkers(args[0], workers, outputs, outputPaths)
+			defer stopWorkers()
+		}
 
-			if args[1] != stdFilename {
-				cmd.Println(fmt.Errorf("Rendered pages %s into %s\n", *parsedPageRange, args[1]))
+		for i, outputPages := range outputs {
+			pagesDescription := outputDescription(outputPages)
+			newFilePath := outputPaths[i]
+
+			var result *renderedOutput
+			if workerResults != nil {
+				result = <-workerResults[i]
+				workerDone()
 			} else {
-				_, err = os.Stdout.Write(*result.ImageBytes)
-				if err != nil {
-					handleError(cmd, fmt.Errorf("could not render pages %s into image: %w\n", *parsedPageRange, err), ExitCodeInvalidOutput)
+				result = renderOutputFile(pdf.PdfiumInstance, outputPages, filepath.Base(newFilePath))
+			}
+
+			if result.err != nil {
+				if isExperimentalError(result.err) {
+					if fileType == "svg" {
+						handleError(cmd, fmt.Errorf("SVG support is not enabled in your build, build with the build tag pdfium_experimental to enable!\n"), ExitCodeExperimental)
+						return
+					}
+					if renderColorScheme != nil {
+						handleError(cmd, fmt.Errorf("Color scheme support is not enabled in your build, build with the build tag pdfium_experimental to enable!\n"), ExitCodeExperimental)
+						return
+					}
+					handleError(cmd, fmt.Errorf("could not render %s into image, a feature is used that is not enabled in your build: %w\n", pagesDescription, result.err), ExitCodeExperimental)
 					return
 				}
+				handleError(cmd, fmt.Errorf("could not render %s into image: %w\n", pagesDescription, newPdfiumError(result.err)), ExitCodePdfiumError)
+				return
 			}
-		} else {
-			for i, renderPage := range renderPages {
-				page := strconv.Itoa(renderPage.ByIndex.Index + 1)
-				newFilePath := strings.Replace(args[1], "%d", page, -1)
-
-				renderRequest := &requests.RenderToFile{
-					OutputFormat:  outputFormat,
-					MaxFileSize:   maxFileSize,
-					OutputQuality: quality,
-					Progressive:   progressive,
+
+			if outputArchive == nil {
+				outFile, err := createOutputFile(newFilePath)
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not create output file %s: %w\n", newFilePath, err), ExitCodeInvalidOutput)
+					return
 				}
 
-				if args[1] == stdFilename {
-					renderRequest.OutputTarget = requests.RenderToFileOutputTargetBytes
-				} else {
-					renderRequest.OutputTarget = requests.RenderToFileOutputTargetFile
-					renderRequest.TargetFilePath = newFilePath
+				_, err = outFile.Write(result.imageBytes)
+				if closeErr := outFile.Close(); err == nil {
+					err = closeErr
+				}
+				if err != nil {
+					handleError(cmd, fmt.Errorf("could not write %s into %s: %w\n", pagesDescription, newFilePath, err), ExitCodeInvalidOutput)
+					return
 				}
 
-				if maxWidth > 0 || maxHeight > 0 {
-					renderRequest.RenderPagesInPixels = &requests.RenderPagesInPixels{
-						Pages: []requests.RenderPageInPixels{
-							{
-								Page:        renderPage,
-								Width:       maxWidth,
-								Height:      maxHeight,
-								RenderFlags: renderFlags,
-								RenderForm:  renderForm,
-							},
-						},
-						Padding: padding,
+				cmd.Printf("Rendered %s into %s\n", pagesDescription, newFilePath)
+
+				if textLayer != "none" {
+					textLayerPath := strings.TrimSuffix(newFilePath, filepath.Ext(newFilePath)) + "." + pdf.TextLayerFormat(textLayer).Extension()
+					if result.textLayerErr != nil {
+						handleError(cmd, fmt.Errorf("could not get text layer of %s: %w\n", pagesDescription, newPdfiumError(result.textLayerErr)), ExitCodePdfiumError)
+						return
 					}
-				} else {
-					renderRequest.RenderPagesInDPI = &requests.RenderPagesInDPI{
-						Pages: []requests.RenderPageInDPI{
-							{
-								Page:        renderPage,
-								DPI:         dpi,
-								RenderFlags: renderFlags,
-								RenderForm:  renderForm,
-							},
-						},
-						Padding: padding,
+
+					err = writeOutputFile(textLayerPath, result.textLayerBytes)
+					if err != nil {
+						handleError(cmd, fmt.Errorf("could not write text layer of %s into %s: %w\n", pagesDescription, textLayerPath, err), ExitCodeInvalidOutput)
+						return
 					}
+
+					cmd.Printf("Wrote text layer of %s into %s\n", pagesDescription, textLayerPath)
+				}
+			} else {
+				page := 0
+				if len(outputPages) == 1 {
+					page = outputPages[0].ByIndex.Index + 1
 				}
 
-				result, err := pdf.PdfiumInstance.RenderToFile(renderRequest)
+				err = outputArchive.WriteFile(newStdFile(newFilePath, i+1, page), result.imageBytes)
 				if err != nil {
-					handleError(cmd, fmt.Errorf("could not render page %s into image: %w\n", page, newPdfiumError(err)), ExitCodePdfiumError)
+					handleError(cmd, fmt.Errorf("could not render %s into image: %w\n", pagesDescription, err), ExitCodeInvalidOutput)
 					return
 				}
 
-				if args[1] != stdFilename {
-					cmd.Printf("Rendered page %s into %s\n", page, newFilePath)
-				} else {
-					if i > 0 {
-						os.Stdout.WriteString("\n")
-						os.Stdout.WriteString(stdFileDelimiter)
-						os.Stdout.WriteString("\n")
+				if textLayer != "none" {
+					if result.textLayerErr != nil {
+						handleError(cmd, fmt.Errorf("could not get text layer of %s: %w\n", pagesDescription, newPdfiumError(result.textLayerErr)), ExitCodePdfiumError)
+						return
 					}
-					_, err = os.Stdout.Write(*result.ImageBytes)
+
+					textLayerName := strings.TrimSuffix(newFilePath, filepath.Ext(newFilePath)) + "." + pdf.TextLayerFormat(textLayer).Extension()
+					err = outputArchive.WriteFile(newStdFile(textLayerName, i+1, page), result.textLayerBytes)
 					if err != nil {
-						handleError(cmd, fmt.Errorf("could not render page %s into image: %w\n", page, err), ExitCodeInvalidOutput)
+						handleError(cmd, fmt.Errorf("could not write text layer of %s: %w\n", pagesDescription, err), ExitCodeInvalidOutput)
 						return
 					}
 				}
 			}
 		}
+
+		outputArchive.finish(cmd)
 	},
 }
+
+// renderedOutput is the result of rendering one output file.
+type renderedOutput struct {
+	imageBytes     []byte
+	textLayerBytes []byte
+	err            error // The error of rendering the image.
+	textLayerErr   error // The error of getting the text layer.
+}
+
+// renderOutputFile renders the pages of one output file, and its text layer
+// when enabled. The image name is the file name of the image for the text
+// layer.
+func renderOutputFile(instance pdfium.Pdfium, outputPages []requests.Page, imageName string) *renderedOutput {
+	imageBytes, imageSizes, err := renderOutput(instance, outputPages)
+	if err != nil {
+		return &renderedOutput{err: err}
+	}
+
+	result := &renderedOutput{imageBytes: imageBytes}
+	if textLayer != "none" {
+		result.textLayerBytes, result.textLayerErr = getTextLayer(instance, imageName, outputPages, imageSizes)
+	}
+
+	return result
+}
+
+// renderOutput renders the pages into the bytes of one output file. The pages
+// are combined into one image, or when multi-page is enabled, every page
+// becomes a page of the file. It also returns the size in pixels of every
+// image in the file.
+func renderOutput(instance pdfium.Pdfium, renderPages []requests.Page) ([]byte, []image.Point, error) {
+	// SVG images are not rendered but converted from the page objects, pages
+	// can't be combined so there is only one page.
+	if fileType == "svg" {
+		svg, size, err := renderPageToSVG(instance, renderPages[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		return svg, []image.Point{size}, nil
+	}
+
+	// Use the renderer of pdfium for the file types that it supports, it
+	// supports progressive jpeg when built with turbojpeg.
+	if (fileType == "jpeg" || fileType == "png") && !multiPage && colorMode == "color" && !useBitmapRenderer() && !useLayout() {
+		renderRequest := &requests.RenderToFile{
+			OutputFormat:  requests.RenderToFileOutputFormatJPG,
+			OutputTarget:  requests.RenderToFileOutputTargetBytes,
+			MaxFileSize:   maxFileSize,
+			OutputQuality: quality,
+			Progressive:   progressive,
+		}
+
+		if fileType == "png" {
+			renderRequest.OutputFormat = requests.RenderToFileOutputFormatPNG
+		}
+
+		if maxWidth > 0 || maxHeight > 0 {
+			renderPagesInPixels := []requests.RenderPageInPixels{}
+			for _, renderPage := range renderPages {
+				renderPagesInPixels = append(renderPagesInPixels, requests.RenderPageInPixels{
+					Page:        renderPage,
+					Width:       maxWidth,
+					Height:      maxHeight,
+					RenderFlags: getRenderFlags(),
+					RenderForm:  renderForm,
+				})
+			}
+			renderRequest.RenderPagesInPixels = &requests.RenderPagesInPixels{
+				Pages:   renderPagesInPixels,
+				Padding: padding,
+			}
+		} else {
+			renderPagesInDPI := []requests.RenderPageInDPI{}
+			for _, renderPage := range renderPages {
+				renderPagesInDPI = append(renderPagesInDPI, requests.RenderPageInDPI{
+					Page:        renderPage,
+					DPI:         dpi,
+					RenderFlags: getRenderFlags(),
+					RenderForm:  renderForm,
+				})
+			}
+			renderRequest.RenderPagesInDPI = &requests.RenderPagesInDPI{
+				Pages:   renderPagesInDPI,
+				Padding: padding,
+			}
+		}
+
+		result, err := instance.RenderToFile(renderRequest)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		imageConfig, _, err := image.DecodeConfig(bytes.NewReader(*result.ImageBytes))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return *result.ImageBytes, []image.Point{{X: imageConfig.Width, Y: imageConfig.Height}}, nil
+	}
+
+	images := []*renderedImage{}
+	if multiPage {
+		for _, renderPage := range renderPages {
+			renderedPage, err := renderPagesToImage(instance, []requests.Page{renderPage})
+			if err != nil {
+				return nil, nil, err
+			}
+			images = append(images, renderedPage)
+		}
+	} else {
+		renderedPages, err := renderPagesToImage(instance, renderPages)
+		if err != nil {
+			return nil, nil, err
+		}
+		images = append(images, renderedPages)
+	}
+
+	sizes := []image.Point{}
+	for _, renderedImage := range images {
+		sizes = append(sizes, renderedImage.Image.Bounds().Size())
+	}
+
+	imageBytes, err := encodeRenderedImages(images)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return imageBytes, sizes, nil
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/enums"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/structs"
+)
+
+// renderPageRegion renders a region of a page with the render options. Only
+// the region is rendered, so rendering a small region at a high DPI doesn't
+// need the memory of the full page. When a size is given, the region is fit
+// in it instead of using the DPI or max size. It returns the image and the
+// scale from points to pixels.
+func renderPageRegion(instance pdfium.Pdfium, page requests.Page, region pdf.Region, size image.Point) (*image.NRGBA, float64, error) {
+	pageWidth, pageHeight, regionInPoints, err := getPageRegionInPoints(instance, page, region)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	width, height, scale := regionInPoints.RenderSize(dpi, maxWidth, maxHeight)
+	if size != (image.Point{}) {
+		width, height, scale = regionInPoints.RenderSize(0, size.X, size.Y)
+	}
+
+	bitmap, err := instance.FPDFBitmap_Create(&requests.FPDFBitmap_Create{
+		Width:  width,
+		Height: height,
+		Alpha:  1,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer instance.FPDFBitmap_Destroy(&requests.FPDFBitmap_Destroy{
+		Bitmap: bitmap.Bitmap,
+	})
+
+	// The color is in the format ARGB, but because we render in reverse byte
+	// order, red and blue have to be swapped.
+	_, err = instance.FPDFBitmap_FillRect(&requests.FPDFBitmap_FillRect{
+		Bitmap: bitmap.Bitmap,
+		Width:  width,
+		Height: height,
+		Color:  uint64(renderBackground.A)<<24 | uint64(renderBackground.B)<<16 | uint64(renderBackground.G)<<8 | uint64(renderBackground.R),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Write the bytes in reverse order so that BGRA becomes RGBA.
+	renderFlags := getRenderFlags() | enums.FPDF_RENDER_FLAG_REVERSE_BYTE_ORDER
+
+	if renderColorScheme != nil {
+		err = renderPageWithColorScheme(instance, bitmap.Bitmap, page, -regionInPoints.X*scale, -regionInPoints.Y*scale, pageWidth*scale, pageHeight*scale, renderFlags)
+		if err != nil {
+			return nil, 0, err
+		}
+	} else {
+		// The matrix moves the region to the origin of the bitmap and scales
+		// it, everything outside the bitmap is clipped.
+		_, err = instance.FPDF_RenderPageBitmapWithMatrix(&requests.FPDF_RenderPageBitmapWithMatrix{
+			Bitmap: bitmap.Bitmap,
+			Page:   page,
+			Matrix: structs.FPDF_FS_MATRIX{
+				A: float32(scale),
+				D: float32(scale),
+				E: float32(-regionInPoints.X * scale),
+				F: float32(-regionInPoints.Y * scale),
+			},
+			Clipping: structs.FPDF_FS_RECTF{
+				Right:  float32(width),
+				Bottom: float32(height),
+			},
+			Flags: renderFlags,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if renderForm {
+		err = renderPageForm(instance, bitmap.Bitmap, page, -regionInPoints.X*scale, -regionInPoints.Y*scale, pageWidth*scale, pageHeight*scale, renderFlags)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	stride, err := instance.FPDFBitmap_GetStride(&requests.FPDFBitmap_GetStride{
+		Bitmap: bitmap.Bitmap,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	buffer, err := instance.FPDFBitmap_GetBuffer(&requests.FPDFBitmap_GetBuffer{
+		Bitmap: bitmap.Bitmap,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(buffer.Buffer) < stride.Stride*(height-1)+width*4 {
+		return nil, 0, errors.New("bitmap buffer is too small")
+	}
+
+	// Copy the image, the buffer is released with the bitmap. PDFium's
+	// bitmaps have straight (non-premultiplied) alpha.
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		copy(img.Pix[y*img.Stride:(y+1)*img.Stride], buffer.Buffer[y*stride.Stride:])
+	}
+
+	return img, scale, nil
+}
+
+// getPageRegionInPoints returns the size of the page and the region on the
+// page in points.
+func getPageRegionInPoints(instance pdfium.Pdfium, page requests.Page, region pdf.Region) (float64, float64, pdf.Region, error) {
+	pageWidth, err := instance.FPDF_GetPageWidthF(&requests.FPDF_GetPageWidthF{
+		Page: page,
+	})
+	if err != nil {
+		return 0, 0, pdf.Region{}, err
+	}
+
+	pageHeight, err := instance.FPDF_GetPageHeightF(&requests.FPDF_GetPageHeightF{
+		Page: page,
+	})
+	if err != nil {
+		return 0, 0, pdf.Region{}, err
+	}
+
+	regionInPoints, err := region.InPoints(float64(pageWidth.PageWidth), float64(pageHeight.PageHeight))
+	if err != nil {
+		return 0, 0, pdf.Region{}, err
+	}
+
+	return float64(pageWidth.PageWidth), float64(pageHeight.PageHeight), regionInPoints, nil
+}
+
+// renderPageWithColorScheme renders a page on a bitmap with the color scheme
+// of the render options, the page is placed at the given position and size in
+// pixels. Color schemes are only supported by the progressive renderer, which
+// we let run until it's done.
+func renderPageWithColorScheme(instance pdfium.Pdfium, bitmap references.FPDF_BITMAP, page requests.Page, x, y, width, height float64, renderFlags enums.FPDF_RENDER_FLAG) error {
+	fpdfColorScheme := renderColorScheme.FPDFColorScheme()
+	neverPause := func() bool {
+		return false
+	}
+
+	renderStatus, err := instance.FPDF_RenderPageBitmapWithColorScheme_Start(&requests.FPDF_RenderPageBitmapWithColorScheme_Start{
+		Bitmap:                 bitmap,
+		Page:                   page,
+		StartX:                 int(x),
+		StartY:                 int(y),
+		SizeX:                  int(width),
+		SizeY:                  int(height),
+		Flags:                  renderFlags,
+		ColorScheme:            &fpdfColorScheme,
+		NeedToPauseNowCallback: neverPause,
+	})
+	if err != nil {
+		return err
+	}
+	defer instance.FPDF_RenderPage_Close(&requests.FPDF_RenderPage_Close{
+		Page: page,
+	})
+
+	status := renderStatus.RenderStatus
+	for status == enums.FPDF_RENDER_STATUS_TOBECONTINUED {
+		continueStatus, err := instance.FPDF_RenderPage_Continue(&requests.FPDF_RenderPage_Continue{
+			Page:                   page,
+			NeedToPauseNowCallback: neverPause,
+		})
+		if err != nil {
+			return err
+		}
+		status = continueStatus.RenderStatus
+	}
+
+	if status != enums.FPDF_RENDER_STATUS_DONE {
+		return fmt.Errorf("could not render page with color scheme, render status %d", status)
+	}
+
+	return nil
+}
+
+// renderPageForm draws the form fields of a page on a bitmap, the page is
+// placed at the given position and size in pixels.
+func renderPageForm(instance pdfium.Pdfium, bitmap references.FPDF_BITMAP, page requests.Page, x, y, width, height float64, renderFlags enums.FPDF_RENDER_FLAG) error {
+	if page.ByIndex == nil {
+		return errors.New("document is required when rendering forms")
+	}
+
+	formFillEnvironment, err := instance.FPDFDOC_InitFormFillEnvironment(&requests.FPDFDOC_InitFormFillEnvironment{
+		Document:     page.ByIndex.Document,
+		FormFillInfo: drawOnlyFormFillInfo(),
+	})
+	if err != nil {
+		return err
+	}
+	defer instance.FPDFDOC_ExitFormFillEnvironment(&requests.FPDFDOC_ExitFormFillEnvironment{
+		FormHandle: formFillEnvironment.FormHandle,
+	})
+
+	_, err = instance.FPDF_FFLDraw(&requests.FPDF_FFLDraw{
+		FormHandle: formFillEnvironment.FormHandle,
+		Bitmap:     bitmap,
+		Page:       page,
+		StartX:     int(x),
+		StartY:     int(y),
+		SizeX:      int(width),
+		SizeY:      int(height),
+		Flags:      renderFlags,
+	})
+	return err
+}
+
+// drawOnlyFormFillInfo returns the form fill callbacks that pdfium requires,
+// we only draw the forms so nothing has to happen in them.
+func drawOnlyFormFillInfo() structs.FPDF_FORMFILLINFO {
+	return structs.FPDF_FORMFILLINFO{
+		FFI_Invalidate:         func(page references.FPDF_PAGE, left, top, right, bottom float64) {},
+		FFI_SetCursor:          func(cursorType enums.FXCT) {},
+		FFI_SetTimer:           func(elapse int, timerFunc func(idEvent int)) int { return 0 },
+		FFI_KillTimer:          func(timerID int) {},
+		FFI_GetLocalTime:       func() structs.FPDF_SYSTEMTIME { return structs.FPDF_SYSTEMTIME{} },
+		FFI_GetPage:            func(document references.FPDF_DOCUMENT, index int) *references.FPDF_PAGE { return nil },
+		FFI_GetRotation:        func(page references.FPDF_PAGE) enums.FPDF_PAGE_ROTATION { return enums.FPDF_PAGE_ROTATION_NONE },
+		FFI_ExecuteNamedAction: func(namedAction string) {},
+	}
+}
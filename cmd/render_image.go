@@ -0,0 +1,384 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/enums"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/responses"
+)
+
+var errMaxFileSizeExceeded = errors.New("PDF image would exceed maximum filesize")
+
+// renderedImage is the image of one page or of multiple combined pages.
+type renderedImage struct {
+	Image      image.Image
+	Resolution float64 // The resolution of the image in pixels per inch.
+}
+
+// getRenderFlags returns the pdfium render flags for the render options.
+func getRenderFlags() enums.FPDF_RENDER_FLAG {
+	renderFlags := parsedRenderFlags
+	if renderAnnotations {
+		renderFlags |= enums.FPDF_RENDER_FLAG_ANNOT
+	}
+	if colorMode == "gray" || colorMode == "bw" {
+		renderFlags |= enums.FPDF_RENDER_FLAG_GRAYSCALE
+	}
+	return renderFlags
+}
+
+// renderPagesToImage renders the pages into one image with the render
+// options, multiple pages are placed below each other unless another layout
+// is given. The image is copied out of pdfium, so it stays valid after the
+// next render. Depending on the color mode, the image is an RGBA, gray or
+// black and white image.
+func renderPagesToImage(instance pdfium.Pdfium, renderPages []requests.Page) (*renderedImage, error) {
+	var result image.Image
+	var resolution float64
+	var err error
+	if useLayout() {
+		result, resolution, err = renderPagesInLayout(instance, renderPages)
+	} else if useBitmapRenderer() {
+		result, resolution, err = renderPageRegionsToImage(instance, renderPages, image.Point{})
+	} else {
+		result, resolution, err = renderPagesToRGBA(instance, renderPages, image.Point{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var img image.Image = result
+	if colorMode == "gray" {
+		img = pdf.ToGray(result)
+	} else if colorMode == "bw" {
+		img = pdf.ToBitonal(result, uint8(bwThreshold), bwDither)
+	}
+
+	return &renderedImage{
+		Image:      img,
+		Resolution: resolution,
+	}, nil
+}
+
+// useLayout returns whether combined pages have to be placed with one of the
+// layout options, instead of below each other by the renderer.
+func useLayout() bool {
+	return combinePages && (pdf.LayoutType(layout) != pdf.LayoutVertical || tileLabels != "none" || maxCombinedWidth > 0 || maxCombinedHeight > 0)
+}
+
+// renderPagesInLayout renders every page separately and combines them with
+// the layout options. When the combined image has a max width or height, the
+// pages are rendered at the size they get in the combined image. It returns
+// the image and its resolution.
+func renderPagesInLayout(instance pdfium.Pdfium, renderPages []requests.Page) (*image.NRGBA, float64, error) {
+	pageLayout := pdf.Layout{
+		Type:       pdf.LayoutType(layout),
+		Columns:    columns,
+		Padding:    padding,
+		Background: renderBackground,
+		MaxWidth:   maxCombinedWidth,
+		MaxHeight:  maxCombinedHeight,
+	}
+
+	pageRegion := pdf.Region{Width: 1, Height: 1, Fractions: true}
+	if renderRegion != nil {
+		pageRegion = *renderRegion
+	}
+
+	tileSizes := []image.Point{}
+	for _, renderPage := range renderPages {
+		_, _, regionInPoints, err := getPageRegionInPoints(instance, renderPage, pageRegion)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		width, height, _ := regionInPoints.RenderSize(dpi, maxWidth, maxHeight)
+		tileSizes = append(tileSizes, image.Pt(width, height))
+	}
+
+	tileScale, err := pdf.LayoutScale(tileSizes, tileLabels != "none", pageLayout)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tiles := []pdf.Tile{}
+	resolution := float64(0)
+	for i, renderPage := range renderPages {
+		// Without a scale the pages are rendered with the DPI or max size.
+		tileSize := image.Point{}
+		if tileScale < 1 {
+			tileSize = image.Pt(max(1, int(float64(tileSizes[i].X)*tileScale)), max(1, int(float64(tileSizes[i].Y)*tileScale)))
+		}
+
+		var img image.Image
+		var pageResolution float64
+		var err error
+		if useBitmapRenderer() {
+			img, pageResolution, err = renderPageRegionsToImage(instance, []requests.Page{renderPage}, tileSize)
+		} else {
+			img, pageResolution, err = renderPagesToRGBA(instance, []requests.Page{renderPage}, tileSize)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if i == 0 {
+			resolution = pageResolution
+		}
+
+		tile := pdf.Tile{
+			Image: img,
+		}
+		if tileLabels == "number" {
+			tile.Label = strconv.Itoa(renderPage.ByIndex.Index + 1)
+		} else if tileLabels == "label" {
+			tile.Label = getPageLabel(instance, renderPage.ByIndex.Document, renderPage.ByIndex.Index)
+		}
+
+		tiles = append(tiles, tile)
+	}
+
+	result, scale, err := pdf.ComposeTiles(tiles, pageLayout)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, resolution * scale, nil
+}
+
+// useBitmapRenderer returns whether the pages have to be rendered with our
+// own bitmap renderer, because the renderer of pdfium only renders full pages
+// on a white background without color scheme.
+func useBitmapRenderer() bool {
+	return renderRegion != nil || renderColorScheme != nil || renderBackground != color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+}
+
+// renderPageRegionsToImage renders the region of every page, or the full page
+// when no region is given, and places them below each other. When a size is
+// given, the regions are fit in it instead of using the DPI or max size. It
+// returns the image and its resolution.
+func renderPageRegionsToImage(instance pdfium.Pdfium, renderPages []requests.Page, size image.Point) (*image.NRGBA, float64, error) {
+	pageRegion := pdf.Region{Width: 1, Height: 1, Fractions: true}
+	if renderRegion != nil {
+		pageRegion = *renderRegion
+	}
+
+	images := []*image.NRGBA{}
+	resolution := float64(0)
+	width := 0
+	height := 0
+	for i, renderPage := range renderPages {
+		img, scale, err := renderPageRegion(instance, renderPage, pageRegion, size)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if i == 0 {
+			resolution = scale * 72
+		} else {
+			height += padding
+		}
+
+		images = append(images, img)
+		width = max(width, img.Bounds().Dx())
+		height += img.Bounds().Dy()
+	}
+
+	if len(images) == 1 {
+		return images[0], resolution, nil
+	}
+
+	result := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(result, result.Bounds(), image.NewUniform(renderBackground), image.Point{}, draw.Src)
+	offset := 0
+	for _, img := range images {
+		draw.Draw(result, img.Bounds().Add(image.Pt(0, offset)), img, image.Point{}, draw.Src)
+		offset += img.Bounds().Dy() + padding
+	}
+
+	return result, resolution, nil
+}
+
+// renderPagesToRGBA renders the pages with the renderer of pdfium and places
+// them below each other on a white background. When a size is given, the
+// pages are fit in it instead of using the DPI or max size. It returns the
+// image and its resolution.
+func renderPagesToRGBA(instance pdfium.Pdfium, renderPages []requests.Page, size image.Point) (*image.RGBA, float64, error) {
+	var renderedRGBA *image.RGBA
+	var renderedPages []responses.RenderPagesPage
+
+	renderWidth, renderHeight := maxWidth, maxHeight
+	if size != (image.Point{}) {
+		renderWidth, renderHeight = size.X, size.Y
+	}
+
+	if renderWidth > 0 || renderHeight > 0 {
+		renderPagesInPixels := []requests.RenderPageInPixels{}
+		for _, renderPage := range renderPages {
+			renderPagesInPixels = append(renderPagesInPixels, requests.RenderPageInPixels{
+				Page:        renderPage,
+				Width:       renderWidth,
+				Height:      renderHeight,
+				RenderFlags: getRenderFlags(),
+				RenderForm:  renderForm,
+			})
+		}
+
+		resp, err := instance.RenderPagesInPixels(&requests.RenderPagesInPixels{
+			Pages:   renderPagesInPixels,
+			Padding: padding,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		defer resp.Cleanup()
+
+		renderedRGBA = resp.Result.Image
+		renderedPages = resp.Result.Pages
+	} else {
+		renderPagesInDPI := []requests.RenderPageInDPI{}
+		for _, renderPage := range renderPages {
+			renderPagesInDPI = append(renderPagesInDPI, requests.RenderPageInDPI{
+				Page:        renderPage,
+				DPI:         dpi,
+				RenderFlags: getRenderFlags(),
+				RenderForm:  renderForm,
+			})
+		}
+
+		resp, err := instance.RenderPagesInDPI(&requests.RenderPagesInDPI{
+			Pages:   renderPagesInDPI,
+			Padding: padding,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		defer resp.Cleanup()
+
+		renderedRGBA = resp.Result.Image
+		renderedPages = resp.Result.Pages
+	}
+
+	hasTransparency := false
+	for _, renderedPage := range renderedPages {
+		if renderedPage.HasTransparency {
+			hasTransparency = true
+		}
+	}
+
+	// Place the image on a white background, like a PDF viewer would and
+	// like RenderToFile does. The background is always drawn because the
+	// image has to be copied anyway.
+	result := image.NewRGBA(renderedRGBA.Bounds())
+	draw.Draw(result, result.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	if hasTransparency {
+		// PDFium's FPDFBitmap_BGRA has straight (non-premultiplied) alpha.
+		straightAlphaSrc := &image.NRGBA{
+			Pix:    renderedRGBA.Pix,
+			Stride: renderedRGBA.Stride,
+			Rect:   renderedRGBA.Rect,
+		}
+		draw.Draw(result, result.Bounds(), straightAlphaSrc, straightAlphaSrc.Bounds().Min, draw.Over)
+	} else {
+		draw.Draw(result, result.Bounds(), renderedRGBA, renderedRGBA.Bounds().Min, draw.Over)
+	}
+
+	resolution := float64(dpi)
+	if len(renderedPages) > 0 && (renderWidth > 0 || renderHeight > 0) {
+		resolution = renderedPages[0].PointToPixelRatio * 72
+	}
+
+	return result, resolution, nil
+}
+
+// encodeRenderedImages encodes the images into the file type of the render
+// options. Multiple images are only supported by tiff, they will become the
+// pages of the file. When a max file size is given, jpeg images will be
+// compressed further until it fits.
+func encodeRenderedImages(images []*renderedImage) ([]byte, error) {
+	if len(images) == 0 {
+		return nil, errors.New("no images to encode")
+	}
+
+	if len(images) > 1 && fileType != "tiff" {
+		return nil, fmt.Errorf("file type %s does not support multiple pages", fileType)
+	}
+
+	// Only png and tiff support 1-bit images, the other formats get the
+	// black and white image as gray image.
+	firstImage := images[0].Image
+	if fileType == "jpeg" || fileType == "webp" {
+		if _, ok := firstImage.(*image.Paletted); ok {
+			firstImage = pdf.ToGray(firstImage)
+		}
+	}
+
+	imgBuf := &bytes.Buffer{}
+	switch fileType {
+	case "jpeg":
+		opt := jpeg.Options{
+			Quality: quality,
+		}
+
+		for {
+			if err := jpeg.Encode(imgBuf, firstImage, &opt); err != nil {
+				return nil, err
+			}
+
+			if maxFileSize == 0 || int64(imgBuf.Len()) < maxFileSize {
+				break
+			}
+
+			opt.Quality -= 10
+			if opt.Quality <= 45 {
+				return nil, errMaxFileSizeExceeded
+			}
+
+			imgBuf.Reset()
+		}
+	case "png":
+		if err := png.Encode(imgBuf, firstImage); err != nil {
+			return nil, err
+		}
+	case "webp":
+		if err := nativewebp.Encode(imgBuf, firstImage, nil); err != nil {
+			return nil, err
+		}
+	case "tiff":
+		tiffWriter, err := pdf.NewTIFFWriter(pdf.TIFFCompression(tiffCompression))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, img := range images {
+			if err := tiffWriter.AddPage(img.Image, img.Resolution); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := tiffWriter.WriteTo(imgBuf); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid file type: %s", fileType)
+	}
+
+	if maxFileSize != 0 && int64(imgBuf.Len()) > maxFileSize {
+		return nil, errMaxFileSizeExceeded
+	}
+
+	return imgBuf.Bytes(), nil
+}
@@ -0,0 +1,682 @@
+package cmd
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/enums"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/structs"
+)
+
+// renderPageToSVG converts the objects of a page into an SVG image. Paths,
+// text and images are converted, text is drawn in the font of the viewer when
+// available. Shadings, annotations and form fields are not included. It returns
+// the SVG image and its display size in pixels.
+func renderPageToSVG(instance pdfium.Pdfium, page requests.Page) ([]byte, image.Point, error) {
+	pageWidth, err := instance.FPDF_GetPageWidthF(&requests.FPDF_GetPageWidthF{
+		Page: page,
+	})
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+
+	pageHeight, err := instance.FPDF_GetPageHeightF(&requests.FPDF_GetPageHeightF{
+		Page: page,
+	})
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+
+	region := pdf.Region{Width: 1, Height: 1, Fractions: true}
+	if renderRegion != nil {
+		region = *renderRegion
+	}
+
+	regionInPoints, err := region.InPoints(float64(pageWidth.PageWidth), float64(pageHeight.PageHeight))
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+
+	width, height, _ := regionInPoints.RenderSize(dpi, maxWidth, maxHeight)
+
+	boundingBox, err := instance.FPDF_GetPageBoundingBox(&requests.FPDF_GetPageBoundingBox{
+		Page: page,
+	})
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+
+	rotation, err := instance.FPDFPage_GetRotation(&requests.FPDFPage_GetRotation{
+		Page: page,
+	})
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+
+	pageMatrix := pdf.DisplayMatrix(pdf.Rect{
+		Left:   float64(boundingBox.Rect.Left),
+		Bottom: float64(boundingBox.Rect.Bottom),
+		Right:  float64(boundingBox.Rect.Right),
+		Top:    float64(boundingBox.Rect.Top),
+	}, int(rotation.PageRotation)*90)
+
+	writer := pdf.NewSVGWriter(regionInPoints, width, height, pageMatrix, renderBackground)
+
+	// The text page is needed to get the text of text objects.
+	textPage, err := instance.FPDFText_LoadPage(&requests.FPDFText_LoadPage{
+		Page: page,
+	})
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	defer instance.FPDFText_ClosePage(&requests.FPDFText_ClosePage{
+		TextPage: textPage.TextPage,
+	})
+
+	objectCount, err := instance.FPDFPage_CountObjects(&requests.FPDFPage_CountObjects{
+		Page: page,
+	})
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+
+	for i := 0; i < objectCount.Count; i++ {
+		object, err := instance.FPDFPage_GetObject(&requests.FPDFPage_GetObject{
+			Page:  page,
+			Index: i,
+		})
+		if err != nil {
+			return nil, image.Point{}, err
+		}
+
+		err = addSVGObject(instance, writer, page, textPage.TextPage, object.PageObject, pdf.IdentityMatrix, nil)
+		if err != nil {
+			return nil, image.Point{}, err
+		}
+	}
+
+	var out bytes.Buffer
+	if _, err := writer.WriteTo(&out); err != nil {
+		return nil, image.Point{}, err
+	}
+
+	return out.Bytes(), image.Pt(width, height), nil
+}
+
+// addSVGObject adds a page object to the SVG image and descends into form
+// XObjects recursively. The matrix transforms the coordinates of the parent of
+// the object into page space, the clip paths are the clip paths of the
+// parents in page space.
+func addSVGObject(instance pdfium.Pdfium, writer *pdf.SVGWriter, page requests.Page, textPage references.FPDF_TEXTPAGE, object references.FPDF_PAGEOBJECT, matrix pdf.Matrix, clipPaths [][]pdf.PathSegment) error {
+	objectType, err := instance.FPDFPageObj_GetType(&requests.FPDFPageObj_GetType{
+		PageObject: object,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Objects that are not converted don't need the rest of the information.
+	if objectType.Type != enums.FPDF_PAGEOBJ_PATH && objectType.Type != enums.FPDF_PAGEOBJ_TEXT && objectType.Type != enums.FPDF_PAGEOBJ_IMAGE && objectType.Type != enums.FPDF_PAGEOBJ_FORM {
+		return nil
+	}
+
+	objectClipPaths, err := getObjectClipPaths(instance, object)
+	if err != nil {
+		return err
+	}
+
+	// Copy the clip paths so that siblings don't share the same backing array.
+	clipPaths = append([][]pdf.PathSegment{}, clipPaths...)
+	for _, objectClipPath := range objectClipPaths {
+		clipPaths = append(clipPaths, matrix.TransformPath(objectClipPath))
+	}
+
+	objectMatrix, err := instance.FPDFPageObj_GetMatrix(&requests.FPDFPageObj_GetMatrix{
+		PageObject: object,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The object is placed on its parent, which is placed on the page.
+	pageObjectMatrix := pdf.NewMatrix(objectMatrix.Matrix).Multiply(matrix)
+
+	switch objectType.Type {
+	case enums.FPDF_PAGEOBJ_PATH:
+		segmentCount, err := instance.FPDFPath_CountSegments(&requests.FPDFPath_CountSegments{
+			PageObject: object,
+		})
+		if err != nil {
+			return err
+		}
+
+		segments := []pdf.PathSegment{}
+		for i := 0; i < segmentCount.Count; i++ {
+			pathSegment, err := instance.FPDFPath_GetPathSegment(&requests.FPDFPath_GetPathSegment{
+				PageObject: object,
+				Index:      i,
+			})
+			if err != nil {
+				return err
+			}
+
+			segment, err := getPathSegment(instance, pathSegment.PathSegment)
+			if err != nil {
+				return err
+			}
+			segments = append(segments, segment)
+		}
+
+		drawMode, err := instance.FPDFPath_GetDrawMode(&requests.FPDFPath_GetDrawMode{
+			PageObject: object,
+		})
+		if err != nil {
+			return err
+		}
+
+		style := pdf.SVGStyle{
+			EvenOdd: drawMode.FillMode == enums.FPDF_FILLMODE_ALTERNATE,
+		}
+
+		if drawMode.FillMode != enums.FPDF_FILLMODE_NONE {
+			style.Fill = getObjectFillColor(instance, object)
+			if renderColorScheme != nil {
+				style.Fill = &renderColorScheme.PathFill
+			}
+		}
+
+		if drawMode.Stroke {
+			style.Stroke = getObjectStrokeColor(instance, object)
+			if renderColorScheme != nil {
+				style.Stroke = &renderColorScheme.PathStroke
+			}
+
+			err = getObjectStrokeStyle(instance, object, &style)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Paths without fill and stroke are only used for clipping.
+		if style.Fill == nil && style.Stroke == nil {
+			return nil
+		}
+
+		writer.AddPath(segments, pageObjectMatrix, style, clipPaths)
+	case enums.FPDF_PAGEOBJ_TEXT:
+		return addSVGText(instance, writer, textPage, object, pageObjectMatrix, pdf.NewMatrix(objectMatrix.Matrix), clipPaths)
+	case enums.FPDF_PAGEOBJ_IMAGE:
+		return addSVGImage(instance, writer, page, object, pageObjectMatrix, clipPaths)
+	case enums.FPDF_PAGEOBJ_FORM:
+		formObjectCount, err := instance.FPDFFormObj_CountObjects(&requests.FPDFFormObj_CountObjects{
+			PageObject: object,
+		})
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < formObjectCount.Count; i++ {
+			childObject, err := instance.FPDFFormObj_GetObject(&requests.FPDFFormObj_GetObject{
+				PageObject: object,
+				Index:      uint64(i),
+			})
+			if err != nil {
+				return err
+			}
+
+			err = addSVGObject(instance, writer, page, textPage, childObject.PageObject, pageObjectMatrix, clipPaths)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// addSVGText adds a text object to the SVG image. The text is stretched to
+// the width of the text in the PDF, because the font of the viewer is
+// usually not the same as the font of the PDF.
+func addSVGText(instance pdfium.Pdfium, writer *pdf.SVGWriter, textPage references.FPDF_TEXTPAGE, object references.FPDF_PAGEOBJECT, matrix pdf.Matrix, objectMatrix pdf.Matrix, clipPaths [][]pdf.PathSegment) error {
+	text, err := instance.FPDFTextObj_GetText(&requests.FPDFTextObj_GetText{
+		PageObject: object,
+		TextPage:   textPage,
+	})
+	if err != nil {
+		return err
+	}
+
+	fontSize, err := instance.FPDFTextObj_GetFontSize(&requests.FPDFTextObj_GetFontSize{
+		PageObject: object,
+	})
+	if err != nil {
+		return err
+	}
+
+	svgText := pdf.SVGText{
+		Text:          text.Text,
+		Matrix:        matrix,
+		FontSize:      float64(fontSize.FontSize),
+		GenericFamily: "sans-serif",
+	}
+
+	font, err := instance.FPDFTextObj_GetFont(&requests.FPDFTextObj_GetFont{
+		PageObject: object,
+	})
+	if err == nil {
+		baseFontName, err := instance.FPDFFont_GetBaseFontName(&requests.FPDFFont_GetBaseFontName{
+			Font: font.Font,
+		})
+		if err == nil {
+			svgText.FontFamily, svgText.Bold, svgText.Italic = pdf.ParseFontName(baseFontName.BaseFontName)
+		}
+
+		// The family name of an embedded font is better than the one from
+		// the font name, for other fonts it's the name of the font that
+		// pdfium uses instead.
+		isEmbedded, err := instance.FPDFFont_GetIsEmbedded(&requests.FPDFFont_GetIsEmbedded{
+			Font: font.Font,
+		})
+		if err == nil && isEmbedded.IsEmbedded {
+			familyName, err := instance.FPDFFont_GetFamilyName(&requests.FPDFFont_GetFamilyName{
+				Font: font.Font,
+			})
+			if err == nil && familyName.FamilyName != "" {
+				svgText.FontFamily = familyName.FamilyName
+			}
+		}
+
+		fontFlags, err := instance.FPDFFont_GetFlags(&requests.FPDFFont_GetFlags{
+			Font: font.Font,
+		})
+		if err == nil {
+			svgText.GenericFamily = pdf.GenericFontFamily(svgText.FontFamily, fontFlags.Serif, fontFlags.FixedPitch)
+			svgText.Bold = svgText.Bold || fontFlags.ForceBold
+			svgText.Italic = svgText.Italic || fontFlags.Italic
+		} else {
+			svgText.GenericFamily = pdf.GenericFontFamily(svgText.FontFamily, false, false)
+		}
+
+		fontWeight, err := instance.FPDFFont_GetWeight(&requests.FPDFFont_GetWeight{
+			Font: font.Font,
+		})
+		if err == nil && fontWeight.Weight >= 600 {
+			svgText.Bold = true
+		}
+	}
+
+	// The bounds are in the coordinate space of the parent, which is the
+	// width in text space scaled by the object matrix. Only use it when the
+	// text is not rotated or skewed.
+	if objectMatrix.B == 0 && objectMatrix.C == 0 && objectMatrix.A != 0 {
+		bounds, err := instance.FPDFPageObj_GetBounds(&requests.FPDFPageObj_GetBounds{
+			PageObject: object,
+		})
+		if err == nil {
+			svgText.Width = float64(bounds.Right-bounds.Left) / math.Abs(objectMatrix.A)
+		}
+	}
+
+	renderMode, err := instance.FPDFTextObj_GetTextRenderMode(&requests.FPDFTextObj_GetTextRenderMode{
+		PageObject: object,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The modes 4 to 7 are the modes 0 to 3 with clipping, which is not
+	// supported.
+	mode := enums.FPDF_TEXTRENDERMODE_FILL
+	if renderMode.TextRenderMode != enums.FPDF_TEXTRENDERMODE_UNKNOWN {
+		mode = renderMode.TextRenderMode % 4
+	}
+
+	if mode == enums.FPDF_TEXTRENDERMODE_FILL || mode == enums.FPDF_TEXTRENDERMODE_FILL_STROKE {
+		svgText.Style.Fill = getObjectFillColor(instance, object)
+		if renderColorScheme != nil {
+			svgText.Style.Fill = &renderColorScheme.TextFill
+		}
+	}
+
+	if mode == enums.FPDF_TEXTRENDERMODE_STROKE || mode == enums.FPDF_TEXTRENDERMODE_FILL_STROKE {
+		svgText.Style.Stroke = getObjectStrokeColor(instance, object)
+		if renderColorScheme != nil {
+			svgText.Style.Stroke = &renderColorScheme.TextStroke
+		}
+
+		err = getObjectStrokeStyle(instance, object, &svgText.Style)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Invisible text, like the text layer of a scanned document, is kept
+	// transparent so that it can still be selected and searched.
+	if mode == enums.FPDF_TEXTRENDERMODE_INVISIBLE {
+		svgText.Style.Fill = &color.NRGBA{}
+	}
+
+	writer.AddText(svgText, clipPaths)
+	return nil
+}
+
+// addSVGImage adds an image object to the SVG image. JPEG images are embedded
+// as they are in the PDF, other images are converted to PNG.
+func addSVGImage(instance pdfium.Pdfium, writer *pdf.SVGWriter, page requests.Page, object references.FPDF_PAGEOBJECT, matrix pdf.Matrix, clipPaths [][]pdf.PathSegment) error {
+	filterCount, err := instance.FPDFImageObj_GetImageFilterCount(&requests.FPDFImageObj_GetImageFilterCount{
+		ImageObject: object,
+	})
+	if err != nil {
+		return err
+	}
+
+	if filterCount.Count == 1 {
+		filter, err := instance.FPDFImageObj_GetImageFilter(&requests.FPDFImageObj_GetImageFilter{
+			ImageObject: object,
+			Index:       0,
+		})
+		if err != nil {
+			return err
+		}
+
+		metadata, err := instance.FPDFImageObj_GetImageMetadata(&requests.FPDFImageObj_GetImageMetadata{
+			ImageObject: object,
+			Page:        page,
+		})
+		if err != nil {
+			return err
+		}
+
+		// CMYK JPEG images are not supported by all viewers, convert them
+		// like the other images.
+		isGrayOrRGB := metadata.ImageMetadata.BitsPerPixel == 8 || metadata.ImageMetadata.BitsPerPixel == 24
+		if filter.ImageFilter == "DCTDecode" && isGrayOrRGB {
+			rawData, err := instance.FPDFImageObj_GetImageDataRaw(&requests.FPDFImageObj_GetImageDataRaw{
+				ImageObject: object,
+			})
+			if err != nil {
+				return err
+			}
+
+			writer.AddImage(pdf.SVGImage{
+				MimeType: "image/jpeg",
+				Data:     rawData.Data,
+				Matrix:   matrix,
+			}, clipPaths)
+			return nil
+		}
+	}
+
+	imageBitmap, err := instance.FPDFImageObj_GetBitmap(&requests.FPDFImageObj_GetBitmap{
+		ImageObject: object,
+	})
+	if err != nil {
+		return err
+	}
+	defer instance.FPDFBitmap_Destroy(&requests.FPDFBitmap_Destroy{
+		Bitmap: imageBitmap.Bitmap,
+	})
+
+	img, err := getBitmapImage(instance, imageBitmap.Bitmap)
+	if err != nil {
+		return err
+	}
+
+	var imageData bytes.Buffer
+	if err := png.Encode(&imageData, img); err != nil {
+		return err
+	}
+
+	writer.AddImage(pdf.SVGImage{
+		MimeType: "image/png",
+		Data:     imageData.Bytes(),
+		Matrix:   matrix,
+	}, clipPaths)
+	return nil
+}
+
+// getBitmapImage returns an image that reads from the buffer of the bitmap,
+// so it can only be used until the bitmap is destroyed.
+func getBitmapImage(instance pdfium.Pdfium, bitmap references.FPDF_BITMAP) (image.Image, error) {
+	stride, err := instance.FPDFBitmap_GetStride(&requests.FPDFBitmap_GetStride{
+		Bitmap: bitmap,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	width, err := instance.FPDFBitmap_GetWidth(&requests.FPDFBitmap_GetWidth{
+		Bitmap: bitmap,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	height, err := instance.FPDFBitmap_GetHeight(&requests.FPDFBitmap_GetHeight{
+		Bitmap: bitmap,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := instance.FPDFBitmap_GetFormat(&requests.FPDFBitmap_GetFormat{
+		Bitmap: bitmap,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buffer, err := instance.FPDFBitmap_GetBuffer(&requests.FPDFBitmap_GetBuffer{
+		Bitmap: bitmap,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rect := image.Rect(0, 0, width.Width, height.Height)
+	switch format.Format {
+	case enums.FPDF_BITMAP_FORMAT_BGRA:
+		return &BGRA{Pix: buffer.Buffer, Stride: stride.Stride, Rect: rect}, nil
+	case enums.FPDF_BITMAP_FORMAT_BGRX:
+		return &BGRX{Pix: buffer.Buffer, Stride: stride.Stride, Rect: rect}, nil
+	case enums.FPDF_BITMAP_FORMAT_BGR:
+		return &BGR{Pix: buffer.Buffer, Stride: stride.Stride, Rect: rect}, nil
+	default:
+		return &image.Gray{Pix: buffer.Buffer, Stride: stride.Stride, Rect: rect}, nil
+	}
+}
+
+// getObjectClipPaths returns the clip paths of an object in the coordinate
+// space of its parent. The object is only visible inside all of the paths.
+func getObjectClipPaths(instance pdfium.Pdfium, object references.FPDF_PAGEOBJECT) ([][]pdf.PathSegment, error) {
+	clipPath, err := instance.FPDFPageObj_GetClipPath(&requests.FPDFPageObj_GetClipPath{
+		PageObject: object,
+	})
+	if err != nil {
+		// Objects without clip path.
+		return nil, nil
+	}
+
+	pathCount, err := instance.FPDFClipPath_CountPaths(&requests.FPDFClipPath_CountPaths{
+		ClipPath: clipPath.ClipPath,
+	})
+	if err != nil {
+		// Objects with an empty clip path.
+		return nil, nil
+	}
+
+	clipPaths := [][]pdf.PathSegment{}
+	for i := 0; i < pathCount.Count; i++ {
+		segmentCount, err := instance.FPDFClipPath_CountPathSegments(&requests.FPDFClipPath_CountPathSegments{
+			ClipPath:  clipPath.ClipPath,
+			PathIndex: i,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		segments := []pdf.PathSegment{}
+		for j := 0; j < segmentCount.Count; j++ {
+			pathSegment, err := instance.FPDFClipPath_GetPathSegment(&requests.FPDFClipPath_GetPathSegment{
+				ClipPath:     clipPath.ClipPath,
+				PathIndex:    i,
+				SegmentIndex: j,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			segment, err := getPathSegment(instance, pathSegment.PathSegment)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment)
+		}
+
+		if len(segments) > 0 {
+			clipPaths = append(clipPaths, segments)
+		}
+	}
+
+	return clipPaths, nil
+}
+
+func getPathSegment(instance pdfium.Pdfium, pathSegment references.FPDF_PATHSEGMENT) (pdf.PathSegment, error) {
+	point, err := instance.FPDFPathSegment_GetPoint(&requests.FPDFPathSegment_GetPoint{
+		PathSegment: pathSegment,
+	})
+	if err != nil {
+		return pdf.PathSegment{}, err
+	}
+
+	segmentType, err := instance.FPDFPathSegment_GetType(&requests.FPDFPathSegment_GetType{
+		PathSegment: pathSegment,
+	})
+	if err != nil {
+		return pdf.PathSegment{}, err
+	}
+
+	isClose, err := instance.FPDFPathSegment_GetClose(&requests.FPDFPathSegment_GetClose{
+		PathSegment: pathSegment,
+	})
+	if err != nil {
+		return pdf.PathSegment{}, err
+	}
+
+	segment := pdf.PathSegment{
+		Type:  pdf.PathSegmentLineTo,
+		X:     float64(point.X),
+		Y:     float64(point.Y),
+		Close: isClose.IsClose,
+	}
+
+	switch segmentType.Type {
+	case enums.FPDF_SEGMENT_MOVETO:
+		segment.Type = pdf.PathSegmentMoveTo
+	case enums.FPDF_SEGMENT_BEZIERTO:
+		segment.Type = pdf.PathSegmentBezierTo
+	}
+
+	return segment, nil
+}
+
+// getObjectFillColor returns the fill color of an object, or nil when the
+// color can't be read, like for patterns.
+func getObjectFillColor(instance pdfium.Pdfium, object references.FPDF_PAGEOBJECT) *color.NRGBA {
+	fillColor, err := instance.FPDFPageObj_GetFillColor(&requests.FPDFPageObj_GetFillColor{
+		PageObject: object,
+	})
+	if err != nil {
+		return nil
+	}
+	return fpdfColorToNRGBA(fillColor.FillColor)
+}
+
+// getObjectStrokeColor returns the stroke color of an object, or nil when the
+// color can't be read, like for patterns.
+func getObjectStrokeColor(instance pdfium.Pdfium, object references.FPDF_PAGEOBJECT) *color.NRGBA {
+	strokeColor, err := instance.FPDFPageObj_GetStrokeColor(&requests.FPDFPageObj_GetStrokeColor{
+		PageObject: object,
+	})
+	if err != nil {
+		return nil
+	}
+	return fpdfColorToNRGBA(strokeColor.StrokeColor)
+}
+
+func fpdfColorToNRGBA(fpdfColor structs.FPDF_COLOR) *color.NRGBA {
+	return &color.NRGBA{
+		R: uint8(fpdfColor.R),
+		G: uint8(fpdfColor.G),
+		B: uint8(fpdfColor.B),
+		A: uint8(fpdfColor.A),
+	}
+}
+
+// getObjectStrokeStyle sets the width, line cap, line join and dashes of the
+// stroke of an object on the style.
+func getObjectStrokeStyle(instance pdfium.Pdfium, object references.FPDF_PAGEOBJECT, style *pdf.SVGStyle) error {
+	strokeWidth, err := instance.FPDFPageObj_GetStrokeWidth(&requests.FPDFPageObj_GetStrokeWidth{
+		PageObject: object,
+	})
+	if err != nil {
+		return err
+	}
+	style.StrokeWidth = float64(strokeWidth.StrokeWidth)
+
+	lineCap, err := instance.FPDFPageObj_GetLineCap(&requests.FPDFPageObj_GetLineCap{
+		PageObject: object,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch lineCap.LineCap {
+	case enums.FPDF_LINECAP_ROUND:
+		style.LineCap = "round"
+	case enums.FPDF_LINECAP_PROJECTING_SQUAR:
+		style.LineCap = "square"
+	}
+
+	lineJoin, err := instance.FPDFPageObj_GetLineJoin(&requests.FPDFPageObj_GetLineJoin{
+		PageObject: object,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch lineJoin.LineJoin {
+	case enums.FPDF_LINEJOIN_ROUND:
+		style.LineJoin = "round"
+	case enums.FPDF_LINEJOIN_BEVEL:
+		style.LineJoin = "bevel"
+	}
+
+	// Dashes are only available in experimental builds, draw solid lines
+	// otherwise.
+	dashArray, err := instance.FPDFPageObj_GetDashArray(&requests.FPDFPageObj_GetDashArray{
+		PageObject: object,
+	})
+	if err == nil && len(dashArray.DashArray) > 0 {
+		for _, dash := range dashArray.DashArray {
+			style.DashArray = append(style.DashArray, float64(dash))
+		}
+
+		dashPhase, err := instance.FPDFPageObj_GetDashPhase(&requests.FPDFPageObj_GetDashPhase{
+			PageObject: object,
+		})
+		if err == nil {
+			style.DashPhase = float64(dashPhase.DashPhase)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bytes"
+	"image"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/requests"
+)
+
+// getTextLayer returns the text layer of the rendered pages, the sizes are the
+// sizes of the rendered images of the pages in pixels.
+func getTextLayer(instance pdfium.Pdfium, imageName string, pages []requests.Page, sizes []image.Point) ([]byte, error) {
+	textLayerPages := []pdf.TextLayerPage{}
+	for i, page := range pages {
+		textLayerPage, err := getTextLayerPage(instance, page, sizes[i], renderRegion, imageName)
+		if err != nil {
+			return nil, err
+		}
+		textLayerPages = append(textLayerPages, textLayerPage)
+	}
+
+	var out bytes.Buffer
+	if err := pdf.WriteTextLayer(&out, pdf.TextLayerFormat(textLayer), textLayerPages); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// getTextLayerPage gets the text of a page with the position of every
+// character in pixels of the rendered image, grouped into words and lines. The
+// region is the part of the page that was rendered, nil for the whole page.
+func getTextLayerPage(instance pdfium.Pdfium, page requests.Page, size image.Point, renderedRegion *pdf.Region, imageName string) (pdf.TextLayerPage, error) {
+	pageText, err := instance.GetPageTextStructured(&requests.GetPageTextStructured{
+		Page: page,
+		Mode: requests.GetPageTextStructuredModeChars,
+	})
+	if err != nil {
+		return pdf.TextLayerPage{}, err
+	}
+
+	pageWidth, err := instance.FPDF_GetPageWidthF(&requests.FPDF_GetPageWidthF{
+		Page: page,
+	})
+	if err != nil {
+		return pdf.TextLayerPage{}, err
+	}
+
+	pageHeight, err := instance.FPDF_GetPageHeightF(&requests.FPDF_GetPageHeightF{
+		Page: page,
+	})
+	if err != nil {
+		return pdf.TextLayerPage{}, err
+	}
+
+	region := pdf.Region{Width: 1, Height: 1, Fractions: true}
+	if renderedRegion != nil {
+		region = *renderedRegion
+	}
+
+	regionInPoints, err := region.InPoints(float64(pageWidth.PageWidth), float64(pageHeight.PageHeight))
+	if err != nil {
+		return pdf.TextLayerPage{}, err
+	}
+
+	boundingBox, err := instance.FPDF_GetPageBoundingBox(&requests.FPDF_GetPageBoundingBox{
+		Page: page,
+	})
+	if err != nil {
+		return pdf.TextLayerPage{}, err
+	}
+
+	rotation, err := instance.FPDFPage_GetRotation(&requests.FPDFPage_GetRotation{
+		Page: page,
+	})
+	if err != nil {
+		return pdf.TextLayerPage{}, err
+	}
+
+	displayMatrix := pdf.DisplayMatrix(pdf.Rect{
+		Left:   float64(boundingBox.Rect.Left),
+		Bottom: float64(boundingBox.Rect.Bottom),
+		Right:  float64(boundingBox.Rect.Right),
+		Top:    float64(boundingBox.Rect.Top),
+	}, int(rotation.PageRotation)*90)
+
+	// Use the size of the rendered image for the scale, the renderers round
+	// the size in different ways.
+	scaleX := float64(size.X) / regionInPoints.Width
+	scaleY := float64(size.Y) / regionInPoints.Height
+
+	chars := []pdf.TextChar{}
+	for _, char := range pageText.Chars {
+		// The positions are in PDF coordinates, with the origin in the
+		// bottom left corner.
+		box, ok := pdf.NewTextBox(pdf.Rect{
+			Left:   char.PointPosition.Left,
+			Bottom: char.PointPosition.Bottom,
+			Right:  char.PointPosition.Right,
+			Top:    char.PointPosition.Top,
+		}, displayMatrix, regionInPoints, scaleX, scaleY)
+		if !ok {
+			continue
+		}
+
+		chars = append(chars, pdf.TextChar{Text: char.Text, Box: box})
+	}
+
+	return pdf.TextLayerPage{
+		Number:    page.ByIndex.Index + 1,
+		ImageName: imageName,
+		Width:     size.X,
+		Height:    size.Y,
+		Lines:     pdf.GroupTextLines(chars),
+	}, nil
+}
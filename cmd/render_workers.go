@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+)
+
+// startRenderWorkers renders the output files with a pool of workers. Every
+// worker gets its own pdfium instance and opens the document itself. The
+// results are sent on a channel per output file, so that the caller can write
+// them in page order. The done function must be called after every result
+// that has been handled, so that the workers don't render too far ahead of
+// the output. The stop function stops the workers and waits until they have
+// closed their instances.
+func startRenderWorkers(filename string, workers int, outputs [][]requests.Page, outputPaths []string) ([]chan *renderedOutput, func(), func()) {
+	results := make([]chan *renderedOutput, len(outputs))
+	for i := range results {
+		results[i] = make(chan *renderedOutput, 1)
+	}
+
+	// Limit the amount of results that are waiting to be written.
+	window := make(chan struct{}, workers*2)
+	jobs := make(chan int)
+	stop := make(chan struct{})
+	go func() {
+		defer close(jobs)
+		for i := range outputs {
+			select {
+			case window <- struct{}{}:
+			case <-stop:
+				return
+			}
+
+			select {
+			case jobs <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			renderWorker(filename, outputs, outputPaths, jobs, results)
+		}()
+	}
+
+	done := func() {
+		<-window
+	}
+
+	stopOnce := sync.Once{}
+	stopWorkers := func() {
+		stopOnce.Do(func() {
+			close(stop)
+			wg.Wait()
+		})
+	}
+
+	return results, done, stopWorkers
+}
+
+// renderWorker renders the output files of the jobs it receives until there
+// are no more jobs. A crash while rendering is sent as the error of the
+// output file, so that the main goroutine handles it like any other error.
+func renderWorker(filename string, outputs [][]requests.Page, outputPaths []string, jobs <-chan int, results []chan *renderedOutput) {
+	instance, err := pdf.NewPdfiumInstance()
+	if err == nil {
+		defer instance.Close()
+	}
+
+	var document references.FPDF_DOCUMENT
+	if err == nil {
+		openedDocument, closeFile, openErr := reopenFile(instance, filename)
+		if closeFile != nil {
+			defer closeFile()
+		}
+		if openErr != nil {
+			err = openErr
+		} else {
+			document = openedDocument.Document
+		}
+	}
+
+	for i := range jobs {
+		if err != nil {
+			results[i] <- &renderedOutput{err: err}
+			continue
+		}
+
+		// The pages refer to the document of the main instance, so render
+		// the same pages of the document of this worker.
+		workerPages := []requests.Page{}
+		for _, page := range outputs[i] {
+			workerPages = append(workerPages, requests.Page{
+				ByIndex: &requests.PageByIndex{
+					Document: document,
+					Index:    page.ByIndex.Index,
+				},
+			})
+		}
+
+		results[i] <- renderWorkerOutput(instance, workerPages, filepath.Base(outputPaths[i]))
+	}
+}
+
+// renderWorkerOutput renders the pages of one output file and recovers from a
+// crash of the render, a panic in a worker would otherwise stop the process
+// without closing the output files.
+func renderWorkerOutput(instance pdfium.Pdfium, renderPages []requests.Page, imageName string) (result *renderedOutput) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = &renderedOutput{err: fmt.Errorf("the render crashed: %v", r)}
+		}
+	}()
+
+	return renderOutputFile(instance, renderPages, imageName)
+}
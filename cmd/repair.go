@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/spf13/cobra"
+)
+
+var repairReportOutput string
+
+func init() {
+	addGenericPDFOptions(repairCmd)
+	repairCmd.Flags().StringVarP(&outputType, "output-type", "", "text", "The file type of the report, text or json")
+	repairCmd.Flags().StringVarP(&repairReportOutput, "report", "", "", "Write the report into this file instead of stdout. When the output is stdout, the report is written to stderr by default.")
+	rootCmd.AddCommand(repairCmd)
+}
+
+var repairCmd = &cobra.Command{
+	Use:   "repair [input] [output]",
+	Short: "Repair a damaged PDF",
+	Long:  fmt.Sprintf("Repair a damaged PDF by opening it, which makes pdfium reconstruct a broken cross reference table, and saving it again completely, without the damaged structure. Every page is loaded and rendered to find damaged pages, and is compared with the page in the repaired PDF by its objects and by how it looks. A report of what was recovered is written to stdout, the command exits with exit code %d when content was lost.\n[input] can either be a file path or - for stdin.\n[output] can either be a file path or - for stdout.", ExitCodeRepairContentLost),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
+		}
+
+		if err := validFile(args[0]); err != nil {
+			return fmt.Errorf("could not open input file %s: %w\n", args[0], newExitCodeError(err, ExitCodeInvalidInput))
+		}
+
+		if outputType != "text" && outputType != "json" {
+			return newExitCodeError(fmt.Errorf("invalid output type %s, should be text or json\n", outputType), ExitCodeInvalidArguments)
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pdf.LoadPdfium()
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not load pdfium: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+		defer pdf.ClosePdfium()
+
+		document, closeFile, err := openFile(args[0])
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not open input file %s: %w\n", args[0], err), ExitCodeInvalidInput)
+			return
+		}
+		defer closeFile()
+
+		repairedBytes, report, err := pdf.RepairDocument(pdf.PdfiumInstance, document.Document)
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not repair %s: %w\n", args[0], newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+		report.File = args[0]
+		report.Output = args[1]
+
+		if args[1] == stdFilename {
+			cmd.OutOrStdout().Write(repairedBytes)
+		} else {
+			if err := writeOutputFile(args[1], repairedBytes); err != nil {
+				handleError(cmd, fmt.Errorf("could not write repaired document into %s: %w\n", args[1], err), ExitCodeInvalidOutput)
+				return
+			}
+		}
+
+		reportBuffer := &bytes.Buffer{}
+		if outputType == "json" {
+			outputJson, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Fprintln(reportBuffer, string(outputJson))
+		} else {
+			writeRepairReport(reportBuffer, *report)
+		}
+
+		if repairReportOutput != "" && repairReportOutput != stdFilename {
+			if err := writeOutputFile(repairReportOutput, reportBuffer.Bytes()); err != nil {
+				handleError(cmd, fmt.Errorf("could not write report into %s: %w\n", repairReportOutput, err), ExitCodeInvalidOutput)
+				return
+			}
+		} else if args[1] == stdFilename && repairReportOutput == "" {
+			cmd.PrintErr(reportBuffer.String())
+		} else {
+			cmd.Print(reportBuffer.String())
+		}
+
+		if report.ContentLost {
+			handleError(cmd, fmt.Errorf("content of %s was lost while repairing\n", args[0]), ExitCodeRepairContentLost)
+			return
+		}
+	},
+}
+
+func writeRepairReport(writer io.Writer, report pdf.RepairReport) {
+	fmt.Fprintf(writer, "Repaired %s into %s\n", report.File, report.Output)
+
+	if report.ValidCrossReferenceTable == nil {
+		fmt.Fprintf(writer, "Cross reference table: unknown\n")
+	} else if *report.ValidCrossReferenceTable {
+		fmt.Fprintf(writer, "Cross reference table: valid\n")
+	} else {
+		fmt.Fprintf(writer, "Cross reference table: invalid, reconstructed\n")
+	}
+
+	fmt.Fprintf(writer, "Page count: %d, %d in repaired PDF\n", report.PageCount, report.RepairedPageCount)
+	for _, page := range report.LostPages() {
+		switch {
+		case page.LoadError != "":
+			fmt.Fprintf(writer, " - Page %d: %s\n", page.Page, page.LoadError)
+		case page.RenderError != "":
+			fmt.Fprintf(writer, " - Page %d: %s\n", page.Page, page.RenderError)
+		case page.RepairedError != "":
+			fmt.Fprintf(writer, " - Page %d: %s in repaired PDF\n", page.Page, page.RepairedError)
+		case page.DifferentPixels > 0:
+			fmt.Fprintf(writer, " - Page %d: %d pixels look different in repaired PDF\n", page.Page, page.DifferentPixels)
+		default:
+			fmt.Fprintf(writer, " - Page %d: %d of %d objects recovered\n", page.Page, page.RepairedObjects, page.Objects)
+		}
+	}
+
+	if report.ContentLost {
+		fmt.Fprintf(writer, "Content was lost on %d page(s)\n", len(report.LostPages()))
+	} else {
+		fmt.Fprintf(writer, "All content was recovered\n")
+	}
+}
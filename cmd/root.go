@@ -23,6 +23,7 @@ var (
 // Execute executes the root command.
 func Execute() error {
 	rootCmd.SetOut(os.Stdout)
+	defer removeStdinDocuments()
 	return rootCmd.Execute()
 }
 
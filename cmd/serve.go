@@ -0,0 +1,443 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveListen        string
+	serveMaxUploadSize int64
+	serveTimeout       time.Duration
+	servePoolSize      int
+)
+
+// serveUnsupportedOptions are options that can't be used over HTTP, because
+// they refer to files on the server.
+var serveUnsupportedOptions = []string{"help", "bounds", "std-file-delimiter", "std-format"}
+
+// serveSlot makes sure that only one command runs at a time, the commands
+// share the pdfium instance and their options.
+var serveSlot = make(chan struct{}, 1)
+
+type serveError struct {
+	Error    string
+	ExitCode int `json:",omitempty"`
+}
+
+func init() {
+	serveCmd.Flags().StringVarP(&serveListen, "listen", "", ":8080", "The address to listen on.")
+	serveCmd.Flags().Int64VarP(&serveMaxUploadSize, "max-upload-size", "", 100, "The maximum size in MiB of the uploaded files of a request.")
+	serveCmd.Flags().DurationVarP(&serveTimeout, "timeout", "", 2*time.Minute, "The maximum duration of a request, including waiting for other requests. Commands that take longer are stopped, in builds with CGO the command can't be stopped and keeps the server busy until it's done.")
+	serveCmd.Flags().IntVarP(&servePoolSize, "pool-size", "", 1, "The amount of pdfium instances to keep loaded. One instance runs the commands, render can use the others with the option workers.")
+	rootCmd.AddCommand(serveCmd)
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run an HTTP server for the commands",
+	Long: "Run an HTTP server that keeps pdfium loaded and runs the commands info, text, form, render, merge, explode, flatten, images and attachments.\n" +
+		"Send a POST request to /[command], like /render, with the PDF as body, or as multipart form with one or more files (merge uses the files in order). " +
+		"The options of the command are given as query parameters or multipart form values, like /render?dpi=150&file-type=png. " +
+		"When the command writes one file, the response is that file, otherwise the response is multipart/mixed with a part per file. " +
+		"Errors are returned as JSON with the error and the exit code of the command.\n" +
+		"Commands run one at a time, requests wait for their turn within the timeout. GET /health returns whether the server is running.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.NoArgs(cmd, args); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if servePoolSize < 1 {
+			handleError(cmd, fmt.Errorf("invalid pool size %d, should be at least 1\n", servePoolSize), ExitCodeInvalidArguments)
+			return
+		}
+
+		if serveMaxUploadSize < 1 {
+			handleError(cmd, fmt.Errorf("invalid max upload size %d, should be at least 1\n", serveMaxUploadSize), ExitCodeInvalidArguments)
+			return
+		}
+
+		pdf.SetPdfiumKillable(true)
+		pdf.KeepPdfiumLoaded(true)
+		err := pdf.LoadPdfiumPool(servePoolSize)
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not load pdfium: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+		defer func() {
+			pdf.KeepPdfiumLoaded(false)
+			pdf.ClosePdfium()
+		}()
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte("ok\n"))
+		})
+		for name := range inProcessCommands {
+			name := name
+			mux.HandleFunc("/"+name, func(w http.ResponseWriter, r *http.Request) {
+				handleServeRequest(cmd, name, w, r)
+			})
+		}
+
+		server := &http.Server{
+			Addr:              serveListen,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       serveTimeout,
+			WriteTimeout:      serveTimeout + 30*time.Second,
+			IdleTimeout:       time.Minute,
+		}
+
+		// The output of the commands is not useful in the server.
+		rootCmd.SetOut(io.Discard)
+		rootCmd.SetErr(io.Discard)
+		cmd.SetOut(os.Stdout)
+		cmd.SetErr(os.Stderr)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), serveTimeout)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		cmd.Printf("Listening on %s\n", serveListen)
+		err = server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			handleError(cmd, fmt.Errorf("could not run server: %w\n", err), ExitCodeInvalidArguments)
+			return
+		}
+	},
+}
+
+// handleServeRequest runs a command for a request.
+func handleServeRequest(cmd *cobra.Command, name string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeServeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed, use POST", r.Method), 0)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), serveTimeout)
+	defer cancel()
+
+	tempDir, err := os.MkdirTemp("", "pdfium-cli-serve-")
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err, 0)
+		return
+	}
+
+	// The folder is removed when the command is done, that can be after the
+	// response when the command timed out.
+	removeTempDir := true
+	defer func() {
+		if removeTempDir {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	r.Body = http.MaxBytesReader(w, r.Body, serveMaxUploadSize*1024*1024)
+	inputs, options, err := readServeRequest(r, filepath.Join(tempDir, "in"))
+	if err != nil {
+		maxBytesError := &http.MaxBytesError{}
+		if errors.As(err, &maxBytesError) {
+			writeServeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("the request is larger than %d MiB", serveMaxUploadSize), 0)
+			return
+		}
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("could not read request: %w", err), 0)
+		return
+	}
+
+	command := inProcessCommands[name]
+	if len(inputs) == 0 {
+		writeServeError(w, http.StatusBadRequest, errors.New("no input file given"), ExitCodeInvalidArguments)
+		return
+	}
+
+	if len(inputs) > 1 && !command.multipleInputs {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("the command %s takes one input file, got %d", name, len(inputs)), ExitCodeInvalidArguments)
+		return
+	}
+
+	outputFolder := filepath.Join(tempDir, "out")
+	if err := os.MkdirAll(outputFolder, 0755); err != nil {
+		writeServeError(w, http.StatusInternalServerError, err, 0)
+		return
+	}
+
+	output := command.getOutputArgument(outputFolder, inputs[0], options.Get("output-type"), options.Get("archive"))
+	commandArgs, err := getServeCommandArgs(name, inputs, output, options)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, err, ExitCodeInvalidArguments)
+		return
+	}
+
+	// Wait for our turn.
+	select {
+	case serveSlot <- struct{}{}:
+	case <-ctx.Done():
+		writeServeError(w, http.StatusServiceUnavailable, errors.New("timeout while waiting for other requests"), 0)
+		return
+	}
+
+	type commandResult struct {
+		exitCode int
+		err      error
+	}
+
+	done := make(chan commandResult, 1)
+	go func() {
+		defer func() {
+			<-serveSlot
+		}()
+		exitCode, err := runCommandInProcess(commandArgs)
+		done <- commandResult{exitCode: exitCode, err: err}
+	}()
+
+	var result commandResult
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		pdf.KillPdfium()
+		removeTempDir = false
+		go func() {
+			<-done
+			os.RemoveAll(tempDir)
+		}()
+		cmd.PrintErrf("Stopped %s after the timeout of %s\n", name, serveTimeout)
+		writeServeError(w, http.StatusGatewayTimeout, fmt.Errorf("the command did not finish within %s", serveTimeout), 0)
+		return
+	}
+
+	if result.err != nil {
+		// Don't show the folders of the server.
+		message := strings.ReplaceAll(strings.TrimSpace(result.err.Error()), tempDir+string(filepath.Separator), "")
+		writeServeError(w, serveStatusCode(result.exitCode), errors.New(message), result.exitCode)
+		return
+	}
+
+	err = writeServeOutput(w, outputFolder)
+	if err != nil {
+		cmd.PrintErrf("Could not write response of %s: %s\n", name, err.Error())
+	}
+}
+
+// readServeRequest saves the uploaded files into the input folder and returns
+// their paths and the options of the request.
+func readServeRequest(r *http.Request, inputFolder string) ([]string, url.Values, error) {
+	options := r.URL.Query()
+	inputs := []string{}
+
+	saveInput := func(name string, reader io.Reader) error {
+		// Every input gets its own folder so that the original filename can
+		// be kept for the output names.
+		folder := filepath.Join(inputFolder, strconv.Itoa(len(inputs)+1))
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			return err
+		}
+
+		path := filepath.Join(folder, sanitizeInputFilename(name))
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if _, err := io.Copy(file, reader); err != nil {
+			return err
+		}
+
+		inputs = append(inputs, path)
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		name := ""
+		if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil {
+			name = params["filename"]
+		}
+
+		if err := saveInput(name, r.Body); err != nil {
+			return nil, nil, err
+		}
+
+		// An empty body is no input.
+		if stat, err := os.Stat(inputs[0]); err == nil && stat.Size() == 0 {
+			inputs = []string{}
+		}
+
+		return inputs, options, nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if part.FileName() != "" {
+			err = saveInput(part.FileName(), part)
+		} else {
+			var value []byte
+			value, err = io.ReadAll(io.LimitReader(part, 64*1024))
+			options.Add(part.FormName(), string(value))
+		}
+		part.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return inputs, options, nil
+}
+
+// getServeCommandArgs returns the arguments to run a command for a request.
+// Only the options of the command are allowed.
+func getServeCommandArgs(name string, inputs []string, output string, options url.Values) ([]string, error) {
+	command, _, err := rootCmd.Find([]string{name})
+	if err != nil {
+		return nil, err
+	}
+
+	optionNames := []string{}
+	for optionName := range options {
+		optionNames = append(optionNames, optionName)
+	}
+	sort.Strings(optionNames)
+
+	args := append([]string{name}, inputs...)
+	args = append(args, output)
+	for _, optionName := range optionNames {
+		if command.Flags().Lookup(optionName) == nil {
+			return nil, fmt.Errorf("unknown option %s for command %s", optionName, name)
+		}
+
+		for _, unsupportedOption := range serveUnsupportedOptions {
+			if optionName == unsupportedOption {
+				return nil, fmt.Errorf("the option %s is not supported by the server", optionName)
+			}
+		}
+
+		for _, value := range options[optionName] {
+			if optionName == "output-template" && (filepath.IsAbs(value) || strings.Contains(value, "..")) {
+				return nil, fmt.Errorf("the option output-template can't contain .. or be an absolute path")
+			}
+
+			args = append(args, "--"+optionName+"="+value)
+		}
+	}
+
+	return args, nil
+}
+
+// serveStatusCode returns the HTTP status code for the exit code of a
+// command.
+func serveStatusCode(exitCode int) int {
+	switch exitCode {
+	case ExitCodeInvalidArguments, ExitCodeInvalidPageRange:
+		return http.StatusBadRequest
+	case ExitCodePdfiumFileError, ExitCodePdfiumBadFileError, ExitCodePdfiumPasswordError, ExitCodePdfiumSecurityError, ExitCodePdfiumPageError, ExitCodeInvalidInput:
+		return http.StatusUnprocessableEntity
+	case ExitCodeExperimental:
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeServeError(w http.ResponseWriter, statusCode int, err error, exitCode int) {
+	outputJson, _ := json.MarshalIndent(serveError{Error: err.Error(), ExitCode: exitCode}, "", "  ")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(outputJson)
+}
+
+// writeServeOutput writes the files in the output folder as response, one
+// file as body, otherwise as multipart.
+func writeServeOutput(w http.ResponseWriter, outputFolder string) error {
+	files, err := findOutputFiles(outputFolder)
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err, 0)
+		return err
+	}
+
+	if len(files) == 1 {
+		file, err := os.Open(files[0])
+		if err != nil {
+			writeServeError(w, http.StatusInternalServerError, err, 0)
+			return err
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", getContentType(files[0]))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(files[0])}))
+		_, err = io.Copy(w, file)
+		return err
+	}
+
+	multipartWriter := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+multipartWriter.Boundary())
+	for _, path := range files {
+		name, err := filepath.Rel(outputFolder, path)
+		if err != nil {
+			return err
+		}
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", getContentType(path))
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.ToSlash(name)}))
+		part, err := multipartWriter.CreatePart(header)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(part, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return multipartWriter.Close()
+}
@@ -1,13 +1,12 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	"image/jpeg"
 	"image/png"
 	"io"
-	"os"
-	"path"
 	"strconv"
 	"strings"
 
@@ -20,9 +19,11 @@ import (
 
 func init() {
 	addGenericPDFOptions(thumbnailsCmd)
+	addArchiveOption(thumbnailsCmd)
 	addPagesOption("The pages or page to get thumbnails of", thumbnailsCmd)
 	thumbnailsCmd.Flags().StringVarP(&fileType, "file-type", "", "jpeg", "The file type to render in, jpeg or png")
 	thumbnailsCmd.Flags().IntVarP(&jpegQuality, "jpeg-quality", "", 95, "Quality to use when file type is jpeg")
+	addOutputTemplateOption(thumbnailsCmd, "thumbnail-page-{page}.{ext}")
 
 	rootCmd.AddCommand(thumbnailsCmd)
 }
@@ -30,7 +31,7 @@ func init() {
 var thumbnailsCmd = &cobra.Command{
 	Use:   "thumbnails [input] [output-folder]",
 	Short: "Extract the thumbnails of a PDF",
-	Long:  "Extract the thumbnails of a PDF and store them as file.\n[input] can either be a file path or - for stdin.\nThis extracts embedded thumbnails, it does not render a thumbnail of the page. Not all PDFs and pages have thumbnails. You can use the render command if you want to generate thumbnails.[output-folder] can be either a folder or - for stdout. In the case of stdout, multiple files will be delimited by the value of the std-file-delimiter, with a newline before and after it.",
+	Long:  "Extract the thumbnails of a PDF and store them as file.\n[input] can either be a file path or - for stdin.\nThis extracts embedded thumbnails, it does not render a thumbnail of the page. Not all PDFs and pages have thumbnails. You can use the render command if you want to generate thumbnails.[output-folder] can be either a folder or - for stdout. The folder will be created when it doesn't exist. In the case of stdout, multiple files are written in the std-format, by default delimited by the value of the std-file-delimiter, with a newline before and after it. When the output ends with .zip, the files are written into a zip archive with a manifest.json.",
 	Args: func(cmd *cobra.Command, args []string) error {
 		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
 			return newExitCodeError(err, ExitCodeInvalidArguments)
@@ -40,15 +41,12 @@ var thumbnailsCmd = &cobra.Command{
 			return fmt.Errorf("could not open input file %s: %w\n", args[0], newExitCodeError(err, ExitCodeInvalidInput))
 		}
 
-		if args[1] != stdFilename {
-			folderStat, err := os.Stat(args[1])
-			if err != nil {
-				return fmt.Errorf("could not open output folder %s: %w\n", args[1], newExitCodeError(err, ExitCodeInvalidOutput))
-			}
+		if err := validOutputFolder(args[1]); err != nil {
+			return err
+		}
 
-			if !folderStat.IsDir() {
-				return newExitCodeError(fmt.Errorf("output folder %s is not a folder\n", args[1]), ExitCodeInvalidOutput)
-			}
+		if err := validOutputTemplate(getOutputTemplate(cmd)); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
 		}
 
 		return nil
@@ -88,6 +86,12 @@ var thumbnailsCmd = &cobra.Command{
 			return
 		}
 
+		outputArchive, err := newOutputArchive(cmd, args[1], args[0], pageCount.PageCount)
+		if err != nil {
+			handleError(cmd, fmt.Errorf("%w\n", err), ExitCodeInvalidArguments)
+			return
+		}
+
 		pages := strings.Split(*parsedPageRange, ",")
 		imageCount := 0
 		for _, page := range pages {
@@ -224,12 +228,31 @@ var thumbnailsCmd = &cobra.Command{
 				ext = "png"
 			}
 
-			filePath := path.Join(args[1], fmt.Sprintf("thumbnail-page-%d.%s", pageInt, ext))
+			templateValues := pdf.OutputTemplateValues{
+				Input: pdf.OutputTemplateInputName(args[0]),
+				Page:  pageInt,
+				Index: imageCount + 1,
+				Ext:   ext,
+			}
+			if pdf.OutputTemplateHasToken(getOutputTemplate(cmd), "label") {
+				templateValues.Label = getPageLabel(pdf.PdfiumInstance, document.Document, pageInt-1)
+			}
+
+			fileName, err := pdf.ExpandOutputTemplate(getOutputTemplate(cmd), templateValues)
+			if err != nil {
+				closePageFunc()
+				closeBitmapFunc()
+				handleError(cmd, fmt.Errorf("could not create output path for thumbnail of page %d for PDF %s: %w\n", pageInt, args[0], err), ExitCodeInvalidArguments)
+				return
+			}
+
+			filePath := joinOutputPath(args[1], fileName)
 
 			closeFunc := func() {}
 			var outWriter io.Writer
-			if args[1] != stdFilename {
-				outFile, err := os.Create(filePath)
+			archiveFileBuffer := &bytes.Buffer{}
+			if outputArchive == nil {
+				outFile, err := createOutputFile(filePath)
 				if err != nil {
 					closePageFunc()
 					closeBitmapFunc()
@@ -238,15 +261,10 @@ var thumbnailsCmd = &cobra.Command{
 				}
 				outWriter = outFile
 				closeFunc = func() {
-					outFile.Close()
+					closeOutputFile(cmd, outFile, filePath)
 				}
 			} else {
-				if imageCount > 0 {
-					os.Stdout.WriteString("\n")
-					os.Stdout.WriteString(stdFileDelimiter)
-					os.Stdout.WriteString("\n")
-				}
-				outWriter = os.Stdout
+				outWriter = archiveFileBuffer
 			}
 
 			if fileType == "png" {
@@ -273,11 +291,20 @@ var thumbnailsCmd = &cobra.Command{
 			closeFunc()
 			closeBitmapFunc()
 
-			if args[1] != stdFilename {
+			if outputArchive == nil {
 				cmd.Printf("Exported thumbnail from page %d into %s\n", pageInt, filePath)
+			} else {
+				err = outputArchive.WriteFile(newStdFile(fileName, imageCount+1, pageInt), archiveFileBuffer.Bytes())
+				if err != nil {
+					closePageFunc()
+					handleError(cmd, fmt.Errorf("could not write thumbnail of page %d for PDF %s: %w\n", pageInt, args[0], err), ExitCodeInvalidOutput)
+					return
+				}
 			}
 
 			imageCount++
 		}
+
+		outputArchive.finish(cmd)
 	},
 }
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/klippa-app/pdfium-cli/pdf"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	workerPoolSize int
+)
+
+// workerInput is an input of a worker request, a path or the content of the
+// file.
+type workerInput struct {
+	Path string // The path of the input file.
+	Data []byte // The content of the input file, base64 encoded in JSON.
+	Name string // The filename of Data, used for the output names.
+}
+
+type workerRequest struct {
+	Id           json.RawMessage // Returned in the response, to match responses to requests.
+	Command      string          // The name of the command.
+	Args         []string        // The options of the command, like ["--dpi", "150"].
+	Inputs       []workerInput   // The inputs of the command, merge takes multiple inputs.
+	OutputFolder string          // When given, the outputs are moved into this folder and the response contains their paths.
+}
+
+type workerOutput struct {
+	Name string // The name of the output file, relative to the output folder.
+	Path string `json:",omitempty"` // The path of the output file, when the request has an output folder.
+	Data []byte `json:",omitempty"` // The content of the output file, base64 encoded in JSON.
+}
+
+type workerResponse struct {
+	Id       json.RawMessage
+	ExitCode int
+	Error    string `json:",omitempty"`
+	Outputs  []workerOutput
+}
+
+func init() {
+	workerCmd.Flags().IntVarP(&workerPoolSize, "pool-size", "", 1, "The amount of pdfium instances to keep loaded. One instance runs the commands, render can use the others with the option workers.")
+	rootCmd.AddCommand(workerCmd)
+}
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Run commands from JSON requests on stdin",
+	Long: "Run commands from newline-delimited JSON requests on stdin and write a JSON response per request on stdout, pdfium stays loaded between the requests.\n" +
+		"A request looks like {\"Id\": 1, \"Command\": \"render\", \"Args\": [\"--dpi\", \"150\"], \"Inputs\": [{\"Path\": \"file.pdf\"}]}, an input can also be given as {\"Data\": \"[base64]\", \"Name\": \"file.pdf\"}. " +
+		"The commands info, text, form, render, merge, explode, flatten, images and attachments are supported.\n" +
+		"A response looks like {\"Id\": 1, \"ExitCode\": 0, \"Outputs\": [{\"Name\": \"file-1.png\", \"Data\": \"[base64]\"}]}, with an Error when the command failed. " +
+		"When the request has an OutputFolder, the outputs are moved into that folder and the response contains their Path instead of their Data.\n" +
+		"Requests are handled one at a time, in order. A failing or crashing command only fails its own request. The worker stops at the end of stdin.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.NoArgs(cmd, args); err != nil {
+			return newExitCodeError(err, ExitCodeInvalidArguments)
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if workerPoolSize < 1 {
+			handleError(cmd, fmt.Errorf("invalid pool size %d, should be at least 1\n", workerPoolSize), ExitCodeInvalidArguments)
+			return
+		}
+
+		// Stdout is only used for the responses. Everything else that writes
+		// to stdout, like the commands and pdfium itself, writes to stderr.
+		responses := os.Stdout
+		os.Stdout = os.Stderr
+		defer func() {
+			os.Stdout = responses
+		}()
+
+		pdf.KeepPdfiumLoaded(true)
+		err := pdf.LoadPdfiumPool(workerPoolSize)
+		if err != nil {
+			handleError(cmd, fmt.Errorf("could not load pdfium: %w\n", newPdfiumError(err)), ExitCodePdfiumError)
+			return
+		}
+		defer func() {
+			pdf.KeepPdfiumLoaded(false)
+			pdf.ClosePdfium()
+		}()
+
+		// The output of the commands is not useful in the worker.
+		rootCmd.SetOut(io.Discard)
+		rootCmd.SetErr(io.Discard)
+		cmd.SetOut(os.Stderr)
+		cmd.SetErr(os.Stderr)
+
+		encoder := json.NewEncoder(responses)
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			line, readErr := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				response := handleWorkerRequest(line)
+				if err := encoder.Encode(response); err != nil {
+					handleError(cmd, fmt.Errorf("could not write response: %w\n", err), ExitCodeInvalidOutput)
+					return
+				}
+			}
+
+			if readErr != nil {
+				if errors.Is(readErr, io.EOF) {
+					return
+				}
+				handleError(cmd, fmt.Errorf("could not read request: %w\n", readErr), ExitCodeInvalidInput)
+				return
+			}
+		}
+	},
+}
+
+// handleWorkerRequest runs the command of a request.
+func handleWorkerRequest(line []byte) *workerResponse {
+	request := workerRequest{}
+	if err := json.Unmarshal(line, &request); err != nil {
+		return &workerResponse{ExitCode: ExitCodeInvalidArguments, Error: fmt.Sprintf("could not read request: %s", err.Error()), Outputs: []workerOutput{}}
+	}
+
+	response := &workerResponse{Id: request.Id, Outputs: []workerOutput{}}
+	fail := func(err error, exitCode int) *workerResponse {
+		response.ExitCode = exitCode
+		response.Error = err.Error()
+		return response
+	}
+
+	command, ok := inProcessCommands[request.Command]
+	if !ok {
+		return fail(fmt.Errorf("unknown command %s, supported are info, text, form, render, merge, explode, flatten, images and attachments", request.Command), ExitCodeInvalidArguments)
+	}
+
+	if len(request.Inputs) == 0 {
+		return fail(errors.New("no input given"), ExitCodeInvalidArguments)
+	}
+
+	if len(request.Inputs) > 1 && !command.multipleInputs {
+		return fail(fmt.Errorf("the command %s takes one input, got %d", request.Command, len(request.Inputs)), ExitCodeInvalidArguments)
+	}
+
+	// The temporary folder is created inside the output folder, so that the
+	// outputs can be moved instead of copied.
+	if request.OutputFolder != "" {
+		if err := os.MkdirAll(request.OutputFolder, 0755); err != nil {
+			return fail(fmt.Errorf("could not create output folder: %w", err), ExitCodeInvalidOutput)
+		}
+	}
+
+	tempDir, err := os.MkdirTemp(request.OutputFolder, ".pdfium-cli-worker-")
+	if err != nil {
+		return fail(fmt.Errorf("could not create temporary folder: %w", err), ExitCodeInvalidOutput)
+	}
+	defer os.RemoveAll(tempDir)
+
+	inputs := []string{}
+	for i, input := range request.Inputs {
+		if input.Path != "" {
+			if input.Path == stdFilename {
+				return fail(errors.New("stdin can't be used as input in the worker"), ExitCodeInvalidArguments)
+			}
+			inputs = append(inputs, input.Path)
+			continue
+		}
+
+		// Every input gets its own folder so that the original filename can
+		// be kept for the output names.
+		folder := filepath.Join(tempDir, "in", strconv.Itoa(i+1))
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			return fail(err, ExitCodeInvalidInput)
+		}
+
+		path := filepath.Join(folder, sanitizeInputFilename(input.Name))
+		if err := os.WriteFile(path, input.Data, 0600); err != nil {
+			return fail(fmt.Errorf("could not write input: %w", err), ExitCodeInvalidInput)
+		}
+		inputs = append(inputs, path)
+	}
+
+	outputFolder := filepath.Join(tempDir, "out")
+	if err := os.MkdirAll(outputFolder, 0755); err != nil {
+		return fail(err, ExitCodeInvalidOutput)
+	}
+
+	commandArgs := append([]string{request.Command}, inputs...)
+	commandArgs = append(commandArgs, command.getOutputArgument(outputFolder, inputs[0], getOptionValue(request.Args, "output-type"), getOptionValue(request.Args, "archive")))
+	commandArgs = append(commandArgs, request.Args...)
+
+	exitCode, err := runCommandInProcess(commandArgs)
+	if err != nil {
+		// Don't show the temporary folders.
+		message := strings.ReplaceAll(strings.TrimSpace(err.Error()), outputFolder+string(filepath.Separator), "")
+		message = strings.ReplaceAll(message, tempDir+string(filepath.Separator), "")
+		return fail(errors.New(message), exitCode)
+	}
+
+	files, err := findOutputFiles(outputFolder)
+	if err != nil {
+		return fail(fmt.Errorf("could not read outputs: %w", err), ExitCodeInvalidOutput)
+	}
+
+	for _, file := range files {
+		name, err := filepath.Rel(outputFolder, file)
+		if err != nil {
+			return fail(err, ExitCodeInvalidOutput)
+		}
+
+		output := workerOutput{Name: filepath.ToSlash(name)}
+		if request.OutputFolder != "" {
+			output.Path = filepath.Join(request.OutputFolder, name)
+			if err := os.MkdirAll(filepath.Dir(output.Path), 0755); err != nil {
+				return fail(fmt.Errorf("could not write output %s: %w", output.Path, err), ExitCodeInvalidOutput)
+			}
+			if err := os.Rename(file, output.Path); err != nil {
+				return fail(fmt.Errorf("could not write output %s: %w", output.Path, err), ExitCodeInvalidOutput)
+			}
+		} else {
+			output.Data, err = os.ReadFile(file)
+			if err != nil {
+				return fail(fmt.Errorf("could not read output %s: %w", name, err), ExitCodeInvalidOutput)
+			}
+		}
+
+		response.Outputs = append(response.Outputs, output)
+	}
+
+	return response
+}
+
+// getOptionValue returns the last value of an option in the arguments of a
+// command, or an empty string when the option is not given.
+func getOptionValue(args []string, name string) string {
+	value := ""
+	for i, arg := range args {
+		if arg == "--"+name && i+1 < len(args) {
+			value = args[i+1]
+		} else if strings.HasPrefix(arg, "--"+name+"=") {
+			value = strings.TrimPrefix(arg, "--"+name+"=")
+		}
+	}
+	return value
+}
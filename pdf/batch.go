@@ -0,0 +1,186 @@
+package pdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BatchInput is an input file of a batch.
+type BatchInput struct {
+	Path string // The path of the input file.
+	Dir  string // The folder of the input file, relative to the part of the input without wildcards.
+}
+
+// FindBatchInputs finds the input files of a batch. An input can be a file,
+// a folder that is searched recursively for files with one of the extensions,
+// or a glob pattern. Files that are found more than once are only returned
+// once. The files of a folder or glob are sorted by path.
+func FindBatchInputs(inputs []string, extensions []string) ([]BatchInput, error) {
+	result := []BatchInput{}
+	seen := map[string]bool{}
+
+	add := func(path string, base string) {
+		cleanPath := filepath.Clean(path)
+		if seen[cleanPath] {
+			return
+		}
+		seen[cleanPath] = true
+
+		dir, err := filepath.Rel(base, filepath.Dir(cleanPath))
+		if err != nil || strings.HasPrefix(dir, "..") {
+			dir = "."
+		}
+
+		result = append(result, BatchInput{Path: path, Dir: filepath.ToSlash(dir)})
+	}
+
+	for _, input := range inputs {
+		matches := []string{input}
+		base := filepath.Dir(input)
+		isGlob := strings.ContainsAny(input, "*?[")
+		if isGlob {
+			var err error
+			matches, err = filepath.Glob(input)
+			if err != nil {
+				return nil, fmt.Errorf("invalid glob %s: %w", input, err)
+			}
+
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %s", input)
+			}
+
+			sort.Strings(matches)
+			base = globBase(input)
+		}
+
+		for _, match := range matches {
+			stat, err := os.Stat(match)
+			if err != nil {
+				return nil, fmt.Errorf("could not open input %s: %w", match, err)
+			}
+
+			if !stat.IsDir() {
+				add(match, base)
+				continue
+			}
+
+			// Folders given directly are the base of their files, folders
+			// from a glob keep the base of the glob.
+			folderBase := match
+			if isGlob {
+				folderBase = base
+			}
+
+			folderFiles := []string{}
+			err = filepath.WalkDir(match, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+
+				if entry.IsDir() || !hasExtension(path, extensions) {
+					return nil
+				}
+
+				folderFiles = append(folderFiles, path)
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("could not search folder %s: %w", match, err)
+			}
+
+			sort.Strings(folderFiles)
+			for _, folderFile := range folderFiles {
+				add(folderFile, folderBase)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// globBase returns the folder of a glob pattern before the first part with
+// wildcards.
+func globBase(pattern string) string {
+	parts := strings.Split(filepath.ToSlash(pattern), "/")
+	for i, part := range parts {
+		if strings.ContainsAny(part, "*?[") {
+			if i == 0 {
+				return "."
+			}
+			if i == 1 && parts[0] == "" {
+				return "/"
+			}
+			return filepath.FromSlash(strings.Join(parts[:i], "/"))
+		}
+	}
+	return filepath.Dir(pattern)
+}
+
+func hasExtension(path string, extensions []string) bool {
+	if len(extensions) == 0 {
+		return true
+	}
+
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	for _, extension := range extensions {
+		if ext == strings.TrimPrefix(strings.ToLower(extension), ".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadBatchInputList reads a list of inputs, one per line. Empty lines and
+// lines starting with # are skipped.
+func ReadBatchInputList(reader io.Reader) ([]string, error) {
+	inputs := []string{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		inputs = append(inputs, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return inputs, nil
+}
+
+// ExpandBatchArguments returns the arguments of a command for an input of a
+// batch. The first argument is the name of the command. The tokens {file}
+// (the path of the input), {dir} (the folder of the input) and {input} (the
+// filename of the input without extension) are replaced in every argument.
+// When no argument contains {file}, the path of the input is given as the
+// first argument of the command.
+func ExpandBatchArguments(args []string, input BatchInput) []string {
+	replacer := strings.NewReplacer("{file}", input.Path, "{dir}", input.Dir, "{input}", OutputTemplateInputName(input.Path))
+
+	hasFile := false
+	for _, arg := range args {
+		if strings.Contains(arg, "{file}") {
+			hasFile = true
+			break
+		}
+	}
+
+	result := []string{}
+	for i, arg := range args {
+		result = append(result, replacer.Replace(arg))
+		if i == 0 && !hasFile {
+			result = append(result, input.Path)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,134 @@
+package pdf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindBatchInputs(t *testing.T) {
+	folder := t.TempDir()
+	for _, file := range []string{"a.pdf", "b.PDF", "c.txt", "sub/d.pdf", "sub/deeper/e.pdf", "other/f.pdf"} {
+		path := filepath.Join(folder, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("expected no error but got error %s", err.Error())
+		}
+		if err := os.WriteFile(path, []byte("%PDF"), 0644); err != nil {
+			t.Fatalf("expected no error but got error %s", err.Error())
+		}
+	}
+
+	path := func(file string) string {
+		return filepath.Join(folder, filepath.FromSlash(file))
+	}
+
+	tests := []struct {
+		name       string
+		inputs     []string
+		extensions []string
+		want       []BatchInput
+		wantErr    bool
+	}{
+		{"test file", []string{path("c.txt")}, []string{"pdf"}, []BatchInput{{Path: path("c.txt"), Dir: "."}}, false},
+		{
+			"test folder",
+			[]string{path("sub")},
+			[]string{"pdf"},
+			[]BatchInput{{Path: path("sub/d.pdf"), Dir: "."}, {Path: path("sub/deeper/e.pdf"), Dir: "deeper"}},
+			false,
+		},
+		{
+			"test folder with extensions",
+			[]string{folder},
+			[]string{".pdf"},
+			[]BatchInput{
+				{Path: path("a.pdf"), Dir: "."},
+				{Path: path("b.PDF"), Dir: "."},
+				{Path: path("other/f.pdf"), Dir: "other"},
+				{Path: path("sub/d.pdf"), Dir: "sub"},
+				{Path: path("sub/deeper/e.pdf"), Dir: "sub/deeper"},
+			},
+			false,
+		},
+		{
+			"test glob",
+			[]string{path("*/*.pdf")},
+			[]string{"pdf"},
+			[]BatchInput{{Path: path("other/f.pdf"), Dir: "other"}, {Path: path("sub/d.pdf"), Dir: "sub"}},
+			false,
+		},
+		{
+			"test duplicates",
+			[]string{path("a.pdf"), path("*.pdf"), path("a.pdf")},
+			[]string{"pdf"},
+			[]BatchInput{{Path: path("a.pdf"), Dir: "."}},
+			false,
+		},
+		{"test missing file", []string{path("missing.pdf")}, []string{"pdf"}, nil, true},
+		{"test glob without matches", []string{path("*.doc")}, []string{"pdf"}, nil, true},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, err := FindBatchInputs(tests[i].inputs, tests[i].extensions)
+			if tests[i].wantErr {
+				if err == nil {
+					t.Errorf("expected error but got no error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+
+			if !reflect.DeepEqual(got, tests[i].want) {
+				t.Errorf("expected %+v but got %+v", tests[i].want, got)
+			}
+		})
+	}
+}
+
+func TestReadBatchInputList(t *testing.T) {
+	got, err := ReadBatchInputList(strings.NewReader("a.pdf\n\n# comment\n  docs/b.pdf  \r\nfolder\n"))
+	if err != nil {
+		t.Fatalf("expected no error but got error %s", err.Error())
+	}
+
+	want := []string{"a.pdf", "docs/b.pdf", "folder"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v but got %v", want, got)
+	}
+}
+
+func TestExpandBatchArguments(t *testing.T) {
+	input := BatchInput{Path: "docs/2024/invoice.pdf", Dir: "2024"}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			"test input as first argument",
+			[]string{"render", "out/{dir}/{input}-{page}.png", "--dpi", "150"},
+			[]string{"render", "docs/2024/invoice.pdf", "out/2024/invoice-{page}.png", "--dpi", "150"},
+		},
+		{
+			"test file token",
+			[]string{"merge", "cover.pdf", "{file}", "out/{input}.pdf"},
+			[]string{"merge", "cover.pdf", "docs/2024/invoice.pdf", "out/invoice.pdf"},
+		},
+		{"test only command", []string{"info"}, []string{"info", "docs/2024/invoice.pdf"}},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			if got := ExpandBatchArguments(tests[i].args, input); !reflect.DeepEqual(got, tests[i].want) {
+				t.Errorf("expected %v but got %v", tests[i].want, got)
+			}
+		})
+	}
+}
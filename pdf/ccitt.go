@@ -0,0 +1,199 @@
+package pdf
+
+import (
+	"bytes"
+)
+
+// The run length codes of ITU-T T.4, as strings of bits. The terminating
+// codes are for runs of 0 to 63 pixels, the makeup codes for runs of 64 to
+// 1728 pixels in steps of 64.
+var (
+	ccittWhiteTerminatingCodes = [64]string{
+		"00110101", "000111", "0111", "1000", "1011", "1100", "1110", "1111",
+		"10011", "10100", "00111", "01000", "001000", "000011", "110100", "110101",
+		"101010", "101011", "0100111", "0001100", "0001000", "0010111", "0000011", "0000100",
+		"0101000", "0101011", "0010011", "0100100", "0011000", "00000010", "00000011", "00011010",
+		"00011011", "00010010", "00010011", "00010100", "00010101", "00010110", "00010111", "00101000",
+		"00101001", "00101010", "00101011", "00101100", "00101101", "00000100", "00000101", "00001010",
+		"00001011", "01010010", "01010011", "01010100", "01010101", "00100100", "00100101", "01011000",
+		"01011001", "01011010", "01011011", "01001010", "01001011", "00110010", "00110011", "00110100",
+	}
+	ccittWhiteMakeupCodes = [27]string{
+		"11011", "10010", "010111", "0110111", "00110110", "00110111", "01100100", "01100101",
+		"01101000", "01100111", "011001100", "011001101", "011010010", "011010011", "011010100", "011010101",
+		"011010110", "011010111", "011011000", "011011001", "011011010", "011011011", "010011000", "010011001",
+		"010011010", "011000", "010011011",
+	}
+	ccittBlackTerminatingCodes = [64]string{
+		"0000110111", "010", "11", "10", "011", "0011", "0010", "00011",
+		"000101", "000100", "0000100", "0000101", "0000111", "00000100", "00000111", "000011000",
+		"0000010111", "0000011000", "0000001000", "00001100111", "00001101000", "00001101100", "00000110111", "00000101000",
+		"00000010111", "00000011000", "000011001010", "000011001011", "000011001100", "000011001101", "000001101000", "000001101001",
+		"000001101010", "000001101011", "000011010010", "000011010011", "000011010100", "000011010101", "000011010110", "000011010111",
+		"000001101100", "000001101101", "000011011010", "000011011011", "000001010100", "000001010101", "000001010110", "000001010111",
+		"000001100100", "000001100101", "000001010010", "000001010011", "000000100100", "000000110111", "000000111000", "000000100111",
+		"000000101000", "000001011000", "000001011001", "000000101011", "000000101100", "000001011010", "000001100110", "000001100111",
+	}
+	ccittBlackMakeupCodes = [27]string{
+		"0000001111", "000011001000", "000011001001", "000001011011", "000000110011", "000000110100", "000000110101", "0000001101100",
+		"0000001101101", "0000001001010", "0000001001011", "0000001001100", "0000001001101", "0000001110010", "0000001110011", "0000001110100",
+		"0000001110101", "0000001110110", "0000001110111", "0000001010010", "0000001010011", "0000001010100", "0000001010101", "0000001011010",
+		"0000001011011", "0000001100100", "0000001100101",
+	}
+
+	// The makeup codes for runs of 1792 to 2560 pixels, shared by both colors.
+	ccittExtendedMakeupCodes = [13]string{
+		"00000001000", "00000001100", "00000001101", "000000010010", "000000010011", "000000010100", "000000010101",
+		"000000010110", "000000010111", "000000011100", "000000011101", "000000011110", "000000011111",
+	}
+
+	// The vertical mode codes for a distance between a1 and b1 of -3 to 3.
+	ccittVerticalCodes = [7]string{"0000010", "000010", "010", "1", "011", "000011", "0000011"}
+)
+
+const (
+	ccittPassCode       = "0001"
+	ccittHorizontalCode = "001"
+	ccittEOL            = "000000000001"
+)
+
+// ccittBitWriter writes codes MSB first.
+type ccittBitWriter struct {
+	out      bytes.Buffer
+	current  byte
+	bitCount uint
+}
+
+func (w *ccittBitWriter) writeCode(code string) {
+	for i := 0; i < len(code); i++ {
+		w.current <<= 1
+		if code[i] == '1' {
+			w.current |= 1
+		}
+		w.bitCount++
+		if w.bitCount == 8 {
+			w.out.WriteByte(w.current)
+			w.current = 0
+			w.bitCount = 0
+		}
+	}
+}
+
+func (w *ccittBitWriter) writeRun(length int, black bool) {
+	terminatingCodes, makeupCodes := &ccittWhiteTerminatingCodes, &ccittWhiteMakeupCodes
+	if black {
+		terminatingCodes, makeupCodes = &ccittBlackTerminatingCodes, &ccittBlackMakeupCodes
+	}
+
+	for length >= 2560 {
+		w.writeCode(ccittExtendedMakeupCodes[len(ccittExtendedMakeupCodes)-1])
+		length -= 2560
+	}
+
+	if length >= 1792 {
+		w.writeCode(ccittExtendedMakeupCodes[(length-1792)/64])
+		length %= 64
+	} else if length >= 64 {
+		w.writeCode(makeupCodes[length/64-1])
+		length %= 64
+	}
+
+	w.writeCode(terminatingCodes[length])
+}
+
+func (w *ccittBitWriter) bytes() []byte {
+	if w.bitCount > 0 {
+		w.out.WriteByte(w.current << (8 - w.bitCount))
+		w.current = 0
+		w.bitCount = 0
+	}
+	return w.out.Bytes()
+}
+
+// encodeCCITTG4 compresses 1-bit rows, where 1 is black and every row starts
+// on a new byte, with CCITT Group 4 (ITU-T T.6) two-dimensional coding.
+func encodeCCITTG4(data []byte, width int, height int) []byte {
+	rowSize := (width + 7) / 8
+	writer := &ccittBitWriter{}
+
+	// The reference line of the first row is an imaginary white line.
+	reference := make([]byte, rowSize)
+	for y := 0; y < height; y++ {
+		row := data[y*rowSize : (y+1)*rowSize]
+		encodeCCITTG4Row(writer, row, reference, width)
+		reference = row
+	}
+
+	// End of facsimile block.
+	writer.writeCode(ccittEOL)
+	writer.writeCode(ccittEOL)
+
+	return writer.bytes()
+}
+
+func encodeCCITTG4Row(writer *ccittBitWriter, row []byte, reference []byte, width int) {
+	pixel := func(line []byte, x int) bool {
+		if x < 0 || x >= width {
+			return false
+		}
+		return line[x/8]&(0x80>>(x%8)) != 0
+	}
+
+	// nextChange returns the position of the first pixel after start with
+	// a different color than the pixel at start, or width when there is none.
+	nextChange := func(line []byte, start int) int {
+		if start >= width {
+			return width
+		}
+		color := pixel(line, start)
+		x := start + 1
+		for x < width && pixel(line, x) == color {
+			x++
+		}
+		return x
+	}
+
+	// findChange returns the first changing element after a0 that changes
+	// to the given color, a0 being -1 at the start of the line.
+	findChange := func(line []byte, a0 int, black bool) int {
+		x := a0 + 1
+		for x < width {
+			if pixel(line, x) == black && pixel(line, x-1) != black {
+				return x
+			}
+			x++
+		}
+		return width
+	}
+
+	a0 := -1
+	black := false
+	for a0 < width {
+		a1 := findChange(row, a0, !black)
+		b1 := findChange(reference, a0, !black)
+		b2 := nextChange(reference, b1)
+
+		if b2 < a1 {
+			writer.writeCode(ccittPassCode)
+			a0 = b2
+			continue
+		}
+
+		if distance := a1 - b1; distance >= -3 && distance <= 3 {
+			writer.writeCode(ccittVerticalCodes[distance+3])
+			a0 = a1
+			black = !black
+			continue
+		}
+
+		a2 := nextChange(row, a1)
+		start := a0
+		if start < 0 {
+			start = 0
+		}
+		writer.writeCode(ccittHorizontalCode)
+		writer.writeRun(a1-start, black)
+		writer.writeRun(a2-a1, !black)
+		a0 = a2
+	}
+}
@@ -8,9 +8,12 @@ import (
 	"github.com/klippa-app/go-pdfium/single_threaded"
 )
 
-func LoadPdfium() error {
+// LoadPdfiumPool loads the pdfium library. All instances share the same
+// library, which is not thread safe, so calls to pdfium are executed one at a
+// time. The instances parameter is only used for WebAssembly.
+func LoadPdfiumPool(instances int) error {
 	if isLoaded {
-		return nil
+		return reloadPdfium(instances)
 	}
 
 	var err error
@@ -24,6 +27,7 @@ func LoadPdfium() error {
 	}
 
 	isLoaded = true
+	loadedInstances = instances
 
 	return nil
 }
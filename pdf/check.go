@@ -0,0 +1,281 @@
+package pdf
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+)
+
+// CheckStatus is the result of a check.
+type CheckStatus string
+
+const (
+	CheckStatusPass    CheckStatus = "pass"    // Everything could be read.
+	CheckStatusWarn    CheckStatus = "warn"    // Something could not be read, but the pages can be used.
+	CheckStatusFail    CheckStatus = "fail"    // The document or a page could not be read.
+	CheckStatusSkipped CheckStatus = "skipped" // The check is not supported by this build.
+)
+
+// severity returns how bad the status is, skipped counts as passed.
+func (s CheckStatus) severity() int {
+	switch s {
+	case CheckStatusWarn:
+		return 1
+	case CheckStatusFail:
+		return 2
+	}
+	return 0
+}
+
+// Worst returns the worst of the statuses, pass when there are none.
+func (s CheckStatus) Worst(others ...CheckStatus) CheckStatus {
+	worst := s
+	if worst == CheckStatusSkipped || worst == "" {
+		worst = CheckStatusPass
+	}
+
+	for _, other := range others {
+		if other.severity() > worst.severity() {
+			worst = other
+		}
+	}
+
+	return worst
+}
+
+// CheckReport is the result of checking a document.
+type CheckReport struct {
+	File                     string
+	Status                   CheckStatus
+	Error                    string `json:",omitempty"` // The error of opening the document.
+	ValidCrossReferenceTable *bool  `json:",omitempty"` // Whether the cross reference table was valid, nil when unknown.
+	PageCount                int
+	Pages                    []CheckPage
+	Forms                    CheckItem // The form fields of all pages.
+	Attachments              CheckItem
+	Signatures               CheckItem
+	Milliseconds             float64 // The duration of the whole check.
+}
+
+// CheckPage is the result of checking a page.
+type CheckPage struct {
+	Page               int // The page number (1-index based).
+	Status             CheckStatus
+	Characters         int    // The amount of characters of the text.
+	LoadError          string `json:",omitempty"`
+	TextError          string `json:",omitempty"`
+	RenderError        string `json:",omitempty"`
+	LoadMilliseconds   float64
+	TextMilliseconds   float64
+	RenderMilliseconds float64
+}
+
+// CheckItem is the result of reading a part of the document, like the
+// attachments.
+type CheckItem struct {
+	Status CheckStatus
+	Count  int
+	Error  string `json:",omitempty"`
+}
+
+// finish sets the status of the pages and the document. A document fails when
+// it could not be read, has no pages or when a page could not be loaded or
+// rendered. Other errors and a broken cross reference table are warnings.
+func (r *CheckReport) finish() {
+	if r.Error != "" || r.PageCount == 0 {
+		r.Status = CheckStatusFail
+		return
+	}
+
+	r.Status = CheckStatusPass
+	for i := range r.Pages {
+		page := &r.Pages[i]
+		page.Status = CheckStatusPass
+		if page.TextError != "" {
+			page.Status = CheckStatusWarn
+		}
+		if page.LoadError != "" || page.RenderError != "" {
+			page.Status = CheckStatusFail
+		}
+		r.Status = r.Status.Worst(page.Status)
+	}
+
+	r.Status = r.Status.Worst(r.Forms.Status, r.Attachments.Status, r.Signatures.Status)
+	if r.ValidCrossReferenceTable != nil && !*r.ValidCrossReferenceTable {
+		r.Status = r.Status.Worst(CheckStatusWarn)
+	}
+}
+
+// FailedPages returns the pages that didn't pass.
+func (r *CheckReport) FailedPages() []CheckPage {
+	failedPages := []CheckPage{}
+	for _, page := range r.Pages {
+		if page.Status != CheckStatusPass {
+			failedPages = append(failedPages, page)
+		}
+	}
+	return failedPages
+}
+
+// CheckDocument checks whether a document can be processed, by loading every
+// page, extracting its text, rendering it in the DPI and reading the forms,
+// attachments and signatures. The errors in the report are the errors of
+// pdfium, File and Milliseconds are not set.
+func CheckDocument(instance pdfium.Pdfium, document references.FPDF_DOCUMENT, dpi int) *CheckReport {
+	report := &CheckReport{
+		Pages: []CheckPage{},
+	}
+	defer report.finish()
+
+	// Only available in experimental builds of the native version.
+	crossReferenceTable, err := instance.FPDF_DocumentHasValidCrossReferenceTable(&requests.FPDF_DocumentHasValidCrossReferenceTable{
+		Document: document,
+	})
+	if err == nil {
+		report.ValidCrossReferenceTable = &crossReferenceTable.DocumentHasValidCrossReferenceTable
+	}
+
+	pageCount, err := instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+		Document: document,
+	})
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.PageCount = pageCount.PageCount
+
+	report.Forms = CheckItem{Status: CheckStatusPass}
+	for i := 0; i < pageCount.PageCount; i++ {
+		page, fields := checkPage(instance, document, i, dpi)
+		report.Pages = append(report.Pages, page)
+
+		if fields.Error != "" && report.Forms.Error == "" {
+			report.Forms.Error = fmt.Sprintf("page %d: %s", i+1, fields.Error)
+		}
+		report.Forms.Count += fields.Count
+		report.Forms.Status = report.Forms.Status.Worst(fields.Status)
+		if fields.Status == CheckStatusSkipped {
+			report.Forms.Status = CheckStatusSkipped
+		}
+	}
+
+	report.Attachments = CheckItem{Status: CheckStatusPass}
+	attachments, err := instance.GetAttachments(&requests.GetAttachments{
+		Document: document,
+	})
+	if err != nil {
+		report.Attachments = checkItemError(err)
+	} else {
+		report.Attachments.Count = len(attachments.Attachments)
+	}
+
+	report.Signatures = checkSignatures(instance, document)
+
+	return report
+}
+
+// checkPage loads a page, extracts its text, renders it and reads its form
+// fields.
+func checkPage(instance pdfium.Pdfium, document references.FPDF_DOCUMENT, index int, dpi int) (CheckPage, CheckItem) {
+	result := CheckPage{Page: index + 1}
+	fields := CheckItem{Status: CheckStatusPass}
+
+	start := time.Now()
+	page, err := instance.FPDF_LoadPage(&requests.FPDF_LoadPage{
+		Document: document,
+		Index:    index,
+	})
+	result.LoadMilliseconds = milliseconds(time.Since(start))
+	if err != nil {
+		result.LoadError = err.Error()
+		return result, fields
+	}
+	defer instance.FPDF_ClosePage(&requests.FPDF_ClosePage{Page: page.Page})
+
+	start = time.Now()
+	pageText, err := instance.GetPageText(&requests.GetPageText{
+		Page: requests.Page{
+			ByReference: &page.Page,
+		},
+	})
+	result.TextMilliseconds = milliseconds(time.Since(start))
+	if err != nil {
+		result.TextError = err.Error()
+	} else {
+		result.Characters = utf8.RuneCountInString(pageText.Text)
+	}
+
+	start = time.Now()
+	renderedPage, err := instance.RenderPageInDPI(&requests.RenderPageInDPI{
+		Page: requests.Page{
+			ByReference: &page.Page,
+		},
+		DPI: dpi,
+	})
+	result.RenderMilliseconds = milliseconds(time.Since(start))
+	if err != nil {
+		result.RenderError = err.Error()
+	} else {
+		renderedPage.Cleanup()
+	}
+
+	pageForm, err := instance.GetForm(&requests.GetForm{
+		Page: requests.Page{
+			ByReference: &page.Page,
+		},
+	})
+	if err != nil {
+		fields = checkItemError(err)
+	} else {
+		fields.Count = len(pageForm.Fields)
+	}
+
+	return result, fields
+}
+
+// checkSignatures reads the contents of every signature.
+func checkSignatures(instance pdfium.Pdfium, document references.FPDF_DOCUMENT) CheckItem {
+	signatureCount, err := instance.FPDF_GetSignatureCount(&requests.FPDF_GetSignatureCount{
+		Document: document,
+	})
+	if err != nil {
+		return checkItemError(err)
+	}
+
+	for i := 0; i < signatureCount.Count; i++ {
+		signature, err := instance.FPDF_GetSignatureObject(&requests.FPDF_GetSignatureObject{
+			Document: document,
+			Index:    i,
+		})
+		if err != nil {
+			return checkItemError(fmt.Errorf("could not get signature %d: %w", i+1, err))
+		}
+
+		_, err = instance.FPDFSignatureObj_GetContents(&requests.FPDFSignatureObj_GetContents{
+			Signature: signature.Signature,
+		})
+		if err != nil {
+			return checkItemError(fmt.Errorf("could not get contents of signature %d: %w", i+1, err))
+		}
+	}
+
+	return CheckItem{Status: CheckStatusPass, Count: signatureCount.Count}
+}
+
+// checkItemError returns the result of a part that could not be read, which
+// is skipped when the build doesn't support it.
+func checkItemError(err error) CheckItem {
+	if IsExperimentalError(err) {
+		return CheckItem{Status: CheckStatusSkipped, Error: "not supported by this build, build with the build tag pdfium_experimental to enable"}
+	}
+
+	return CheckItem{Status: CheckStatusWarn, Error: err.Error()}
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
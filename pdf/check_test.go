@@ -0,0 +1,102 @@
+package pdf
+
+import (
+	"testing"
+)
+
+func TestCheckStatusWorst(t *testing.T) {
+	tests := []struct {
+		name   string
+		status CheckStatus
+		others []CheckStatus
+		want   CheckStatus
+	}{
+		{"test none", CheckStatusPass, nil, CheckStatusPass},
+		{"test empty", "", nil, CheckStatusPass},
+		{"test warn", CheckStatusPass, []CheckStatus{CheckStatusWarn, CheckStatusPass}, CheckStatusWarn},
+		{"test fail", CheckStatusWarn, []CheckStatus{CheckStatusFail, CheckStatusWarn}, CheckStatusFail},
+		{"test stays fail", CheckStatusFail, []CheckStatus{CheckStatusPass}, CheckStatusFail},
+		{"test skipped", CheckStatusSkipped, []CheckStatus{CheckStatusSkipped}, CheckStatusPass},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			if got := tests[i].status.Worst(tests[i].others...); got != tests[i].want {
+				t.Errorf("expected %s but got %s", tests[i].want, got)
+			}
+		})
+	}
+}
+
+func TestCheckDocument(t *testing.T) {
+	instance := loadTestPdfium(t)
+
+	tests := []struct {
+		name          string
+		file          []byte
+		want          CheckStatus
+		wantPageCount int
+	}{
+		{"test pass", generateTestPDF(t, instance, "Page 1", "Page two"), CheckStatusPass, 2},
+		{"test no pages", generateTestPDF(t, instance), CheckStatusFail, 0},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			document := openTestPDF(t, instance, tests[i].file)
+			report := CheckDocument(instance, document, 18)
+
+			if report.Status != tests[i].want || report.PageCount != tests[i].wantPageCount || len(report.Pages) != tests[i].wantPageCount {
+				t.Errorf("expected %s with %d pages but got %+v", tests[i].want, tests[i].wantPageCount, report)
+			}
+
+			for _, status := range []CheckStatus{report.Forms.Status, report.Attachments.Status, report.Signatures.Status} {
+				if status != CheckStatusPass && status != CheckStatusSkipped {
+					t.Errorf("expected the forms, attachments and signatures to pass but got %+v", report)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckPage(t *testing.T) {
+	instance := loadTestPdfium(t)
+	document := openTestPDF(t, instance, generateTestPDF(t, instance, "Page 1", "Page two"))
+
+	tests := []struct {
+		name           string
+		index          int
+		wantCharacters int
+		wantLoadError  bool
+	}{
+		{"test first page", 0, 6, false},
+		{"test second page", 1, 8, false},
+		{"test page that doesn't exist", 2, 0, true},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			page, _ := checkPage(instance, document, tests[i].index, 18)
+			if page.Page != tests[i].index+1 || page.Characters != tests[i].wantCharacters {
+				t.Errorf("expected page %d with %d characters but got %+v", tests[i].index+1, tests[i].wantCharacters, page)
+			}
+			if (page.LoadError != "") != tests[i].wantLoadError {
+				t.Errorf("expected load error %t but got %q", tests[i].wantLoadError, page.LoadError)
+			}
+			if !tests[i].wantLoadError && (page.TextError != "" || page.RenderError != "") {
+				t.Errorf("expected no text and render errors but got %+v", page)
+			}
+		})
+	}
+
+	// A page that can't be loaded makes the document fail.
+	report := CheckReport{PageCount: 3, Pages: []CheckPage{}}
+	for index := 0; index < 3; index++ {
+		page, _ := checkPage(instance, document, index, 18)
+		report.Pages = append(report.Pages, page)
+	}
+	report.finish()
+	if report.Status != CheckStatusFail || len(report.FailedPages()) != 1 {
+		t.Errorf("expected status fail with 1 failed page but got %+v", report)
+	}
+}
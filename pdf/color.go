@@ -0,0 +1,68 @@
+package pdf
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// BitonalPalette is the palette of black and white images, index 0 is black.
+var BitonalPalette = color.Palette{color.Black, color.White}
+
+// ToGray converts an image to an 8-bit gray image.
+func ToGray(img image.Image) *image.Gray {
+	if grayImage, ok := img.(*image.Gray); ok {
+		return grayImage
+	}
+
+	grayImage := image.NewGray(img.Bounds())
+	draw.Draw(grayImage, grayImage.Bounds(), img, img.Bounds().Min, draw.Src)
+	return grayImage
+}
+
+// ToBitonal converts an image to a black and white image. Pixels with a gray
+// value below the threshold become black. When dither is enabled, the error
+// of every pixel is spread over the neighbouring pixels with Floyd-Steinberg
+// dithering, which keeps the impression of gray areas like photos.
+func ToBitonal(img image.Image, threshold uint8, dither bool) *image.Paletted {
+	grayImage := ToGray(img)
+	bounds := grayImage.Bounds()
+	bitonalImage := image.NewPaletted(bounds, BitonalPalette)
+
+	width := bounds.Dx()
+	currentErrors := make([]int, width+2)
+	nextErrors := make([]int, width+2)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			column := x - bounds.Min.X
+			value := int(grayImage.GrayAt(x, y).Y)
+			if dither {
+				value += currentErrors[column+1] / 16
+			}
+
+			output := 0
+			if value >= int(threshold) {
+				output = 255
+				bitonalImage.SetColorIndex(x, y, 1)
+			}
+
+			if dither {
+				quantizationError := value - output
+				currentErrors[column+2] += quantizationError * 7
+				nextErrors[column] += quantizationError * 3
+				nextErrors[column+1] += quantizationError * 5
+				nextErrors[column+2] += quantizationError
+			}
+		}
+
+		if dither {
+			currentErrors, nextErrors = nextErrors, currentErrors
+			for i := range nextErrors {
+				nextErrors[i] = 0
+			}
+		}
+	}
+
+	return bitonalImage
+}
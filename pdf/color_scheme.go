@@ -0,0 +1,111 @@
+package pdf
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+
+	"github.com/klippa-app/go-pdfium/structs"
+)
+
+// ColorScheme contains the colors to force when rendering a page. Images
+// keep their colors.
+type ColorScheme struct {
+	PathFill   color.NRGBA
+	PathStroke color.NRGBA
+	TextFill   color.NRGBA
+	TextStroke color.NRGBA
+	Background color.NRGBA
+}
+
+// ColorSchemePresets are the color schemes that can be given by name.
+var ColorSchemePresets = map[string]ColorScheme{
+	"dark": {
+		PathFill:   color.NRGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xFF},
+		PathStroke: color.NRGBA{R: 0xBB, G: 0xBB, B: 0xBB, A: 0xFF},
+		TextFill:   color.NRGBA{R: 0xE6, G: 0xE6, B: 0xE6, A: 0xFF},
+		TextStroke: color.NRGBA{R: 0xE6, G: 0xE6, B: 0xE6, A: 0xFF},
+		Background: color.NRGBA{R: 0x1E, G: 0x1E, B: 0x1E, A: 0xFF},
+	},
+	"high-contrast": {
+		PathFill:   color.NRGBA{A: 0xFF},
+		PathStroke: color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+		TextFill:   color.NRGBA{R: 0xFF, G: 0xFF, A: 0xFF},
+		TextStroke: color.NRGBA{R: 0xFF, G: 0xFF, A: 0xFF},
+		Background: color.NRGBA{A: 0xFF},
+	},
+}
+
+// ParseColorScheme parses a color scheme. The value is a comma separated list
+// that can start with the name of a preset, followed by colors to override,
+// like dark,text-fill=#00ff00. Without preset, the colors start as black on
+// white. The colors are path-fill, path-stroke, text-fill, text-stroke and
+// background.
+func ParseColorScheme(value string) (*ColorScheme, error) {
+	colorScheme := ColorScheme{
+		PathFill:   color.NRGBA{A: 0xFF},
+		PathStroke: color.NRGBA{A: 0xFF},
+		TextFill:   color.NRGBA{A: 0xFF},
+		TextStroke: color.NRGBA{A: 0xFF},
+		Background: color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+	}
+
+	for i, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		name, colorValue, isColor := strings.Cut(item, "=")
+		if !isColor {
+			preset, ok := ColorSchemePresets[item]
+			if !ok || i > 0 {
+				return nil, fmt.Errorf("invalid color scheme %s, start with one of the presets %s, or give colors like text-fill=#ffffff", value, strings.Join(colorSchemePresetNames(), ", "))
+			}
+			colorScheme = preset
+			continue
+		}
+
+		parsedColor, err := ParseColor(strings.TrimSpace(colorValue))
+		if err != nil {
+			return nil, err
+		}
+
+		switch strings.TrimSpace(name) {
+		case "path-fill":
+			colorScheme.PathFill = parsedColor
+		case "path-stroke":
+			colorScheme.PathStroke = parsedColor
+		case "text-fill":
+			colorScheme.TextFill = parsedColor
+		case "text-stroke":
+			colorScheme.TextStroke = parsedColor
+		case "background":
+			colorScheme.Background = parsedColor
+		default:
+			return nil, fmt.Errorf("invalid color %s in color scheme, use path-fill, path-stroke, text-fill, text-stroke or background", name)
+		}
+	}
+
+	return &colorScheme, nil
+}
+
+// FPDFColorScheme returns the color scheme for pdfium.
+func (c ColorScheme) FPDFColorScheme() structs.FPDF_COLORSCHEME {
+	return structs.FPDF_COLORSCHEME{
+		PathFillColor:   colorToARGB(c.PathFill),
+		PathStrokeColor: colorToARGB(c.PathStroke),
+		TextFillColor:   colorToARGB(c.TextFill),
+		TextStrokeColor: colorToARGB(c.TextStroke),
+	}
+}
+
+func colorToARGB(c color.NRGBA) uint64 {
+	return uint64(c.A)<<24 | uint64(c.R)<<16 | uint64(c.G)<<8 | uint64(c.B)
+}
+
+func colorSchemePresetNames() []string {
+	names := []string{}
+	for name := range ColorSchemePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
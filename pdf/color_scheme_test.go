@@ -0,0 +1,98 @@
+package pdf
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/klippa-app/go-pdfium/structs"
+)
+
+func TestParseColorScheme(t *testing.T) {
+	black := color.NRGBA{A: 0xFF}
+	white := color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	green := color.NRGBA{G: 0xFF, A: 0xFF}
+
+	darkWithGreenText := ColorSchemePresets["dark"]
+	darkWithGreenText.TextFill = green
+
+	tests := []struct {
+		name    string
+		value   string
+		want    *ColorScheme
+		wantErr string
+	}{
+		{
+			"test preset",
+			"high-contrast",
+			&ColorScheme{PathFill: black, PathStroke: white, TextFill: color.NRGBA{R: 0xFF, G: 0xFF, A: 0xFF}, TextStroke: color.NRGBA{R: 0xFF, G: 0xFF, A: 0xFF}, Background: black},
+			"",
+		},
+		{
+			"test preset with override",
+			"dark, text-fill=#00ff00",
+			&darkWithGreenText,
+			"",
+		},
+		{
+			"test custom colors",
+			"text-fill=#00ff00,background=#000000",
+			&ColorScheme{PathFill: black, PathStroke: black, TextFill: green, TextStroke: black, Background: black},
+			"",
+		},
+		{
+			"test unknown preset",
+			"sepia",
+			nil,
+			"invalid color scheme sepia, start with one of the presets dark, high-contrast, or give colors like text-fill=#ffffff",
+		},
+		{
+			"test preset after colors",
+			"text-fill=#00ff00,dark",
+			nil,
+			"invalid color scheme text-fill=#00ff00,dark, start with one of the presets dark, high-contrast, or give colors like text-fill=#ffffff",
+		},
+		{
+			"test unknown color",
+			"dark,link=#00ff00",
+			nil,
+			"invalid color link in color scheme, use path-fill, path-stroke, text-fill, text-stroke or background",
+		},
+		{
+			"test invalid color",
+			"dark,text-fill=green",
+			nil,
+			"invalid color green, use a hex color like #ffffff or transparent",
+		},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, err := ParseColorScheme(tests[i].value)
+			if tests[i].wantErr == "" && err != nil {
+				t.Errorf("expected no error but got error %s", err.Error())
+			} else if tests[i].wantErr != "" && err == nil {
+				t.Errorf("expected error %s but got no error", tests[i].wantErr)
+			} else if tests[i].wantErr != "" && err != nil && err.Error() != tests[i].wantErr {
+				t.Errorf("expected error %s but got error %s", tests[i].wantErr, err.Error())
+			} else if err == nil && *got != *tests[i].want {
+				t.Errorf("expected %+v but got %+v", *tests[i].want, *got)
+			}
+		})
+	}
+}
+
+func TestColorSchemeFPDFColorScheme(t *testing.T) {
+	colorScheme := ColorScheme{
+		PathFill:   color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xFF},
+		PathStroke: color.NRGBA{R: 0x44, G: 0x55, B: 0x66, A: 0x80},
+	}
+
+	want := structs.FPDF_COLORSCHEME{
+		PathFillColor:   0xFF112233,
+		PathStrokeColor: 0x80445566,
+	}
+
+	if got := colorScheme.FPDFColorScheme(); got != want {
+		t.Errorf("expected %+v but got %+v", want, got)
+	}
+}
@@ -0,0 +1,72 @@
+package pdf
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestToGray(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	img.SetRGBA(1, 0, color.RGBA{R: 255, A: 255})
+
+	grayImage := ToGray(img)
+	if got := grayImage.GrayAt(0, 0).Y; got != 255 {
+		t.Errorf("expected white to be 255 but got %d", got)
+	}
+	if got := grayImage.GrayAt(1, 0).Y; got != 76 {
+		t.Errorf("expected red to be 76 but got %d", got)
+	}
+}
+
+func TestToBitonal(t *testing.T) {
+	gradient := image.NewGray(image.Rect(0, 0, 256, 1))
+	for x := 0; x < 256; x++ {
+		gradient.SetGray(x, 0, color.Gray{Y: uint8(x)})
+	}
+
+	uniform := image.NewGray(image.Rect(0, 0, 100, 100))
+	for i := range uniform.Pix {
+		uniform.Pix[i] = 64
+	}
+
+	tests := []struct {
+		name      string
+		img       image.Image
+		threshold uint8
+		dither    bool
+		wantBlack int
+	}{
+		{"test threshold 128", gradient, 128, false, 128},
+		{"test threshold 200", gradient, 200, false, 200},
+		{"test threshold 0", gradient, 0, false, 0},
+		{"test uniform gray without dithering", uniform, 128, false, 10000},
+		{"test uniform gray with dithering", uniform, 128, true, 7500},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got := ToBitonal(tests[i].img, tests[i].threshold, tests[i].dither)
+			if got.Bounds() != tests[i].img.Bounds() {
+				t.Fatalf("expected bounds %v but got %v", tests[i].img.Bounds(), got.Bounds())
+			}
+
+			black := 0
+			for _, index := range got.Pix {
+				if index == 0 {
+					black++
+				}
+			}
+
+			// Dithering should give about the same amount of black as the
+			// gray value, allow a margin of 2%.
+			margin := len(got.Pix) / 50
+			if tests[i].dither && (black < tests[i].wantBlack-margin || black > tests[i].wantBlack+margin) {
+				t.Errorf("expected about %d black pixels but got %d", tests[i].wantBlack, black)
+			} else if !tests[i].dither && black != tests[i].wantBlack {
+				t.Errorf("expected %d black pixels but got %d", tests[i].wantBlack, black)
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+package pdf
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/responses"
+)
+
+// DiffReport is the result of comparing two documents.
+type DiffReport struct {
+	A          string
+	B          string
+	Equal      bool
+	PageCountA int
+	PageCountB int
+	Metadata   []DiffMetadata // The metadata tags that differ.
+	Pages      []DiffPage
+}
+
+// DiffMetadata is a metadata tag that has a different value in the documents.
+type DiffMetadata struct {
+	Tag string
+	A   string
+	B   string
+}
+
+// DiffPage is the result of comparing a page of the documents.
+type DiffPage struct {
+	Page            int    // The page number (1-index based).
+	Equal           bool   // Whether the page looks the same and has the same text.
+	MissingIn       string `json:",omitempty"` // a or b when the page only exists in one of the documents.
+	DifferentPixels int
+	Score           float64 // The fraction of the pixels that differ, between 0 and 1.
+	TextDiff        string  `json:",omitempty"` // The unified diff of the text.
+	Image           string  `json:",omitempty"` // The path of the highlighted diff image.
+}
+
+// Finish sets whether the pages and the documents are equal. Pages with a
+// score up to maxScore are considered to look the same.
+func (r *DiffReport) Finish(maxScore float64) {
+	r.Equal = r.PageCountA == r.PageCountB && len(r.Metadata) == 0
+	for i := range r.Pages {
+		page := &r.Pages[i]
+		page.Equal = page.MissingIn == "" && page.Score <= maxScore && page.TextDiff == ""
+		if !page.Equal {
+			r.Equal = false
+		}
+	}
+}
+
+// DifferentPages returns the pages that are not equal.
+func (r *DiffReport) DifferentPages() []DiffPage {
+	differentPages := []DiffPage{}
+	for _, page := range r.Pages {
+		if !page.Equal {
+			differentPages = append(differentPages, page)
+		}
+	}
+	return differentPages
+}
+
+// DiffOptions contains the options to compare the pages of two documents.
+type DiffOptions struct {
+	DPI         int    // The DPI to render the pages in.
+	Tolerance   int    // The difference (0-255) a color channel may have before a pixel differs.
+	TextContext int    // The amount of lines around a change in the text diff.
+	NameA       string // The name of the first document in the text diff.
+	NameB       string // The name of the second document in the text diff.
+}
+
+// ComparePage renders the page of both documents and compares the images and
+// the text. It returns the result of the page and the pixel diff, of which
+// the image highlights the different pixels.
+func ComparePage(instance pdfium.Pdfium, documentA references.FPDF_DOCUMENT, documentB references.FPDF_DOCUMENT, index int, options DiffOptions) (DiffPage, *PixelDiff, error) {
+	result := DiffPage{Page: index + 1}
+
+	pageA := requests.Page{ByIndex: &requests.PageByIndex{Document: documentA, Index: index}}
+	pageB := requests.Page{ByIndex: &requests.PageByIndex{Document: documentB, Index: index}}
+
+	renderedA, err := instance.RenderPageInDPI(&requests.RenderPageInDPI{
+		Page: pageA,
+		DPI:  options.DPI,
+	})
+	if err != nil {
+		return result, nil, fmt.Errorf("could not render page of %s: %w", options.NameA, err)
+	}
+	defer renderedA.Cleanup()
+
+	renderedB, err := instance.RenderPageInDPI(&requests.RenderPageInDPI{
+		Page: pageB,
+		DPI:  options.DPI,
+	})
+	if err != nil {
+		return result, nil, fmt.Errorf("could not render page of %s: %w", options.NameB, err)
+	}
+	defer renderedB.Cleanup()
+
+	pixelDiff := DiffImages(renderedA.Result.Image, renderedB.Result.Image, options.Tolerance)
+	result.DifferentPixels = pixelDiff.DifferentPixels
+	result.Score = pixelDiff.Score
+
+	textA, err := instance.GetPageText(&requests.GetPageText{
+		Page: pageA,
+	})
+	if err != nil {
+		return result, nil, fmt.Errorf("could not get text of %s: %w", options.NameA, err)
+	}
+
+	textB, err := instance.GetPageText(&requests.GetPageText{
+		Page: pageB,
+	})
+	if err != nil {
+		return result, nil, fmt.Errorf("could not get text of %s: %w", options.NameB, err)
+	}
+
+	result.TextDiff = UnifiedDiff(
+		fmt.Sprintf("%s page %d", options.NameA, index+1),
+		fmt.Sprintf("%s page %d", options.NameB, index+1),
+		TextLines(textA.Text),
+		TextLines(textB.Text),
+		options.TextContext,
+	)
+
+	return result, &pixelDiff, nil
+}
+
+// DiffMetadataTags returns the tags that have a different value in a and b,
+// in the order of a followed by the tags that are only in b. Tags in ignore
+// are not compared.
+func DiffMetadataTags(a []responses.GetMetaDataTag, b []responses.GetMetaDataTag, ignore []string) []DiffMetadata {
+	ignored := map[string]bool{}
+	for _, tag := range ignore {
+		ignored[strings.ToLower(tag)] = true
+	}
+
+	values := [2]map[string]string{{}, {}}
+	for i, tags := range [][]responses.GetMetaDataTag{a, b} {
+		for _, tag := range tags {
+			values[i][tag.Tag] = tag.Value
+		}
+	}
+
+	result := []DiffMetadata{}
+	seen := map[string]bool{}
+	for _, tags := range [][]responses.GetMetaDataTag{a, b} {
+		for _, tag := range tags {
+			if seen[tag.Tag] || ignored[strings.ToLower(tag.Tag)] {
+				continue
+			}
+			seen[tag.Tag] = true
+
+			if values[0][tag.Tag] != values[1][tag.Tag] {
+				result = append(result, DiffMetadata{Tag: tag.Tag, A: values[0][tag.Tag], B: values[1][tag.Tag]})
+			}
+		}
+	}
+
+	return result
+}
+
+// PixelDiff is the result of comparing two images.
+type PixelDiff struct {
+	DifferentPixels int
+	Score           float64      // The fraction of the pixels that differ, between 0 and 1.
+	Image           *image.NRGBA // A faded gray version of b with the different pixels in red.
+}
+
+// highlightColor is the color of the different pixels in the diff image.
+var highlightColor = color.NRGBA{R: 255, A: 255}
+
+// DiffImages compares the images pixel by pixel. A pixel differs when one of
+// its channels differs more than tolerance (0-255). When the images have a
+// different size they are compared in the size of the largest, the pixels
+// that only exist in one of the images differ.
+func DiffImages(a image.Image, b image.Image, tolerance int) PixelDiff {
+	aBounds := a.Bounds()
+	bBounds := b.Bounds()
+	width := max(aBounds.Dx(), bBounds.Dx())
+	height := max(aBounds.Dy(), bBounds.Dy())
+
+	result := PixelDiff{
+		Image: image.NewNRGBA(image.Rect(0, 0, width, height)),
+	}
+
+	// The tolerance is compared to the 16-bit channels of color.RGBA().
+	tolerance16 := uint32(tolerance) * 0x101
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			aPoint := image.Point{X: aBounds.Min.X + x, Y: aBounds.Min.Y + y}
+			bPoint := image.Point{X: bBounds.Min.X + x, Y: bBounds.Min.Y + y}
+			inA := aPoint.In(aBounds)
+			inB := bPoint.In(bBounds)
+
+			var aColor, bColor color.Color
+			if inA {
+				aColor = a.At(aPoint.X, aPoint.Y)
+			}
+			if inB {
+				bColor = b.At(bPoint.X, bPoint.Y)
+			}
+
+			if !inA || !inB || !colorsWithinTolerance(aColor, bColor, tolerance16) {
+				result.DifferentPixels++
+				result.Image.SetNRGBA(x, y, highlightColor)
+				continue
+			}
+
+			result.Image.SetNRGBA(x, y, fadedGray(bColor))
+		}
+	}
+
+	if width > 0 && height > 0 {
+		result.Score = float64(result.DifferentPixels) / float64(width*height)
+	}
+
+	return result
+}
+
+func colorsWithinTolerance(a color.Color, b color.Color, tolerance uint32) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	return channelDifference(ar, br) <= tolerance &&
+		channelDifference(ag, bg) <= tolerance &&
+		channelDifference(ab, bb) <= tolerance &&
+		channelDifference(aa, ba) <= tolerance
+}
+
+func channelDifference(a uint32, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// fadedGray returns a light gray version of the color, so that the
+// highlighted pixels stand out.
+func fadedGray(c color.Color) color.NRGBA {
+	gray := color.GrayModel.Convert(c).(color.Gray)
+	faded := 255 - (255-gray.Y)/3
+	return color.NRGBA{R: faded, G: faded, B: faded, A: 255}
+}
+
+// TextLines splits text into lines, pdfium separates lines with \r\n.
+func TextLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffOperation is a line of an edit script, kind is ' ' for a line in both,
+// '-' for a line only in a and '+' for a line only in b.
+type diffOperation struct {
+	kind rune
+	line string
+}
+
+// UnifiedDiff returns the unified diff of the lines with the given amount of
+// context lines, or an empty string when the lines are equal.
+func UnifiedDiff(nameA string, nameB string, a []string, b []string, context int) string {
+	operations := diffLines(a, b)
+
+	// Find the ranges of operations that are changes.
+	type change struct{ start, end int }
+	changes := []change{}
+	for i := 0; i < len(operations); i++ {
+		if operations[i].kind == ' ' {
+			continue
+		}
+		start := i
+		for i < len(operations) && operations[i].kind != ' ' {
+			i++
+		}
+		changes = append(changes, change{start, i})
+	}
+
+	if len(changes) == 0 {
+		return ""
+	}
+
+	// Group the changes into hunks, changes that are close together share
+	// their context.
+	hunks := []change{}
+	for _, c := range changes {
+		start := max(c.start-context, 0)
+		end := min(c.end+context, len(operations))
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1].end {
+			hunks[len(hunks)-1].end = end
+			continue
+		}
+		hunks = append(hunks, change{start, end})
+	}
+
+	// The line numbers in a and b at the start of every operation.
+	aLines := make([]int, len(operations)+1)
+	bLines := make([]int, len(operations)+1)
+	for i, operation := range operations {
+		aLines[i+1] = aLines[i]
+		bLines[i+1] = bLines[i]
+		if operation.kind != '+' {
+			aLines[i+1]++
+		}
+		if operation.kind != '-' {
+			bLines[i+1]++
+		}
+	}
+
+	result := strings.Builder{}
+	fmt.Fprintf(&result, "--- %s\n+++ %s\n", nameA, nameB)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&result, "@@ -%s +%s @@\n",
+			unifiedRange(aLines[hunk.start], aLines[hunk.end]-aLines[hunk.start]),
+			unifiedRange(bLines[hunk.start], bLines[hunk.end]-bLines[hunk.start]))
+		for _, operation := range operations[hunk.start:hunk.end] {
+			fmt.Fprintf(&result, "%c%s\n", operation.kind, operation.line)
+		}
+	}
+
+	return result.String()
+}
+
+// unifiedRange formats the range of a hunk like GNU diff, start is the
+// 0-index based first line.
+func unifiedRange(start int, length int) string {
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+// diffLines returns the edit script from a to b, based on the longest common
+// subsequence of the lines.
+func diffLines(a []string, b []string) []diffOperation {
+	// Skip the common prefix and suffix, which is most of the text when
+	// documents are almost the same.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	operations := []diffOperation{}
+	for _, line := range a[:prefix] {
+		operations = append(operations, diffOperation{' ', line})
+	}
+
+	middleA := a[prefix : len(a)-suffix]
+	middleB := b[prefix : len(b)-suffix]
+
+	// lengths[i][j] is the length of the longest common subsequence of
+	// middleA[i:] and middleB[j:].
+	lengths := make([][]int, len(middleA)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(middleB)+1)
+	}
+	for i := len(middleA) - 1; i >= 0; i-- {
+		for j := len(middleB) - 1; j >= 0; j-- {
+			if middleA[i] == middleB[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(middleA) || j < len(middleB) {
+		switch {
+		case i < len(middleA) && j < len(middleB) && middleA[i] == middleB[j]:
+			operations = append(operations, diffOperation{' ', middleA[i]})
+			i++
+			j++
+		case j == len(middleB) || (i < len(middleA) && lengths[i+1][j] >= lengths[i][j+1]):
+			operations = append(operations, diffOperation{'-', middleA[i]})
+			i++
+		default:
+			operations = append(operations, diffOperation{'+', middleB[j]})
+			j++
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		operations = append(operations, diffOperation{' ', line})
+	}
+
+	return operations
+}
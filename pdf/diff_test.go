@@ -0,0 +1,236 @@
+package pdf
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/responses"
+)
+
+func TestDiffImages(t *testing.T) {
+	white := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for i := range white.Pix {
+		white.Pix[i] = 255
+	}
+
+	changed := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	copy(changed.Pix, white.Pix)
+	changed.SetRGBA(1, 1, color.RGBA{A: 255})
+	changed.SetRGBA(2, 0, color.RGBA{R: 250, G: 250, B: 250, A: 255})
+
+	wider := image.NewRGBA(image.Rect(0, 0, 5, 2))
+	for i := range wider.Pix {
+		wider.Pix[i] = 255
+	}
+
+	tests := []struct {
+		name                string
+		a                   image.Image
+		b                   image.Image
+		tolerance           int
+		wantDifferentPixels int
+		wantScore           float64
+		wantSize            image.Point
+	}{
+		{"test equal", white, white, 0, 0, 0, image.Point{X: 4, Y: 2}},
+		{"test changed", white, changed, 0, 2, 0.25, image.Point{X: 4, Y: 2}},
+		{"test changed with tolerance", white, changed, 5, 1, 0.125, image.Point{X: 4, Y: 2}},
+		{"test different size", white, wider, 0, 2, 0.2, image.Point{X: 5, Y: 2}},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			result := DiffImages(tests[i].a, tests[i].b, tests[i].tolerance)
+			if result.DifferentPixels != tests[i].wantDifferentPixels {
+				t.Errorf("expected %d different pixels but got %d", tests[i].wantDifferentPixels, result.DifferentPixels)
+			}
+			if result.Score != tests[i].wantScore {
+				t.Errorf("expected score %f but got %f", tests[i].wantScore, result.Score)
+			}
+			if size := result.Image.Bounds().Size(); size != tests[i].wantSize {
+				t.Errorf("expected image size %v but got %v", tests[i].wantSize, size)
+			}
+
+			highlighted := 0
+			for j := 0; j < len(result.Image.Pix); j += 4 {
+				if result.Image.Pix[j] == 255 && result.Image.Pix[j+1] == 0 {
+					highlighted++
+				}
+			}
+			if highlighted != tests[i].wantDifferentPixels {
+				t.Errorf("expected %d highlighted pixels but got %d", tests[i].wantDifferentPixels, highlighted)
+			}
+		})
+	}
+}
+
+func TestTextLines(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"test empty", "", []string{}},
+		{"test one line", "Hello", []string{"Hello"}},
+		{"test pdfium line endings", "Hello\r\nWorld", []string{"Hello", "World"}},
+		{"test newlines", "Hello\nWorld\n", []string{"Hello", "World", ""}},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got := TextLines(tests[i].text)
+			if len(got) != len(tests[i].want) {
+				t.Fatalf("expected %q but got %q", tests[i].want, got)
+			}
+			for j := range got {
+				if got[j] != tests[i].want[j] {
+					t.Errorf("expected %q but got %q", tests[i].want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       []string
+		b       []string
+		context int
+		want    string
+	}{
+		{"test equal", []string{"a", "b"}, []string{"a", "b"}, 3, ""},
+		{"test both empty", []string{}, []string{}, 3, ""},
+		{
+			"test changed line",
+			[]string{"Invoice 1", "Total: 10.00", "Thanks"},
+			[]string{"Invoice 1", "Total: 12.00", "Thanks"},
+			3,
+			"--- a\n+++ b\n@@ -1,3 +1,3 @@\n Invoice 1\n-Total: 10.00\n+Total: 12.00\n Thanks\n",
+		},
+		{
+			"test added lines to empty",
+			[]string{},
+			[]string{"new"},
+			3,
+			"--- a\n+++ b\n@@ -0,0 +1 @@\n+new\n",
+		},
+		{
+			"test removed line without context",
+			[]string{"1", "2", "3"},
+			[]string{"1", "3"},
+			0,
+			"--- a\n+++ b\n@@ -2 +1,0 @@\n-2\n",
+		},
+		{
+			"test separate hunks",
+			[]string{"1", "2", "3", "4", "5", "6", "7", "8"},
+			[]string{"1", "x", "3", "4", "5", "6", "7", "y"},
+			1,
+			"--- a\n+++ b\n@@ -1,3 +1,3 @@\n 1\n-2\n+x\n 3\n@@ -7,2 +7,2 @@\n 7\n-8\n+y\n",
+		},
+		{
+			"test merged hunks",
+			[]string{"1", "2", "3", "4"},
+			[]string{"x", "2", "3", "y"},
+			1,
+			"--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n-4\n+y\n",
+		},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			if got := UnifiedDiff("a", "b", tests[i].a, tests[i].b, tests[i].context); got != tests[i].want {
+				t.Errorf("expected:\n%s\nbut got:\n%s", tests[i].want, got)
+			}
+		})
+	}
+}
+
+func TestDiffMetadataTags(t *testing.T) {
+	a := []responses.GetMetaDataTag{{Tag: "Title", Value: "Invoice"}, {Tag: "Author", Value: "Klippa"}, {Tag: "ModDate", Value: "D:20240101"}}
+	b := []responses.GetMetaDataTag{{Tag: "Title", Value: "Invoice 2"}, {Tag: "Author", Value: "Klippa"}, {Tag: "ModDate", Value: "D:20240102"}, {Tag: "Subject", Value: "Invoices"}}
+
+	tests := []struct {
+		name   string
+		ignore []string
+		want   []DiffMetadata
+	}{
+		{
+			"test all tags",
+			nil,
+			[]DiffMetadata{{Tag: "Title", A: "Invoice", B: "Invoice 2"}, {Tag: "ModDate", A: "D:20240101", B: "D:20240102"}, {Tag: "Subject", B: "Invoices"}},
+		},
+		{
+			"test ignored tags",
+			[]string{"moddate", "Subject"},
+			[]DiffMetadata{{Tag: "Title", A: "Invoice", B: "Invoice 2"}},
+		},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got := DiffMetadataTags(a, b, tests[i].ignore)
+			if len(got) != len(tests[i].want) {
+				t.Fatalf("expected %v but got %v", tests[i].want, got)
+			}
+			for j := range got {
+				if got[j] != tests[i].want[j] {
+					t.Errorf("expected %v but got %v", tests[i].want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestComparePage(t *testing.T) {
+	instance := loadTestPdfium(t)
+	document := openTestPDF(t, instance, generateTestPDF(t, instance, "Hello"))
+	sameDocument := openTestPDF(t, instance, generateTestPDF(t, instance, "Hello"))
+	otherDocument := openTestPDF(t, instance, generateTestPDF(t, instance, "World"))
+
+	tests := []struct {
+		name          string
+		documentB     references.FPDF_DOCUMENT
+		tolerance     int
+		maxScore      float64
+		wantDifferent bool
+		wantTextDiff  bool
+		wantEqual     bool
+	}{
+		{"test same page", sameDocument, 0, 0, false, false, true},
+		{"test different page", otherDocument, 0, 0, true, true, false},
+		{"test different page within max score", otherDocument, 0, 1, true, true, false},
+		{"test different page with full tolerance", otherDocument, 255, 0, false, true, false},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			page, pixelDiff, err := ComparePage(instance, document, tests[i].documentB, 0, DiffOptions{DPI: 72, Tolerance: tests[i].tolerance, TextContext: 3, NameA: "a.pdf", NameB: "b.pdf"})
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if (page.DifferentPixels > 0) != tests[i].wantDifferent || pixelDiff.DifferentPixels != page.DifferentPixels {
+				t.Errorf("expected different pixels %t but got %d", tests[i].wantDifferent, page.DifferentPixels)
+			}
+			if (page.TextDiff != "") != tests[i].wantTextDiff {
+				t.Errorf("expected text diff %t but got %q", tests[i].wantTextDiff, page.TextDiff)
+			}
+
+			// The command exits with the diff found exit code when the
+			// report is not equal.
+			report := DiffReport{PageCountA: 1, PageCountB: 1, Pages: []DiffPage{page}}
+			report.Finish(tests[i].maxScore)
+			if report.Equal != tests[i].wantEqual || report.Pages[0].Equal != tests[i].wantEqual {
+				t.Errorf("expected equal %t but got %+v", tests[i].wantEqual, report)
+			}
+		})
+	}
+
+	if _, _, err := ComparePage(instance, document, sameDocument, 1, DiffOptions{DPI: 72}); err == nil {
+		t.Errorf("expected an error for a page that doesn't exist")
+	}
+}
@@ -0,0 +1,227 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// documentStreamReadSize is the amount of bytes that is read from the stream
+// at once.
+const documentStreamReadSize = 64 * 1024
+
+// DocumentStream reads documents from a stream one at a time, like multiple
+// PDF files on stdin.
+type DocumentStream interface {
+	// Next reads the next document from the stream, in the order of the
+	// stream. Returns io.EOF when all documents have been read.
+	Next() (*StreamDocument, error)
+}
+
+// ArchiveDocumentStream is a DocumentStream that reads the documents from an
+// archive, which is read as a whole first.
+type ArchiveDocumentStream interface {
+	DocumentStream
+
+	// Archive returns the archive that the documents are read from, nil
+	// before the first document is read. The archive is removed when all
+	// documents have been read.
+	Archive() *StreamDocument
+}
+
+// delimitedDocumentStream is a stream of documents that are separated by the
+// delimiter on its own line, so the separator is a newline, the delimiter and
+// a newline.
+type delimitedDocumentStream struct {
+	reader    io.Reader
+	separator []byte
+	spoolSize int64
+	pending   []byte // Data that was read but doesn't belong to a document yet.
+	eof       bool   // Whether the end of the stream was reached.
+	done      bool   // Whether the last document was returned.
+}
+
+// NewDelimitedDocumentStream returns a stream of the documents in reader that
+// are separated by the delimiter. Documents that are larger than spoolSize are
+// written into a temporary file instead of kept in memory.
+func NewDelimitedDocumentStream(reader io.Reader, delimiter string, spoolSize int64) DocumentStream {
+	return &delimitedDocumentStream{
+		reader:    reader,
+		separator: []byte("\n" + delimiter + "\n"),
+		spoolSize: spoolSize,
+	}
+}
+
+// Next reads the next document. Like splitting the whole stream on the
+// separator, a stream always has at least one document, which can be empty.
+func (s *delimitedDocumentStream) Next() (*StreamDocument, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+
+	document := &StreamDocument{spoolSize: s.spoolSize}
+	readBuffer := make([]byte, documentStreamReadSize)
+	for {
+		if i := bytes.Index(s.pending, s.separator); i > -1 {
+			if err := document.write(s.pending[:i]); err != nil {
+				document.Remove()
+				return nil, err
+			}
+			s.pending = append([]byte{}, s.pending[i+len(s.separator):]...)
+			return document, document.finish()
+		}
+
+		if s.eof {
+			if err := document.write(s.pending); err != nil {
+				document.Remove()
+				return nil, err
+			}
+			s.pending = nil
+			s.done = true
+			return document, document.finish()
+		}
+
+		// Keep the end that could be the start of a separator, the rest
+		// belongs to the document.
+		if keep := len(s.separator) - 1; len(s.pending) > keep {
+			if err := document.write(s.pending[:len(s.pending)-keep]); err != nil {
+				document.Remove()
+				return nil, err
+			}
+			s.pending = append(s.pending[:0], s.pending[len(s.pending)-keep:]...)
+		}
+
+		n, err := s.reader.Read(readBuffer)
+		s.pending = append(s.pending, readBuffer[:n]...)
+		if errors.Is(err, io.EOF) {
+			s.eof = true
+		} else if err != nil {
+			document.Remove()
+			return nil, err
+		}
+	}
+}
+
+// readStreamDocument reads a whole document from reader.
+func readStreamDocument(reader io.Reader, name string, spoolSize int64) (*StreamDocument, error) {
+	document := &StreamDocument{name: name, spoolSize: spoolSize}
+	readBuffer := make([]byte, documentStreamReadSize)
+	for {
+		n, err := reader.Read(readBuffer)
+		if writeErr := document.write(readBuffer[:n]); writeErr != nil {
+			document.Remove()
+			return nil, writeErr
+		}
+
+		if errors.Is(err, io.EOF) {
+			return document, document.finish()
+		}
+		if err != nil {
+			document.Remove()
+			return nil, err
+		}
+	}
+}
+
+// StreamDocument is a document from a stream, kept in memory or in a
+// temporary file when it's large.
+type StreamDocument struct {
+	name      string // The filename of the document, when the stream has filenames.
+	spoolSize int64
+	data      []byte
+	file      *os.File // The temporary file while the document is written.
+	path      string   // The path of the temporary file.
+	size      int64
+}
+
+func (d *StreamDocument) write(data []byte) error {
+	d.size += int64(len(data))
+	if d.file == nil && d.size <= d.spoolSize {
+		d.data = append(d.data, data...)
+		return nil
+	}
+
+	if d.file == nil {
+		file, err := os.CreateTemp("", "pdfium-cli-stdin-")
+		if err != nil {
+			return err
+		}
+		d.file = file
+		d.path = file.Name()
+
+		if _, err := d.file.Write(d.data); err != nil {
+			return err
+		}
+		d.data = nil
+	}
+
+	_, err := d.file.Write(data)
+	return err
+}
+
+func (d *StreamDocument) finish() error {
+	if d.file == nil {
+		return nil
+	}
+
+	err := d.file.Close()
+	d.file = nil
+	if err != nil {
+		d.Remove()
+		return err
+	}
+
+	return nil
+}
+
+// Name returns the filename of the document, empty when the format of the
+// stream has no filenames.
+func (d *StreamDocument) Name() string {
+	return d.name
+}
+
+// Size returns the size of the document in bytes.
+func (d *StreamDocument) Size() int64 {
+	return d.size
+}
+
+// IsSpooled returns whether the document is kept in a temporary file.
+func (d *StreamDocument) IsSpooled() bool {
+	return d.path != ""
+}
+
+// Open returns a reader of the document. Every reader has its own position,
+// so that the document can be opened more than once at the same time.
+func (d *StreamDocument) Open() (io.ReadSeekCloser, error) {
+	if d.path == "" {
+		return nopReadSeekCloser{bytes.NewReader(d.data)}, nil
+	}
+
+	return os.Open(d.path)
+}
+
+// Remove removes the temporary file of the document, if any.
+func (d *StreamDocument) Remove() error {
+	if d.file != nil {
+		d.file.Close()
+		d.file = nil
+	}
+
+	if d.path == "" {
+		return nil
+	}
+
+	err := os.Remove(d.path)
+	d.path = ""
+	return err
+}
+
+// nopReadSeekCloser is a document in memory, it's also an io.ReaderAt.
+type nopReadSeekCloser struct {
+	*bytes.Reader
+}
+
+func (nopReadSeekCloser) Close() error {
+	return nil
+}
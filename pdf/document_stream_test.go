@@ -0,0 +1,85 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDocumentStream(t *testing.T) {
+	delimiter := "--pdfium-cli-file-boundary"
+	large := strings.Repeat("%PDF-large", documentStreamReadSize/5)
+
+	tests := []struct {
+		name      string
+		input     string
+		spoolSize int64
+		want      []string
+	}{
+		{"test empty", "", 1024, []string{""}},
+		{"test one document", "%PDF-1", 1024, []string{"%PDF-1"}},
+		{"test order", "%PDF-1\n" + delimiter + "\n%PDF-2\n" + delimiter + "\n%PDF-3", 1024, []string{"%PDF-1", "%PDF-2", "%PDF-3"}},
+		{"test trailing delimiter", "%PDF-1\n" + delimiter + "\n", 1024, []string{"%PDF-1", ""}},
+		{"test delimiter without newlines", "%PDF-1" + delimiter + "%PDF-2\n" + delimiter, 1024, []string{"%PDF-1" + delimiter + "%PDF-2\n" + delimiter}},
+		{"test partial delimiter", "%PDF-1\n--pdfium\n%PDF-2", 1024, []string{"%PDF-1\n--pdfium\n%PDF-2"}},
+		{"test spooled", "%PDF-1\n" + delimiter + "\n" + large + "\n" + delimiter + "\n%PDF-3", 1024, []string{"%PDF-1", large, "%PDF-3"}},
+		{"test all spooled", large + "\n" + delimiter + "\n" + large, 0, []string{large, large}},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			// Also compare with splitting the whole input at once.
+			split := bytes.Split([]byte(tests[i].input), []byte("\n"+delimiter+"\n"))
+			if len(split) != len(tests[i].want) {
+				t.Fatalf("expected %d documents from split but got %d", len(tests[i].want), len(split))
+			}
+
+			for _, reader := range []io.Reader{strings.NewReader(tests[i].input), iotest.OneByteReader(strings.NewReader(tests[i].input))} {
+				stream := NewDelimitedDocumentStream(reader, delimiter, tests[i].spoolSize)
+				for j, want := range tests[i].want {
+					document, err := stream.Next()
+					if err != nil {
+						t.Fatalf("expected no error but got error %s", err.Error())
+					}
+
+					if document.Size() != int64(len(want)) {
+						t.Errorf("expected size %d for document %d but got %d", len(want), j+1, document.Size())
+					}
+
+					if wantSpooled := int64(len(want)) > tests[i].spoolSize; document.IsSpooled() != wantSpooled {
+						t.Errorf("expected spooled %t for document %d but got %t", wantSpooled, j+1, document.IsSpooled())
+					}
+
+					// Open twice to check that the readers are independent.
+					for k := 0; k < 2; k++ {
+						documentReader, err := document.Open()
+						if err != nil {
+							t.Fatalf("expected no error but got error %s", err.Error())
+						}
+
+						got, err := io.ReadAll(documentReader)
+						documentReader.Close()
+						if err != nil {
+							t.Fatalf("expected no error but got error %s", err.Error())
+						}
+
+						if string(got) != want {
+							t.Errorf("expected document %d to be %.20q but got %.20q", j+1, want, string(got))
+						}
+					}
+
+					if err := document.Remove(); err != nil {
+						t.Errorf("expected no error but got error %s", err.Error())
+					}
+				}
+
+				if _, err := stream.Next(); !errors.Is(err, io.EOF) {
+					t.Errorf("expected EOF but got %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+
+	_ "golang.org/x/image/tiff"
+)
+
+// ImagePageSize is the size of the pages that are created from images.
+type ImagePageSize string
+
+const (
+	ImagePageSizeFit    ImagePageSize = "fit"    // The size of the image with the margins.
+	ImagePageSizeA4     ImagePageSize = "a4"     // 210x297 mm.
+	ImagePageSizeLetter ImagePageSize = "letter" // 8.5x11 inch.
+)
+
+// defaultImageDPI is the resolution of images that don't have a resolution.
+const defaultImageDPI = 72
+
+// ImageInfo is the information of an image file that is needed to place it
+// on a page.
+type ImageInfo struct {
+	Format      string  // The image format: jpeg, png or tiff.
+	Width       int     // The width in pixels as it's stored.
+	Height      int     // The height in pixels as it's stored.
+	DPIX        float64 // The horizontal resolution in the file, 0 when unknown.
+	DPIY        float64 // The vertical resolution in the file, 0 when unknown.
+	Orientation int     // The EXIF orientation (1-8), 1 when unknown.
+}
+
+// ReadImageInfo reads the size, resolution and orientation of a JPEG, PNG or
+// TIFF image without decoding the pixels. The resolution is read from the
+// JFIF header or EXIF of JPEG, the pHYs chunk of PNG and the tags of TIFF.
+func ReadImageInfo(data []byte) (ImageInfo, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return ImageInfo{}, err
+	}
+
+	info := ImageInfo{
+		Format:      format,
+		Width:       config.Width,
+		Height:      config.Height,
+		Orientation: 1,
+	}
+
+	switch format {
+	case "jpeg":
+		readJPEGInfo(data, &info)
+	case "png":
+		readPNGInfo(data, &info)
+	case "tiff":
+		readTIFFTags(data, &info)
+	default:
+		return ImageInfo{}, fmt.Errorf("unsupported image format %s", format)
+	}
+
+	if info.Orientation < 1 || info.Orientation > 8 {
+		info.Orientation = 1
+	}
+
+	return info, nil
+}
+
+// SizeInPoints returns the size of the image as it's displayed, with the
+// orientation applied, in points. The DPI overrides the resolution of the
+// file when it's larger than 0, images without a resolution have 72 DPI.
+func (i ImageInfo) SizeInPoints(dpi float64) (float64, float64) {
+	dpiX, dpiY := i.DPIX, i.DPIY
+	if dpi > 0 {
+		dpiX, dpiY = dpi, dpi
+	}
+	if dpiX <= 0 || dpiY <= 0 {
+		dpiX, dpiY = defaultImageDPI, defaultImageDPI
+	}
+
+	width := float64(i.Width) / dpiX * 72
+	height := float64(i.Height) / dpiY * 72
+
+	// Orientations 5 to 8 turn the image a quarter.
+	if i.Orientation >= 5 {
+		return height, width
+	}
+	return width, height
+}
+
+// readJPEGInfo reads the resolution and orientation from the segments before
+// the image data of a JPEG.
+func readJPEGInfo(data []byte, info *ImageInfo) {
+	exifInfo := ImageInfo{}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return
+		}
+
+		marker := data[i+1]
+		if marker == 0xff {
+			// Fill byte before a marker.
+			i++
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 {
+			// Start of the image data or end of the image.
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+length]
+
+		switch {
+		case marker == 0xe0 && bytes.HasPrefix(segment, []byte("JFIF\x00")) && len(segment) >= 12:
+			units := segment[7]
+			x := float64(binary.BigEndian.Uint16(segment[8:]))
+			y := float64(binary.BigEndian.Uint16(segment[10:]))
+			switch units {
+			case 1:
+				info.DPIX, info.DPIY = x, y
+			case 2:
+				info.DPIX, info.DPIY = x*2.54, y*2.54
+			}
+		case marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")):
+			readTIFFTags(segment[6:], &exifInfo)
+		}
+
+		i += 2 + length
+	}
+
+	// The JFIF header has priority over the EXIF resolution.
+	if info.DPIX == 0 || info.DPIY == 0 {
+		info.DPIX, info.DPIY = exifInfo.DPIX, exifInfo.DPIY
+	}
+	if exifInfo.Orientation != 0 {
+		info.Orientation = exifInfo.Orientation
+	}
+}
+
+// readPNGInfo reads the resolution from the pHYs chunk of a PNG.
+func readPNGInfo(data []byte, info *ImageInfo) {
+	// Skip the signature, every chunk has a length, type, data and CRC.
+	for i := 8; i+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		chunkType := string(data[i+4 : i+8])
+		if length < 0 || i+12+length > len(data) || chunkType == "IDAT" {
+			return
+		}
+
+		// The resolution is only known when the unit is meter, otherwise it's
+		// only the aspect ratio of the pixels.
+		if chunkType == "pHYs" && length >= 9 && data[i+8+8] == 1 {
+			info.DPIX = float64(binary.BigEndian.Uint32(data[i+8:])) * 0.0254
+			info.DPIY = float64(binary.BigEndian.Uint32(data[i+12:])) * 0.0254
+		}
+
+		i += 12 + length
+	}
+}
+
+// tiffTagOrientation is the TIFF tag of the orientation, which is also used
+// in EXIF.
+const tiffTagOrientation = 274
+
+// readTIFFTags reads the orientation and resolution from the first IFD of a
+// TIFF file or of the EXIF data of a JPEG, which has the same structure.
+func readTIFFTags(data []byte, info *ImageInfo) {
+	byteOrder, ifdOffset, err := readTIFFHeader(data)
+	if err != nil {
+		return
+	}
+
+	if ifdOffset+2 > len(data) {
+		return
+	}
+
+	var xResolution, yResolution float64
+	resolutionUnit := 2 // Inch.
+	entryCount := int(byteOrder.Uint16(data[ifdOffset:]))
+	for i := 0; i < entryCount; i++ {
+		entry := ifdOffset + 2 + i*12
+		if entry+12 > len(data) {
+			break
+		}
+
+		// A short value is in the first bytes of the value, a rational is
+		// stored at the offset in the value.
+		value := data[entry+8 : entry+12]
+		switch byteOrder.Uint16(data[entry:]) {
+		case tiffTagOrientation:
+			info.Orientation = int(byteOrder.Uint16(value))
+		case tiffTagResolutionUnit:
+			resolutionUnit = int(byteOrder.Uint16(value))
+		case tiffTagXResolution:
+			xResolution = readTIFFRational(data, byteOrder, int(byteOrder.Uint32(value)))
+		case tiffTagYResolution:
+			yResolution = readTIFFRational(data, byteOrder, int(byteOrder.Uint32(value)))
+		}
+	}
+
+	switch resolutionUnit {
+	case 2:
+		info.DPIX, info.DPIY = xResolution, yResolution
+	case 3:
+		info.DPIX, info.DPIY = xResolution*2.54, yResolution*2.54
+	}
+}
+
+// readTIFFHeader returns the byte order and the offset of the first IFD of a
+// TIFF file.
+func readTIFFHeader(data []byte) (binary.ByteOrder, int, error) {
+	if len(data) < 8 {
+		return nil, 0, errors.New("TIFF header is too short")
+	}
+
+	var byteOrder binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		byteOrder = binary.LittleEndian
+	case "MM":
+		byteOrder = binary.BigEndian
+	default:
+		return nil, 0, errors.New("invalid TIFF byte order")
+	}
+
+	if byteOrder.Uint16(data[2:]) != 42 {
+		return nil, 0, errors.New("invalid TIFF header")
+	}
+
+	return byteOrder, int(byteOrder.Uint32(data[4:])), nil
+}
+
+// readTIFFRational reads the rational at the offset, 0 when it's invalid.
+func readTIFFRational(data []byte, byteOrder binary.ByteOrder, offset int) float64 {
+	if offset < 0 || offset+8 > len(data) {
+		return 0
+	}
+
+	numerator := byteOrder.Uint32(data[offset:])
+	denominator := byteOrder.Uint32(data[offset+4:])
+	if denominator == 0 {
+		return 0
+	}
+
+	return float64(numerator) / float64(denominator)
+}
+
+// ImageOrientationMatrix returns the matrix that turns and flips the unit
+// square of an image as it's stored into the unit square of the image as
+// it's displayed, for the EXIF orientation.
+func ImageOrientationMatrix(orientation int) Matrix {
+	switch orientation {
+	case 2: // Mirrored horizontally.
+		return Matrix{A: -1, D: 1, E: 1}
+	case 3: // Rotated 180 degrees.
+		return Matrix{A: -1, D: -1, E: 1, F: 1}
+	case 4: // Mirrored vertically.
+		return Matrix{A: 1, D: -1, F: 1}
+	case 5: // Mirrored horizontally and rotated 270 degrees clockwise.
+		return Matrix{B: -1, C: -1, E: 1, F: 1}
+	case 6: // Rotated 90 degrees clockwise.
+		return Matrix{B: -1, C: 1, F: 1}
+	case 7: // Mirrored horizontally and rotated 90 degrees clockwise.
+		return Matrix{B: 1, C: 1}
+	case 8: // Rotated 270 degrees clockwise.
+		return Matrix{B: 1, C: -1, E: 1}
+	default:
+		return IdentityMatrix
+	}
+}
+
+// PlaceImage returns the size of the page for an image of the given size in
+// points, and the rectangle on the page in which the image is drawn. With
+// page size fit the page is the size of the image with the margins. On other
+// page sizes landscape images get a landscape page, the image is centered
+// and scaled down when it doesn't fit within the margins.
+func PlaceImage(pageSize ImagePageSize, width float64, height float64, margin float64) (float64, float64, Rect, error) {
+	var pageWidth, pageHeight float64
+	switch pageSize {
+	case ImagePageSizeFit:
+		return width + 2*margin, height + 2*margin, Rect{Left: margin, Bottom: margin, Right: margin + width, Top: margin + height}, nil
+	case ImagePageSizeA4:
+		pageWidth, pageHeight = 595.28, 841.89
+	case ImagePageSizeLetter:
+		pageWidth, pageHeight = 612, 792
+	default:
+		return 0, 0, Rect{}, fmt.Errorf("invalid page size: %s", pageSize)
+	}
+
+	if width > height {
+		pageWidth, pageHeight = pageHeight, pageWidth
+	}
+
+	areaWidth := pageWidth - 2*margin
+	areaHeight := pageHeight - 2*margin
+	if areaWidth <= 0 || areaHeight <= 0 {
+		return 0, 0, Rect{}, fmt.Errorf("margin %g is too large for page size %s", margin, pageSize)
+	}
+
+	scale := math.Min(1, math.Min(areaWidth/width, areaHeight/height))
+	width *= scale
+	height *= scale
+
+	left := (pageWidth - width) / 2
+	bottom := (pageHeight - height) / 2
+	return pageWidth, pageHeight, Rect{Left: left, Bottom: bottom, Right: left + width, Top: bottom + height}, nil
+}
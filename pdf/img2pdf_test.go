@@ -0,0 +1,201 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+// withJPEGSegments inserts the segments after the start of image marker.
+func withJPEGSegments(t *testing.T, segments ...[]byte) []byte {
+	var jpegFile bytes.Buffer
+	if err := jpeg.Encode(&jpegFile, image.NewGray(image.Rect(0, 0, 40, 20)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data := append([]byte{}, jpegFile.Bytes()[:2]...)
+	for _, segment := range segments {
+		data = append(data, segment...)
+	}
+	return append(data, jpegFile.Bytes()[2:]...)
+}
+
+func jpegSegment(marker byte, data []byte) []byte {
+	segment := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(data)+2))
+	return append(segment, data...)
+}
+
+func jfifSegment(units byte, x uint16, y uint16) []byte {
+	data := []byte("JFIF\x00\x01\x02")
+	data = append(data, units)
+	data = binary.BigEndian.AppendUint16(data, x)
+	data = binary.BigEndian.AppendUint16(data, y)
+	return jpegSegment(0xe0, append(data, 0, 0))
+}
+
+func exifOrientationSegment(orientation uint16) []byte {
+	data := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	data = binary.BigEndian.AppendUint16(data, tiffTagOrientation)
+	data = append(data, 0, 3, 0, 0, 0, 1)
+	data = binary.BigEndian.AppendUint16(data, orientation)
+	return jpegSegment(0xe1, append(data, 0, 0, 0, 0, 0, 0))
+}
+
+// withPNGResolution inserts a pHYs chunk after the IHDR chunk.
+func withPNGResolution(t *testing.T, pixelsPerMeter uint32) []byte {
+	var pngFile bytes.Buffer
+	if err := png.Encode(&pngFile, image.NewGray(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatal(err)
+	}
+
+	chunk := []byte("pHYs")
+	chunk = binary.BigEndian.AppendUint32(chunk, pixelsPerMeter)
+	chunk = binary.BigEndian.AppendUint32(chunk, pixelsPerMeter)
+	chunk = append(chunk, 1)
+
+	// The signature is 8 bytes and IHDR is 25 bytes.
+	data := append([]byte{}, pngFile.Bytes()[:33]...)
+	data = binary.BigEndian.AppendUint32(data, 9)
+	data = append(data, chunk...)
+	data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(chunk))
+	return append(data, pngFile.Bytes()[33:]...)
+}
+
+func TestReadImageInfo(t *testing.T) {
+	var tiffFile bytes.Buffer
+	if err := tiff.Encode(&tiffFile, image.NewGray(image.Rect(0, 0, 40, 20)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want ImageInfo
+	}{
+		{"test jpeg without metadata", withJPEGSegments(t), ImageInfo{Format: "jpeg", Width: 40, Height: 20, Orientation: 1}},
+		{"test jpeg with dpi", withJPEGSegments(t, jfifSegment(1, 300, 150)), ImageInfo{Format: "jpeg", Width: 40, Height: 20, DPIX: 300, DPIY: 150, Orientation: 1}},
+		{"test jpeg with dots per cm", withJPEGSegments(t, jfifSegment(2, 100, 100)), ImageInfo{Format: "jpeg", Width: 40, Height: 20, DPIX: 254, DPIY: 254, Orientation: 1}},
+		{"test jpeg with aspect ratio", withJPEGSegments(t, jfifSegment(0, 1, 1)), ImageInfo{Format: "jpeg", Width: 40, Height: 20, Orientation: 1}},
+		{"test jpeg with orientation", withJPEGSegments(t, jfifSegment(1, 72, 72), exifOrientationSegment(6)), ImageInfo{Format: "jpeg", Width: 40, Height: 20, DPIX: 72, DPIY: 72, Orientation: 6}},
+		{"test jpeg with invalid orientation", withJPEGSegments(t, exifOrientationSegment(9)), ImageInfo{Format: "jpeg", Width: 40, Height: 20, Orientation: 1}},
+		{"test png with resolution", withPNGResolution(t, 11811), ImageInfo{Format: "png", Width: 40, Height: 20, DPIX: 299.9994, DPIY: 299.9994, Orientation: 1}},
+		{"test tiff", tiffFile.Bytes(), ImageInfo{Format: "tiff", Width: 40, Height: 20, DPIX: 72, DPIY: 72, Orientation: 1}},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, err := ReadImageInfo(tests[i].data)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+			if got != tests[i].want {
+				t.Errorf("expected %+v but got %+v", tests[i].want, got)
+			}
+		})
+	}
+
+	if _, err := ReadImageInfo([]byte("not an image")); err == nil {
+		t.Errorf("expected an error for an invalid image")
+	}
+}
+
+func TestImageSizeInPoints(t *testing.T) {
+	tests := []struct {
+		name       string
+		info       ImageInfo
+		dpi        float64
+		wantWidth  float64
+		wantHeight float64
+	}{
+		{"test default dpi", ImageInfo{Width: 144, Height: 72, Orientation: 1}, 0, 144, 72},
+		{"test dpi of file", ImageInfo{Width: 600, Height: 300, DPIX: 300, DPIY: 150, Orientation: 1}, 0, 144, 144},
+		{"test dpi option", ImageInfo{Width: 600, Height: 300, DPIX: 300, DPIY: 150, Orientation: 1}, 150, 288, 144},
+		{"test rotated", ImageInfo{Width: 144, Height: 72, Orientation: 6}, 0, 72, 144},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			width, height := tests[i].info.SizeInPoints(tests[i].dpi)
+			if width != tests[i].wantWidth || height != tests[i].wantHeight {
+				t.Errorf("expected %fx%f but got %fx%f", tests[i].wantWidth, tests[i].wantHeight, width, height)
+			}
+		})
+	}
+}
+
+func TestImageOrientationMatrix(t *testing.T) {
+	// The top left corner of the stored image is at 0,1 in the unit square,
+	// the top right corner at 1,1.
+	tests := []struct {
+		orientation  int
+		wantTopLeft  [2]float64
+		wantTopRight [2]float64
+	}{
+		{1, [2]float64{0, 1}, [2]float64{1, 1}},
+		{2, [2]float64{1, 1}, [2]float64{0, 1}},
+		{3, [2]float64{1, 0}, [2]float64{0, 0}},
+		{4, [2]float64{0, 0}, [2]float64{1, 0}},
+		{5, [2]float64{0, 1}, [2]float64{0, 0}},
+		{6, [2]float64{1, 1}, [2]float64{1, 0}},
+		{7, [2]float64{1, 0}, [2]float64{1, 1}},
+		{8, [2]float64{0, 0}, [2]float64{0, 1}},
+	}
+
+	for i := range tests {
+		matrix := ImageOrientationMatrix(tests[i].orientation)
+		x, y := matrix.TransformPoint(0, 1)
+		if x != tests[i].wantTopLeft[0] || y != tests[i].wantTopLeft[1] {
+			t.Errorf("orientation %d: expected top left at %v but got %f,%f", tests[i].orientation, tests[i].wantTopLeft, x, y)
+		}
+
+		x, y = matrix.TransformPoint(1, 1)
+		if x != tests[i].wantTopRight[0] || y != tests[i].wantTopRight[1] {
+			t.Errorf("orientation %d: expected top right at %v but got %f,%f", tests[i].orientation, tests[i].wantTopRight, x, y)
+		}
+	}
+}
+
+func TestPlaceImage(t *testing.T) {
+	tests := []struct {
+		name       string
+		pageSize   ImagePageSize
+		width      float64
+		height     float64
+		margin     float64
+		wantWidth  float64
+		wantHeight float64
+		wantRect   Rect
+		wantErr    bool
+	}{
+		{"test fit", ImagePageSizeFit, 100, 200, 10, 120, 220, Rect{Left: 10, Bottom: 10, Right: 110, Top: 210}, false},
+		{"test centered", ImagePageSizeLetter, 100, 200, 0, 612, 792, Rect{Left: 256, Bottom: 296, Right: 356, Top: 496}, false},
+		{"test scaled down", ImagePageSizeLetter, 1224, 1584, 36, 612, 792, Rect{Left: 36, Bottom: 46.5882, Right: 576, Top: 745.4118}, false},
+		{"test landscape", ImagePageSizeLetter, 200, 100, 0, 792, 612, Rect{Left: 296, Bottom: 256, Right: 496, Top: 356}, false},
+		{"test margin too large", ImagePageSizeA4, 100, 200, 300, 0, 0, Rect{}, true},
+		{"test invalid page size", ImagePageSize("a5"), 100, 200, 0, 0, 0, Rect{}, true},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			width, height, rect, err := PlaceImage(tests[i].pageSize, tests[i].width, tests[i].height, tests[i].margin)
+			if (err != nil) != tests[i].wantErr {
+				t.Fatalf("expected error %t but got %v", tests[i].wantErr, err)
+			}
+			if width != tests[i].wantWidth || height != tests[i].wantHeight || !rectAlmostEqual(rect, tests[i].wantRect) {
+				t.Errorf("expected %fx%f %+v but got %fx%f %+v", tests[i].wantWidth, tests[i].wantHeight, tests[i].wantRect, width, height, rect)
+			}
+		})
+	}
+}
+
+func rectAlmostEqual(a Rect, b Rect) bool {
+	return math.Abs(a.Left-b.Left) < 0.001 && math.Abs(a.Bottom-b.Bottom) < 0.001 && math.Abs(a.Right-b.Right) < 0.001 && math.Abs(a.Top-b.Top) < 0.001
+}
@@ -0,0 +1,259 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// LayoutType is the way tiles are placed in a combined image.
+type LayoutType string
+
+const (
+	LayoutVertical   LayoutType = "vertical"   // Tiles below each other.
+	LayoutHorizontal LayoutType = "horizontal" // Tiles next to each other.
+	LayoutGrid       LayoutType = "grid"       // Tiles in rows of a fixed amount of columns.
+)
+
+// Layout contains the options to combine tiles into one image.
+type Layout struct {
+	Type       LayoutType
+	Columns    int         // The amount of columns for the grid layout, 0 for a square grid.
+	Padding    int         // The space in pixels between tiles.
+	Background color.Color // The color of the padding and the label area.
+	MaxWidth   int         // The maximum width of the combined image, 0 for no maximum.
+	MaxHeight  int         // The maximum height of the combined image, 0 for no maximum.
+	LabelSize  int         // The font size of the labels in pixels, 0 to base it on the tile size.
+}
+
+// Tile is an image to place in a combined image, with an optional label that
+// is drawn under the image.
+type Tile struct {
+	Image image.Image
+	Label string
+}
+
+// layoutColumns returns the amount of columns and rows of the layout for the
+// given amount of tiles.
+func layoutColumns(tileCount int, layout Layout) (int, int, error) {
+	columns := 1
+	switch layout.Type {
+	case LayoutVertical:
+	case LayoutHorizontal:
+		columns = tileCount
+	case LayoutGrid:
+		columns = layout.Columns
+		if columns <= 0 {
+			columns = int(math.Ceil(math.Sqrt(float64(tileCount))))
+		}
+		columns = min(columns, tileCount)
+	default:
+		return 0, 0, fmt.Errorf("invalid layout %s, use vertical, horizontal or grid", layout.Type)
+	}
+	return columns, (tileCount + columns - 1) / columns, nil
+}
+
+// labelSize returns the font size of the labels in pixels for tiles of the
+// given maximum width.
+func (l Layout) labelSize(maxTileWidth int) int {
+	if l.LabelSize > 0 {
+		return l.LabelSize
+	}
+	return max(12, maxTileWidth/25)
+}
+
+// LayoutScale returns the scale to render tiles of the given sizes in, so
+// that the combined image fits in the max width and height of the layout.
+// The padding and the labels are not scaled, so rendering the tiles at this
+// scale is sharper than scaling down the combined image. It returns 1 when
+// the tiles already fit.
+func LayoutScale(sizes []image.Point, hasLabels bool, layout Layout) (float64, error) {
+	if len(sizes) == 0 {
+		return 0, errors.New("no tiles to compose")
+	}
+
+	columns, rows, err := layoutColumns(len(sizes), layout)
+	if err != nil {
+		return 0, err
+	}
+
+	maxTileWidth := 0
+	columnWidths := make([]int, columns)
+	rowHeights := make([]int, rows)
+	for i, size := range sizes {
+		column, row := i%columns, i/columns
+		maxTileWidth = max(maxTileWidth, size.X)
+		columnWidths[column] = max(columnWidths[column], size.X)
+		rowHeights[row] = max(rowHeights[row], size.Y)
+	}
+
+	tilesWidth, tilesHeight := 0, 0
+	for _, columnWidth := range columnWidths {
+		tilesWidth += columnWidth
+	}
+	for _, rowHeight := range rowHeights {
+		tilesHeight += rowHeight
+	}
+
+	scale := 1.0
+	if layout.MaxWidth > 0 {
+		scale = math.Min(scale, float64(layout.MaxWidth-(columns-1)*layout.Padding)/float64(tilesWidth))
+	}
+	if layout.MaxHeight > 0 {
+		// The label size depends on the tile width, a smaller scale can only
+		// make the labels smaller, so the label height at this scale is safe.
+		labelHeight := 0
+		if hasLabels {
+			labelHeight = layout.labelSize(int(float64(maxTileWidth)*scale)) * 3 / 2
+		}
+		scale = math.Min(scale, float64(layout.MaxHeight-(rows-1)*layout.Padding-rows*labelHeight)/float64(tilesHeight))
+	}
+
+	if scale <= 0 {
+		return 0, errors.New("the max width or height of the layout is too small for the padding and labels")
+	}
+
+	return scale, nil
+}
+
+// ComposeTiles places the tiles into one image with the given layout. Every
+// column is as wide as its widest tile and every row is as high as its
+// highest tile, tiles are placed in the top left of their cell. When the
+// image is larger than the max width or height, it is scaled down to fit,
+// render the tiles at the scale of LayoutScale to prevent this. It returns
+// the image and the scale that was applied.
+func ComposeTiles(tiles []Tile, layout Layout) (*image.NRGBA, float64, error) {
+	if len(tiles) == 0 {
+		return nil, 0, errors.New("no tiles to compose")
+	}
+
+	columns, rows, err := layoutColumns(len(tiles), layout)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hasLabels := false
+	maxTileWidth := 0
+	for _, tile := range tiles {
+		maxTileWidth = max(maxTileWidth, tile.Image.Bounds().Dx())
+		if tile.Label != "" {
+			hasLabels = true
+		}
+	}
+
+	var labelFace font.Face
+	labelHeight := 0
+	if hasLabels {
+		labelSize := layout.labelSize(maxTileWidth)
+
+		parsedFont, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		labelFace, err = opentype.NewFace(parsedFont, &opentype.FaceOptions{
+			Size:    float64(labelSize),
+			DPI:     72,
+			Hinting: font.HintingFull,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		defer labelFace.Close()
+
+		labelHeight = labelSize * 3 / 2
+	}
+
+	columnWidths := make([]int, columns)
+	rowHeights := make([]int, rows)
+	for i, tile := range tiles {
+		column, row := i%columns, i/columns
+		columnWidths[column] = max(columnWidths[column], tile.Image.Bounds().Dx())
+		rowHeights[row] = max(rowHeights[row], tile.Image.Bounds().Dy()+labelHeight)
+	}
+
+	columnOffsets := make([]int, columns)
+	width := 0
+	for column := range columnWidths {
+		if column > 0 {
+			width += layout.Padding
+		}
+		columnOffsets[column] = width
+		width += columnWidths[column]
+	}
+
+	rowOffsets := make([]int, rows)
+	height := 0
+	for row := range rowHeights {
+		if row > 0 {
+			height += layout.Padding
+		}
+		rowOffsets[row] = height
+		height += rowHeights[row]
+	}
+
+	background := layout.Background
+	if background == nil {
+		background = color.White
+	}
+
+	result := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(result, result.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	labelColor := color.Black
+	if backgroundGray := color.GrayModel.Convert(background).(color.Gray); backgroundGray.Y < 128 {
+		_, _, _, alpha := background.RGBA()
+		if alpha > 0 {
+			labelColor = color.White
+		}
+	}
+
+	for i, tile := range tiles {
+		column, row := i%columns, i/columns
+		position := image.Pt(columnOffsets[column], rowOffsets[row])
+		tileBounds := tile.Image.Bounds()
+		draw.Draw(result, tileBounds.Sub(tileBounds.Min).Add(position), tile.Image, tileBounds.Min, draw.Src)
+
+		if tile.Label != "" {
+			drawer := &font.Drawer{
+				Dst:  result,
+				Src:  image.NewUniform(labelColor),
+				Face: labelFace,
+			}
+
+			// Center the label under the tile.
+			labelWidth := drawer.MeasureString(tile.Label).Ceil()
+			metrics := labelFace.Metrics()
+			x := position.X + (tileBounds.Dx()-labelWidth)/2
+			y := position.Y + tileBounds.Dy() + (labelHeight+metrics.Ascent.Ceil()-metrics.Descent.Ceil())/2
+			drawer.Dot = fixed.P(max(position.X, x), y)
+			drawer.DrawString(tile.Label)
+		}
+	}
+
+	scale := 1.0
+	if layout.MaxWidth > 0 && width > layout.MaxWidth {
+		scale = float64(layout.MaxWidth) / float64(width)
+	}
+	if layout.MaxHeight > 0 && height > layout.MaxHeight {
+		scale = math.Min(scale, float64(layout.MaxHeight)/float64(height))
+	}
+
+	if scale < 1 {
+		scaledWidth := max(1, int(math.Round(float64(width)*scale)))
+		scaledHeight := max(1, int(math.Round(float64(height)*scale)))
+		scaled := image.NewNRGBA(image.Rect(0, 0, scaledWidth, scaledHeight))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), result, result.Bounds(), draw.Src, nil)
+		result = scaled
+	}
+
+	return result, scale, nil
+}
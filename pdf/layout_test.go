@@ -0,0 +1,131 @@
+package pdf
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func testTile(width, height int, label string) Tile {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	return Tile{Image: img, Label: label}
+}
+
+func TestComposeTiles(t *testing.T) {
+	tiles := []Tile{testTile(100, 200, ""), testTile(50, 100, ""), testTile(100, 200, ""), testTile(100, 150, ""), testTile(80, 80, "")}
+
+	tests := []struct {
+		name       string
+		tiles      []Tile
+		layout     Layout
+		wantWidth  int
+		wantHeight int
+		wantScale  float64
+		wantErr    bool
+	}{
+		{"test vertical", tiles[:2], Layout{Type: LayoutVertical, Padding: 10}, 100, 310, 1, false},
+		{"test horizontal", tiles[:2], Layout{Type: LayoutHorizontal, Padding: 10}, 160, 200, 1, false},
+		{"test grid with columns", tiles, Layout{Type: LayoutGrid, Columns: 2}, 200, 480, 1, false},
+		{"test square grid", tiles, Layout{Type: LayoutGrid}, 280, 350, 1, false},
+		{"test grid with more columns than tiles", tiles[:2], Layout{Type: LayoutGrid, Columns: 4}, 150, 200, 1, false},
+		{"test max width", tiles[:2], Layout{Type: LayoutHorizontal, MaxWidth: 75}, 75, 100, 0.5, false},
+		{"test max width and height", tiles[:2], Layout{Type: LayoutHorizontal, MaxWidth: 75, MaxHeight: 20}, 15, 20, 0.1, false},
+		{"test max size that fits", tiles[:2], Layout{Type: LayoutHorizontal, MaxWidth: 1000, MaxHeight: 1000}, 150, 200, 1, false},
+		{"test labels", []Tile{testTile(100, 200, "1"), testTile(100, 200, "2")}, Layout{Type: LayoutHorizontal, LabelSize: 20}, 200, 230, 1, false},
+		{"test invalid layout", tiles, Layout{Type: "circle"}, 0, 0, 0, true},
+		{"test no tiles", nil, Layout{Type: LayoutGrid}, 0, 0, 0, true},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, scale, err := ComposeTiles(tests[i].tiles, tests[i].layout)
+			if tests[i].wantErr {
+				if err == nil {
+					t.Errorf("expected error but got no error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+
+			if got.Bounds().Dx() != tests[i].wantWidth || got.Bounds().Dy() != tests[i].wantHeight || scale != tests[i].wantScale {
+				t.Errorf("expected %dx%d with scale %f but got %dx%d with scale %f", tests[i].wantWidth, tests[i].wantHeight, tests[i].wantScale, got.Bounds().Dx(), got.Bounds().Dy(), scale)
+			}
+		})
+	}
+}
+
+func TestComposeTilesPlacement(t *testing.T) {
+	background := color.NRGBA{R: 0xFF, A: 0xFF}
+	got, _, err := ComposeTiles([]Tile{testTile(10, 10, ""), testTile(5, 5, ""), testTile(10, 10, "")}, Layout{Type: LayoutGrid, Columns: 2, Padding: 2, Background: background})
+	if err != nil {
+		t.Fatalf("expected no error but got error %s", err.Error())
+	}
+
+	tests := []struct {
+		x, y int
+		want color.NRGBA
+	}{
+		{0, 0, color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0x80}}, // First tile.
+		{10, 0, background}, // Padding.
+		{12, 0, color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0x80}}, // Second tile.
+		{12, 5, background}, // Below the second tile.
+		{0, 12, color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0x80}}, // Third tile, in the second row.
+		{12, 12, background}, // Empty cell.
+	}
+
+	for _, test := range tests {
+		if pixel := got.NRGBAAt(test.x, test.y); pixel != test.want {
+			t.Errorf("expected pixel %d,%d to be %v but got %v", test.x, test.y, test.want, pixel)
+		}
+	}
+}
+
+func TestLayoutScale(t *testing.T) {
+	sizes := []image.Point{image.Pt(100, 200), image.Pt(50, 100)}
+
+	tests := []struct {
+		name      string
+		sizes     []image.Point
+		hasLabels bool
+		layout    Layout
+		wantScale float64
+		wantErr   bool
+	}{
+		{"test no max size", sizes, false, Layout{Type: LayoutHorizontal}, 1, false},
+		{"test max size that fits", sizes, false, Layout{Type: LayoutHorizontal, MaxWidth: 1000, MaxHeight: 1000}, 1, false},
+		{"test max width", sizes, false, Layout{Type: LayoutHorizontal, MaxWidth: 75}, 0.5, false},
+		{"test max width with padding", sizes, false, Layout{Type: LayoutHorizontal, MaxWidth: 85, Padding: 10}, 0.5, false},
+		{"test max height with padding", sizes, false, Layout{Type: LayoutVertical, MaxHeight: 160, Padding: 10}, 0.5, false},
+		{"test max height with labels", sizes, true, Layout{Type: LayoutHorizontal, MaxHeight: 130, LabelSize: 20}, 0.5, false},
+		{"test grid", []image.Point{image.Pt(100, 100), image.Pt(100, 100), image.Pt(100, 100)}, false, Layout{Type: LayoutGrid, MaxWidth: 100}, 0.5, false},
+		{"test padding larger than max width", sizes, false, Layout{Type: LayoutHorizontal, MaxWidth: 5, Padding: 10}, 0, true},
+		{"test invalid layout", sizes, false, Layout{Type: "circle"}, 0, true},
+		{"test no tiles", nil, false, Layout{Type: LayoutGrid}, 0, true},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			scale, err := LayoutScale(tests[i].sizes, tests[i].hasLabels, tests[i].layout)
+			if tests[i].wantErr {
+				if err == nil {
+					t.Errorf("expected error but got no error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+
+			if scale != tests[i].wantScale {
+				t.Errorf("expected scale %f but got %f", tests[i].wantScale, scale)
+			}
+		})
+	}
+}
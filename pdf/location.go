@@ -0,0 +1,372 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sourceSpoolSize is the size from which a remote file that can't be read
+// in ranges is written into a temporary file instead of kept in memory.
+const sourceSpoolSize = 32 * 1024 * 1024
+
+// Source opens the input files at locations with the scheme of the source,
+// like https://example.com/file.pdf.
+type Source interface {
+	Open(location *url.URL) (SourceFile, error)
+}
+
+// SourceFile is an opened input file. Pdfium reads the file by seeking, so
+// sources should only fetch the parts of the file that are read.
+type SourceFile interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+
+	// Size returns the size of the file in bytes.
+	Size() int64
+}
+
+// Sink creates the output files at locations with the scheme of the sink,
+// like s3://bucket/file.pdf.
+type Sink interface {
+	// Create creates the output file, the file is complete when the writer
+	// has been closed without error.
+	Create(location *url.URL) (io.WriteCloser, error)
+}
+
+var sources = map[string]Source{
+	"file":  fileLocation{},
+	"http":  httpSource{},
+	"https": httpSource{},
+	"s3":    s3Location{},
+}
+
+var sinks = map[string]Sink{
+	"file": fileLocation{},
+	"s3":   s3Location{},
+}
+
+// RegisterSource makes the inputs with the given scheme open through source.
+func RegisterSource(scheme string, source Source) {
+	sources[strings.ToLower(scheme)] = source
+}
+
+// RegisterSink makes the outputs with the given scheme write into sink.
+func RegisterSink(scheme string, sink Sink) {
+	sinks[strings.ToLower(scheme)] = sink
+}
+
+// IsLocation returns whether the path is a location with a scheme, like
+// s3://bucket/file.pdf, instead of a local path.
+func IsLocation(path string) bool {
+	scheme, _, found := strings.Cut(path, "://")
+	// A single letter is a drive letter on Windows.
+	if !found || len(scheme) < 2 {
+		return false
+	}
+
+	for _, char := range scheme {
+		if !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || char == '+' || char == '-' || char == '.') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LocationPath returns the path of a location without scheme, host and
+// query, or the path itself when it's not a location.
+func LocationPath(path string) string {
+	if !IsLocation(path) {
+		return path
+	}
+
+	location, err := url.Parse(path)
+	if err != nil {
+		return path
+	}
+
+	return location.Path
+}
+
+// JoinLocation joins a location, like a folder, and a relative path.
+func JoinLocation(location string, elem string) string {
+	return strings.TrimSuffix(location, "/") + "/" + strings.TrimPrefix(elem, "/")
+}
+
+// OpenLocation opens the input file at the location with the source of its
+// scheme.
+func OpenLocation(path string) (SourceFile, error) {
+	location, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid location %s: %w", path, err)
+	}
+
+	source, ok := sources[strings.ToLower(location.Scheme)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported input location %s, scheme %s is not supported", path, location.Scheme)
+	}
+
+	return source.Open(location)
+}
+
+// CreateLocation creates the output file at the location with the sink of
+// its scheme.
+func CreateLocation(path string) (io.WriteCloser, error) {
+	location, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid location %s: %w", path, err)
+	}
+
+	sink, ok := sinks[strings.ToLower(location.Scheme)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output location %s, scheme %s is not supported", path, location.Scheme)
+	}
+
+	return sink.Create(location)
+}
+
+// ValidLocation checks whether the location can be parsed and whether its
+// scheme is supported, without opening it.
+func ValidLocation(path string, output bool) error {
+	location, err := url.Parse(path)
+	if err != nil {
+		return fmt.Errorf("invalid location %s: %w", path, err)
+	}
+
+	scheme := strings.ToLower(location.Scheme)
+	if output {
+		if _, ok := sinks[scheme]; !ok {
+			return fmt.Errorf("unsupported output location %s, scheme %s is not supported", path, location.Scheme)
+		}
+	} else if _, ok := sources[scheme]; !ok {
+		return fmt.Errorf("unsupported input location %s, scheme %s is not supported", path, location.Scheme)
+	}
+
+	return nil
+}
+
+// fileLocation is the source and sink of file:// locations.
+type fileLocation struct{}
+
+func (fileLocation) path(location *url.URL) (string, error) {
+	if location.Host != "" && location.Host != "localhost" {
+		return "", fmt.Errorf("unsupported host %s in %s, only local files are supported", location.Host, location.String())
+	}
+
+	return filepath.FromSlash(location.Path), nil
+}
+
+func (l fileLocation) Open(location *url.URL) (SourceFile, error) {
+	path, err := l.path(location)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fileStat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &sizedFile{File: file, size: fileStat.Size()}, nil
+}
+
+func (l fileLocation) Create(location *url.URL) (io.WriteCloser, error) {
+	path, err := l.path(location)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	return os.Create(path)
+}
+
+type sizedFile struct {
+	*os.File
+	size int64
+}
+
+func (f *sizedFile) Size() int64 {
+	return f.size
+}
+
+// rangeReader reads a part of a remote file.
+type rangeReader interface {
+	readRange(offset int64, length int64) (io.ReadCloser, error)
+}
+
+// rangedFileBlockSize is the amount of bytes that a ranged file fetches at
+// once, pdfium mostly reads small parts.
+const rangedFileBlockSize = 256 * 1024
+
+// rangedFileMaxBlocks is the amount of blocks that a ranged file keeps.
+const rangedFileMaxBlocks = 64
+
+// rangedFile is a remote file that is read in blocks of ranges, so only the
+// parts that are read are fetched.
+type rangedFile struct {
+	reader     rangeReader
+	size       int64
+	offset     int64
+	blocks     map[int64][]byte
+	blockOrder []int64 // The fetched blocks from old to new.
+}
+
+func newRangedFile(reader rangeReader, size int64) *rangedFile {
+	return &rangedFile{
+		reader: reader,
+		size:   size,
+		blocks: map[int64][]byte{},
+	}
+}
+
+// addBlock keeps a fetched block, and drops the oldest block when too many
+// blocks are kept.
+func (f *rangedFile) addBlock(block int64, data []byte) {
+	if len(f.blockOrder) >= rangedFileMaxBlocks {
+		delete(f.blocks, f.blockOrder[0])
+		f.blockOrder = f.blockOrder[1:]
+	}
+
+	f.blocks[block] = data
+	f.blockOrder = append(f.blockOrder, block)
+}
+
+func (f *rangedFile) getBlock(block int64) ([]byte, error) {
+	if data, ok := f.blocks[block]; ok {
+		return data, nil
+	}
+
+	offset := block * rangedFileBlockSize
+	length := min(rangedFileBlockSize, f.size-offset)
+	reader, err := f.reader.readRange(offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, fmt.Errorf("could not read bytes %d-%d: %w", offset, offset+length-1, err)
+	}
+
+	f.addBlock(block, data)
+	return data, nil
+}
+
+func (f *rangedFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	n := 0
+	for n < len(p) {
+		position := off + int64(n)
+		if position >= f.size {
+			return n, io.EOF
+		}
+
+		data, err := f.getBlock(position / rangedFileBlockSize)
+		if err != nil {
+			return n, err
+		}
+
+		n += copy(p[n:], data[position%rangedFileBlockSize:])
+	}
+
+	return n, nil
+}
+
+func (f *rangedFile) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	if n > 0 && errors.Is(err, io.EOF) {
+		return n, nil
+	}
+
+	return n, err
+}
+
+func (f *rangedFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *rangedFile) Size() int64 {
+	return f.size
+}
+
+func (f *rangedFile) Close() error {
+	f.blocks = map[int64][]byte{}
+	f.blockOrder = nil
+	return nil
+}
+
+// documentSourceFile is a remote file that was read as a whole, because it
+// can't be read in ranges.
+type documentSourceFile struct {
+	io.ReadSeekCloser
+	document *StreamDocument
+}
+
+func newDocumentSourceFile(reader io.Reader, name string) (*documentSourceFile, error) {
+	document, err := readStreamDocument(reader, name, sourceSpoolSize)
+	if err != nil {
+		return nil, err
+	}
+
+	documentReader, err := document.Open()
+	if err != nil {
+		document.Remove()
+		return nil, err
+	}
+
+	return &documentSourceFile{ReadSeekCloser: documentReader, document: document}, nil
+}
+
+func (f *documentSourceFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.ReadSeekCloser.(io.ReaderAt).ReadAt(p, off)
+}
+
+func (f *documentSourceFile) Size() int64 {
+	return f.document.Size()
+}
+
+func (f *documentSourceFile) Close() error {
+	err := f.ReadSeekCloser.Close()
+	if removeErr := f.document.Remove(); err == nil {
+		err = removeErr
+	}
+	return err
+}
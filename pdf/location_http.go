@@ -0,0 +1,116 @@
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// httpSource is the source of http:// and https:// locations. Files are read
+// in ranges when the server supports it, otherwise the whole file is
+// downloaded.
+type httpSource struct {
+	client *http.Client // The client to use, http.DefaultClient when nil.
+}
+
+func (s httpSource) getClient() *http.Client {
+	if s.client == nil {
+		return http.DefaultClient
+	}
+	return s.client
+}
+
+func (s httpSource) Open(location *url.URL) (SourceFile, error) {
+	reader := &httpRangeReader{client: s.getClient(), location: location.String()}
+
+	// Request the first block, the response tells us whether the server
+	// supports ranges and what the size of the file is.
+	resp, err := reader.get(0, rangedFileBlockSize)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		size, err := parseContentRangeSize(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return nil, fmt.Errorf("could not get %s: %w", location.String(), err)
+		}
+
+		// When the file changes while we read it, the server returns the whole
+		// new file instead of a range. Weak ETags can't be used for this.
+		if etag := resp.Header.Get("ETag"); !strings.HasPrefix(etag, "W/") {
+			reader.etag = etag
+		}
+
+		file := newRangedFile(reader, size)
+		data := make([]byte, min(rangedFileBlockSize, size))
+		if _, err := io.ReadFull(resp.Body, data); err != nil {
+			return nil, fmt.Errorf("could not get %s: %w", location.String(), err)
+		}
+		file.addBlock(0, data)
+
+		return file, nil
+	case http.StatusOK:
+		return newDocumentSourceFile(resp.Body, path.Base(location.Path))
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The file is empty.
+		return newDocumentSourceFile(strings.NewReader(""), path.Base(location.Path))
+	}
+
+	return nil, fmt.Errorf("could not get %s: %s", location.String(), resp.Status)
+}
+
+type httpRangeReader struct {
+	client   *http.Client
+	location string
+	etag     string
+}
+
+func (r *httpRangeReader) get(offset int64, length int64) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, r.location, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	if r.etag != "" {
+		req.Header.Set("If-Range", r.etag)
+	}
+
+	return r.client.Do(req)
+}
+
+func (r *httpRangeReader) readRange(offset int64, length int64) (io.ReadCloser, error) {
+	resp, err := r.get(offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("could not get bytes %d-%d of %s: %s", offset, offset+length-1, r.location, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// parseContentRangeSize returns the size of the complete file from a
+// Content-Range header, like bytes 0-1023/4096.
+func parseContentRangeSize(contentRange string) (int64, error) {
+	_, size, found := strings.Cut(contentRange, "/")
+	if !found || !strings.HasPrefix(contentRange, "bytes ") {
+		return 0, fmt.Errorf("invalid Content-Range %q", contentRange)
+	}
+
+	parsedSize, err := strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unknown file size in Content-Range %q", contentRange)
+	}
+
+	return parsedSize, nil
+}
@@ -0,0 +1,176 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// s3PartSize is the size of the parts of an upload to S3, the upload keeps
+// one part in memory.
+const s3PartSize = 16 * 1024 * 1024
+
+// s3Location is the source and sink of s3://bucket/key locations. The client
+// is configured like the AWS CLI with the environment variables
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION and
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL for S3 compatible storage like
+// MinIO, or with the shared credentials file and the instance role.
+type s3Location struct{}
+
+func (s3Location) newClient() (*minio.Client, error) {
+	options := &minio.Options{
+		Creds: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{},
+		}),
+		Secure: true,
+		Region: os.Getenv("AWS_REGION"),
+	}
+	if options.Region == "" {
+		options.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+
+	endpoint := "s3.amazonaws.com"
+	customEndpoint := os.Getenv("AWS_ENDPOINT_URL_S3")
+	if customEndpoint == "" {
+		customEndpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if customEndpoint != "" {
+		endpointURL, err := url.Parse(customEndpoint)
+		if err != nil || endpointURL.Host == "" {
+			return nil, fmt.Errorf("invalid S3 endpoint %s", customEndpoint)
+		}
+
+		// S3 compatible storage mostly doesn't support buckets as subdomain.
+		endpoint = endpointURL.Host
+		options.Secure = endpointURL.Scheme != "http"
+		options.BucketLookup = minio.BucketLookupPath
+	}
+
+	return minio.New(endpoint, options)
+}
+
+func (s3Location) object(location *url.URL) (string, string, error) {
+	bucket := location.Host
+	key := strings.TrimPrefix(location.Path, "/")
+	if bucket == "" || key == "" {
+		return "", "", fmt.Errorf("invalid S3 location %s, should be like s3://bucket/key", location.String())
+	}
+
+	return bucket, key, nil
+}
+
+func (l s3Location) Open(location *url.URL) (SourceFile, error) {
+	bucket, key, err := l.object(location)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := l.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	objectInfo, err := client.StatObject(context.Background(), bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get %s: %w", location.String(), err)
+	}
+
+	return newRangedFile(&s3RangeReader{
+		client: client,
+		bucket: bucket,
+		key:    key,
+		etag:   objectInfo.ETag,
+	}, objectInfo.Size), nil
+}
+
+func (l s3Location) Create(location *url.URL) (io.WriteCloser, error) {
+	bucket, key, err := l.object(location)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := l.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// The size is unknown, so the upload is done in parts while the output
+	// is written.
+	pipeReader, pipeWriter := io.Pipe()
+	writer := &s3Writer{
+		pipeWriter: pipeWriter,
+		done:       make(chan error, 1),
+	}
+
+	go func() {
+		_, err := client.PutObject(context.Background(), bucket, key, pipeReader, -1, minio.PutObjectOptions{
+			ContentType: mime.TypeByExtension(path.Ext(key)),
+			PartSize:    s3PartSize,
+			// Don't sign the content in chunks, that isn't supported by all
+			// S3 compatible storage, the content is protected by TLS.
+			DisableContentSha256: true,
+		})
+		if err != nil {
+			err = fmt.Errorf("could not upload %s: %w", location.String(), err)
+		}
+		pipeReader.CloseWithError(err)
+		writer.done <- err
+	}()
+
+	return writer, nil
+}
+
+type s3RangeReader struct {
+	client *minio.Client
+	bucket string
+	key    string
+	etag   string
+}
+
+func (r *s3RangeReader) readRange(offset int64, length int64) (io.ReadCloser, error) {
+	options := minio.GetObjectOptions{}
+	if err := options.SetRange(offset, offset+length-1); err != nil {
+		return nil, err
+	}
+
+	// Make sure that all the ranges are of the same version of the object.
+	if r.etag != "" {
+		if err := options.SetMatchETag(r.etag); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.client.GetObject(context.Background(), r.bucket, r.key, options)
+}
+
+// s3Writer writes an output file into S3, the upload is finished when the
+// writer is closed.
+type s3Writer struct {
+	pipeWriter *io.PipeWriter
+	done       chan error
+	err        error
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.pipeWriter.Write(p)
+}
+
+func (w *s3Writer) Close() error {
+	if w.done != nil {
+		w.pipeWriter.Close()
+		w.err = <-w.done
+		w.done = nil
+	}
+
+	return w.err
+}
@@ -0,0 +1,227 @@
+package pdf
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+)
+
+func TestIsLocation(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"test s3", "s3://bucket/file.pdf", true},
+		{"test https", "https://example.com/file.pdf", true},
+		{"test file", "file:///tmp/file.pdf", true},
+		{"test local path", "/tmp/file.pdf", false},
+		{"test relative path", "file.pdf", false},
+		{"test stdin", "-", false},
+		{"test drive letter", "C://file.pdf", false},
+		{"test colon in path", "folder/a://b.pdf", false},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			if got := IsLocation(tests[i].path); got != tests[i].want {
+				t.Errorf("expected %t but got %t", tests[i].want, got)
+			}
+		})
+	}
+}
+
+// newTestS3 starts a fake S3 server with a bucket and configures the S3
+// client to use it.
+func newTestS3(t *testing.T) *s3mem.Backend {
+	backend := s3mem.New()
+	if err := backend.CreateBucket("bucket"); err != nil {
+		t.Fatalf("expected no error but got error %s", err.Error())
+	}
+
+	server := httptest.NewServer(gofakes3.New(backend).Server())
+	t.Cleanup(server.Close)
+
+	t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "pdfium-cli")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "pdfium-cli")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	return backend
+}
+
+func TestOpenLocation(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), rangedFileBlockSize/4)
+
+	folder := t.TempDir()
+	if err := os.WriteFile(filepath.Join(folder, "file.pdf"), data, 0644); err != nil {
+		t.Fatalf("expected no error but got error %s", err.Error())
+	}
+
+	var requestCount, responseBytes atomic.Int64
+	rangeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/file.pdf" {
+			http.NotFound(w, r)
+			return
+		}
+
+		requestCount.Add(1)
+		counter := &countingResponseWriter{ResponseWriter: w, count: &responseBytes}
+		http.ServeContent(counter, r, "file.pdf", time.Time{}, bytes.NewReader(data))
+	}))
+	defer rangeServer.Close()
+
+	noRangeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer noRangeServer.Close()
+
+	backend := newTestS3(t)
+	if _, err := backend.PutObject("bucket", "folder/file.pdf", map[string]string{"Last-Modified": time.Now().UTC().Format(http.TimeFormat)}, bytes.NewReader(data), int64(len(data)), nil); err != nil {
+		t.Fatalf("expected no error but got error %s", err.Error())
+	}
+
+	tests := []struct {
+		name       string
+		location   string
+		wantRanged bool
+	}{
+		{"test file", "file://" + filepath.ToSlash(filepath.Join(folder, "file.pdf")), false},
+		{"test http with ranges", rangeServer.URL + "/file.pdf", true},
+		{"test http without ranges", noRangeServer.URL + "/file.pdf", false},
+		{"test s3", "s3://bucket/folder/file.pdf", true},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			requestCount.Store(0)
+			responseBytes.Store(0)
+
+			file, err := OpenLocation(tests[i].location)
+			if err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+			defer file.Close()
+
+			if file.Size() != int64(len(data)) {
+				t.Errorf("expected size %d but got %d", len(data), file.Size())
+			}
+
+			// Pdfium starts by reading the end of the file.
+			end := make([]byte, 10)
+			if _, err := file.ReadAt(end, file.Size()-10); err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+			if !bytes.Equal(end, data[len(data)-10:]) {
+				t.Errorf("expected %q but got %q", data[len(data)-10:], end)
+			}
+
+			if _, ok := file.(*rangedFile); ok != tests[i].wantRanged {
+				t.Errorf("expected ranged %t but got %t", tests[i].wantRanged, ok)
+			}
+
+			if tests[i].location == rangeServer.URL+"/file.pdf" && responseBytes.Load() >= int64(len(data)) {
+				t.Errorf("expected a part of the file to be fetched but got %d bytes in %d requests", responseBytes.Load(), requestCount.Load())
+			}
+
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+			got, err := io.ReadAll(file)
+			if err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("expected the whole file of %d bytes but got %d bytes", len(data), len(got))
+			}
+		})
+	}
+
+	t.Run("test not found", func(t *testing.T) {
+		for _, location := range []string{rangeServer.URL + "/missing.pdf", "s3://bucket/missing.pdf", "file:///missing/file.pdf"} {
+			if _, err := OpenLocation(location); err == nil {
+				t.Errorf("expected error for %s but got no error", location)
+			}
+		}
+	})
+
+	t.Run("test unsupported scheme", func(t *testing.T) {
+		if _, err := OpenLocation("ftp://example.com/file.pdf"); err == nil {
+			t.Errorf("expected error but got no error")
+		}
+	})
+}
+
+type countingResponseWriter struct {
+	http.ResponseWriter
+	count *atomic.Int64
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	w.count.Add(int64(len(p)))
+	return w.ResponseWriter.Write(p)
+}
+
+func TestCreateLocation(t *testing.T) {
+	data := []byte(strings.Repeat("%PDF-output", 1000))
+	folder := t.TempDir()
+	backend := newTestS3(t)
+
+	tests := []struct {
+		name     string
+		location string
+		read     func() ([]byte, error)
+	}{
+		{"test file", "file://" + filepath.ToSlash(filepath.Join(folder, "sub", "output.pdf")), func() ([]byte, error) {
+			return os.ReadFile(filepath.Join(folder, "sub", "output.pdf"))
+		}},
+		{"test s3", "s3://bucket/output/output.pdf", func() ([]byte, error) {
+			object, err := backend.GetObject("bucket", "output/output.pdf", nil)
+			if err != nil {
+				return nil, err
+			}
+			defer object.Contents.Close()
+			return io.ReadAll(object.Contents)
+		}},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			writer, err := CreateLocation(tests[i].location)
+			if err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+
+			if _, err := writer.Write(data); err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+
+			got, err := tests[i].read()
+			if err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("expected %d bytes but got %d bytes", len(data), len(got))
+			}
+		})
+	}
+
+	t.Run("test unsupported scheme", func(t *testing.T) {
+		if _, err := CreateLocation("https://example.com/file.pdf"); err == nil {
+			t.Errorf("expected error but got no error")
+		}
+	})
+}
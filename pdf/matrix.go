@@ -0,0 +1,138 @@
+package pdf
+
+import (
+	"math"
+
+	"github.com/klippa-app/go-pdfium/structs"
+)
+
+// Matrix is a PDF transformation matrix [a b c d e f], which maps a point
+// (x, y) to (a*x + c*y + e, b*x + d*y + f).
+type Matrix struct {
+	A, B, C, D, E, F float64
+}
+
+// IdentityMatrix is the matrix that doesn't transform anything.
+var IdentityMatrix = Matrix{A: 1, D: 1}
+
+// NewMatrix converts a pdfium matrix into a Matrix.
+func NewMatrix(matrix structs.FPDF_FS_MATRIX) Matrix {
+	return Matrix{
+		A: float64(matrix.A),
+		B: float64(matrix.B),
+		C: float64(matrix.C),
+		D: float64(matrix.D),
+		E: float64(matrix.E),
+		F: float64(matrix.F),
+	}
+}
+
+// FSMatrix converts the matrix into a pdfium matrix.
+func (m Matrix) FSMatrix() structs.FPDF_FS_MATRIX {
+	return structs.FPDF_FS_MATRIX{
+		A: float32(m.A),
+		B: float32(m.B),
+		C: float32(m.C),
+		D: float32(m.D),
+		E: float32(m.E),
+		F: float32(m.F),
+	}
+}
+
+// Multiply returns the matrix that first applies m and then other.
+func (m Matrix) Multiply(other Matrix) Matrix {
+	return Matrix{
+		A: m.A*other.A + m.B*other.C,
+		B: m.A*other.B + m.B*other.D,
+		C: m.C*other.A + m.D*other.C,
+		D: m.C*other.B + m.D*other.D,
+		E: m.E*other.A + m.F*other.C + other.E,
+		F: m.E*other.B + m.F*other.D + other.F,
+	}
+}
+
+// TransformPoint applies the matrix to the given point.
+func (m Matrix) TransformPoint(x, y float64) (float64, float64) {
+	return m.A*x + m.C*y + m.E, m.B*x + m.D*y + m.F
+}
+
+// Rect is a rectangle in PDF coordinates, where the origin is in the
+// bottom-left corner of the page.
+type Rect struct {
+	Left   float64
+	Bottom float64
+	Right  float64
+	Top    float64
+}
+
+// Width returns the width of the rectangle.
+func (r Rect) Width() float64 {
+	return r.Right - r.Left
+}
+
+// Height returns the height of the rectangle.
+func (r Rect) Height() float64 {
+	return r.Top - r.Bottom
+}
+
+// TransformRect applies the matrix to the corners of the given rectangle and
+// returns the bounding box of the result, which is only the exact result
+// when the matrix doesn't rotate or skew.
+func (m Matrix) TransformRect(rect Rect) Rect {
+	result := Rect{
+		Left:   math.Inf(1),
+		Bottom: math.Inf(1),
+		Right:  math.Inf(-1),
+		Top:    math.Inf(-1),
+	}
+
+	corners := [][2]float64{
+		{rect.Left, rect.Bottom},
+		{rect.Left, rect.Top},
+		{rect.Right, rect.Bottom},
+		{rect.Right, rect.Top},
+	}
+	for _, corner := range corners {
+		x, y := m.TransformPoint(corner[0], corner[1])
+		result.Left = math.Min(result.Left, x)
+		result.Bottom = math.Min(result.Bottom, y)
+		result.Right = math.Max(result.Right, x)
+		result.Top = math.Max(result.Top, y)
+	}
+
+	return result
+}
+
+// FitMatrix returns the matrix that scales and moves the from rectangle onto
+// the to rectangle. Returns false when the from rectangle is empty.
+func FitMatrix(from Rect, to Rect) (Matrix, bool) {
+	if from.Width() <= 0 || from.Height() <= 0 {
+		return Matrix{}, false
+	}
+
+	scaleX := to.Width() / from.Width()
+	scaleY := to.Height() / from.Height()
+	return Matrix{
+		A: scaleX,
+		D: scaleY,
+		E: to.Left - from.Left*scaleX,
+		F: to.Bottom - from.Bottom*scaleY,
+	}, true
+}
+
+// DisplayMatrix returns the matrix that transforms PDF coordinates of a page
+// into display coordinates, where the origin is in the top left corner of the
+// page like a Region. The box is the visible box of the page and the rotation
+// is the clockwise page rotation in degrees (0, 90, 180 or 270).
+func DisplayMatrix(box Rect, rotation int) Matrix {
+	switch rotation {
+	case 90:
+		return Matrix{B: 1, C: 1, E: -box.Bottom, F: -box.Left}
+	case 180:
+		return Matrix{A: -1, D: 1, E: box.Right, F: -box.Bottom}
+	case 270:
+		return Matrix{B: -1, C: -1, E: box.Top, F: box.Right}
+	default:
+		return Matrix{A: 1, D: -1, E: -box.Left, F: box.Top}
+	}
+}
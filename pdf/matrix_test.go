@@ -0,0 +1,124 @@
+package pdf
+
+import (
+	"testing"
+)
+
+func TestMatrixTransformRect(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix Matrix
+		rect   Rect
+		want   Rect
+	}{
+		{
+			"test identity",
+			IdentityMatrix,
+			Rect{Left: 10, Bottom: 20, Right: 30, Top: 40},
+			Rect{Left: 10, Bottom: 20, Right: 30, Top: 40},
+		},
+		{
+			"test scale and translate",
+			Matrix{A: 2, D: 3, E: 100, F: 200},
+			Rect{Left: 10, Bottom: 20, Right: 30, Top: 40},
+			Rect{Left: 120, Bottom: 260, Right: 160, Top: 320},
+		},
+		{
+			"test rotate 90 degrees",
+			Matrix{B: 1, C: -1},
+			Rect{Left: 0, Bottom: 0, Right: 10, Top: 20},
+			Rect{Left: -20, Bottom: 0, Right: 0, Top: 10},
+		},
+		{
+			"test multiply applies the first matrix first",
+			Matrix{A: 2, D: 2}.Multiply(Matrix{A: 1, D: 1, E: 5, F: 5}),
+			Rect{Left: 0, Bottom: 0, Right: 10, Top: 10},
+			Rect{Left: 5, Bottom: 5, Right: 25, Top: 25},
+		},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got := tests[i].matrix.TransformRect(tests[i].rect)
+			if got != tests[i].want {
+				t.Errorf("expected %+v but got %+v", tests[i].want, got)
+			}
+		})
+	}
+}
+
+func TestDisplayMatrix(t *testing.T) {
+	// A page of 100x200 points, with a box that doesn't start at the origin.
+	box := Rect{Left: 10, Bottom: 20, Right: 110, Top: 220}
+
+	tests := []struct {
+		name     string
+		rotation int
+		// The display coordinates of the top left and bottom right corner of
+		// the unrotated page.
+		wantTopLeft     [2]float64
+		wantBottomRight [2]float64
+	}{
+		{"test no rotation", 0, [2]float64{0, 0}, [2]float64{100, 200}},
+		{"test rotate 90 degrees", 90, [2]float64{200, 0}, [2]float64{0, 100}},
+		{"test rotate 180 degrees", 180, [2]float64{100, 200}, [2]float64{0, 0}},
+		{"test rotate 270 degrees", 270, [2]float64{0, 100}, [2]float64{200, 0}},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			matrix := DisplayMatrix(box, tests[i].rotation)
+
+			x, y := matrix.TransformPoint(box.Left, box.Top)
+			if x != tests[i].wantTopLeft[0] || y != tests[i].wantTopLeft[1] {
+				t.Errorf("expected top left at %v but got %f,%f", tests[i].wantTopLeft, x, y)
+			}
+
+			x, y = matrix.TransformPoint(box.Right, box.Bottom)
+			if x != tests[i].wantBottomRight[0] || y != tests[i].wantBottomRight[1] {
+				t.Errorf("expected bottom right at %v but got %f,%f", tests[i].wantBottomRight, x, y)
+			}
+		})
+	}
+}
+
+func TestFitMatrix(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   Rect
+		to     Rect
+		want   Matrix
+		wantOk bool
+	}{
+		{
+			"test scale and move",
+			Rect{Left: 0, Bottom: -0.2, Right: 2, Top: 0.8},
+			Rect{Left: 100, Bottom: 200, Right: 140, Top: 210},
+			Matrix{A: 20, D: 10, E: 100, F: 202},
+			true,
+		},
+		{
+			"test same rectangle",
+			Rect{Left: 10, Bottom: 20, Right: 30, Top: 40},
+			Rect{Left: 10, Bottom: 20, Right: 30, Top: 40},
+			Matrix{A: 1, D: 1},
+			true,
+		},
+		{
+			"test empty rectangle",
+			Rect{Left: 10, Bottom: 20, Right: 10, Top: 40},
+			Rect{Left: 10, Bottom: 20, Right: 30, Top: 40},
+			Matrix{},
+			false,
+		},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, ok := FitMatrix(tests[i].from, tests[i].to)
+			if got != tests[i].want || ok != tests[i].wantOk {
+				t.Errorf("expected %+v %t but got %+v %t", tests[i].want, tests[i].wantOk, got, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package pdf
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// OptimizeReport is the result of optimizing a document.
+type OptimizeReport struct {
+	File            string
+	Output          string
+	OriginalSize    int64 // The size in bytes of the input.
+	OptimizedSize   int64 // The size in bytes of the output.
+	KeptOriginal    bool  // Whether the input was written because the optimized document was not smaller.
+	ImageCount      int
+	OptimizedImages int
+	Images          []OptimizeImage
+}
+
+// OptimizeImage is the result of optimizing an image object.
+type OptimizeImage struct {
+	Page         int    // The page number (1-index based).
+	Name         string // The name of the image object, like 3 or 3-2 for an image in a form object.
+	Width        int
+	Height       int
+	DPI          float64 // The resolution in which the image is displayed on the page.
+	NewWidth     int     `json:",omitempty"`
+	NewHeight    int     `json:",omitempty"`
+	OriginalSize int     // The size in bytes of the image data.
+	NewSize      int     `json:",omitempty"`
+	Optimized    bool
+	Skipped      string `json:",omitempty"` // The reason the image was not optimized.
+}
+
+// Finish counts the images and sets the optimized size.
+func (r *OptimizeReport) Finish(optimizedSize int64) {
+	r.ImageCount = len(r.Images)
+	r.OptimizedImages = 0
+	for _, optimizeImage := range r.Images {
+		if optimizeImage.Optimized {
+			r.OptimizedImages++
+		}
+	}
+
+	r.OptimizedSize = optimizedSize
+	r.KeptOriginal = optimizedSize >= r.OriginalSize && r.OriginalSize > 0
+	if r.KeptOriginal {
+		r.OptimizedSize = r.OriginalSize
+	}
+}
+
+// Reduction returns the percentage that the output is smaller than the input.
+func (r *OptimizeReport) Reduction() float64 {
+	if r.OriginalSize == 0 {
+		return 0
+	}
+	return float64(r.OriginalSize-r.OptimizedSize) / float64(r.OriginalSize) * 100
+}
+
+// ImageDPI returns the resolution in which an image of the given size in
+// pixels is displayed, the matrix maps the unit square of the image onto the
+// page. Returns 0 when the image is not visible.
+func ImageDPI(matrix Matrix, width int, height int) float64 {
+	displayWidth := math.Hypot(matrix.A, matrix.B)
+	displayHeight := math.Hypot(matrix.C, matrix.D)
+	if displayWidth == 0 || displayHeight == 0 {
+		return 0
+	}
+
+	// Use the lowest resolution, so that the image doesn't lose detail in
+	// either direction when it's downsampled.
+	return math.Min(float64(width)/displayWidth, float64(height)/displayHeight) * 72
+}
+
+// DownsampleSize returns the size of an image that is displayed in the given
+// DPI when it's downsampled to the target DPI, and false when the image
+// doesn't need to be downsampled.
+func DownsampleSize(width int, height int, dpi float64, targetDPI float64) (int, int, bool) {
+	if dpi <= targetDPI || targetDPI <= 0 {
+		return width, height, false
+	}
+
+	scale := targetDPI / dpi
+	newWidth := max(int(math.Round(float64(width)*scale)), 1)
+	newHeight := max(int(math.Round(float64(height)*scale)), 1)
+	if newWidth >= width && newHeight >= height {
+		return width, height, false
+	}
+
+	return newWidth, newHeight, true
+}
+
+// rgbaImage is an image that can return the RGBA color of a pixel without
+// allocating, like the BGR images of pdfium.
+type rgbaImage interface {
+	image.Image
+	RGBAAt(x, y int) color.RGBA
+}
+
+// Downsample scales an image to the given size, gray images stay gray.
+func Downsample(img image.Image, width int, height int) image.Image {
+	var result draw.Image
+	if _, ok := img.(*image.Gray); ok {
+		result = image.NewGray(image.Rect(0, 0, width, height))
+	} else {
+		result = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+
+	// Scaling is a lot faster for the image types of the standard library.
+	if source, ok := img.(rgbaImage); ok {
+		if _, isRGBA := img.(*image.RGBA); !isRGBA {
+			bounds := source.Bounds()
+			converted := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+			for y := 0; y < bounds.Dy(); y++ {
+				for x := 0; x < bounds.Dx(); x++ {
+					converted.SetRGBA(x, y, source.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y))
+				}
+			}
+			img = converted
+		}
+	}
+
+	draw.CatmullRom.Scale(result, result.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return result
+}
@@ -0,0 +1,132 @@
+package pdf
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestImageDPI(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix Matrix
+		width  int
+		height int
+		want   float64
+	}{
+		{"test A4 scan", Matrix{A: 595.28, D: 841.89}, 2480, 3508, 299.96},
+		{"test rotated", Matrix{B: 100, C: -200}, 200, 400, 144},
+		{"test lowest resolution", Matrix{A: 72, D: 72}, 300, 150, 150},
+		{"test invisible", Matrix{A: 0, D: 100}, 300, 300, 0},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			if got := ImageDPI(tests[i].matrix, tests[i].width, tests[i].height); math.Abs(got-tests[i].want) > 0.01 {
+				t.Errorf("expected %f but got %f", tests[i].want, got)
+			}
+		})
+	}
+}
+
+func TestDownsampleSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		width      int
+		height     int
+		dpi        float64
+		targetDPI  float64
+		wantWidth  int
+		wantHeight int
+		wantOk     bool
+	}{
+		{"test below target", 1240, 1754, 150, 150, 1240, 1754, false},
+		{"test above target", 2480, 3508, 300, 150, 1240, 1754, true},
+		{"test minimum size", 10, 2, 1000, 10, 1, 1, true},
+		{"test one pixel", 1, 1, 1000, 10, 1, 1, false},
+		{"test no target", 2480, 3508, 300, 0, 2480, 3508, false},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			width, height, ok := DownsampleSize(tests[i].width, tests[i].height, tests[i].dpi, tests[i].targetDPI)
+			if width != tests[i].wantWidth || height != tests[i].wantHeight || ok != tests[i].wantOk {
+				t.Errorf("expected %dx%d %t but got %dx%d %t", tests[i].wantWidth, tests[i].wantHeight, tests[i].wantOk, width, height, ok)
+			}
+		})
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 40, 20))
+	for i := range gray.Pix {
+		gray.Pix[i] = 128
+	}
+
+	result := Downsample(gray, 10, 5)
+	grayResult, ok := result.(*image.Gray)
+	if !ok {
+		t.Fatalf("expected gray image but got %T", result)
+	}
+	if size := grayResult.Bounds().Size(); size != (image.Point{X: 10, Y: 5}) {
+		t.Errorf("expected size 10x5 but got %v", size)
+	}
+	if got := grayResult.GrayAt(5, 2).Y; got != 128 {
+		t.Errorf("expected gray value 128 but got %d", got)
+	}
+
+	if _, ok := Downsample(image.NewRGBA(image.Rect(0, 0, 40, 20)), 10, 5).(*image.RGBA); !ok {
+		t.Errorf("expected rgba image")
+	}
+}
+
+func TestOptimizeReport(t *testing.T) {
+	tests := []struct {
+		name              string
+		report            OptimizeReport
+		optimizedSize     int64
+		wantOptimized     int
+		wantKeptOriginal  bool
+		wantOptimizedSize int64
+		wantReduction     float64
+	}{
+		{
+			"test smaller",
+			OptimizeReport{OriginalSize: 1000, Images: []OptimizeImage{{Optimized: true}, {Skipped: "transparent"}}},
+			250,
+			1,
+			false,
+			250,
+			75,
+		},
+		{
+			"test larger",
+			OptimizeReport{OriginalSize: 1000, Images: []OptimizeImage{}},
+			1200,
+			0,
+			true,
+			1000,
+			0,
+		},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			report := tests[i].report
+			report.Finish(tests[i].optimizedSize)
+
+			if report.OptimizedImages != tests[i].wantOptimized {
+				t.Errorf("expected %d optimized images but got %d", tests[i].wantOptimized, report.OptimizedImages)
+			}
+			if report.KeptOriginal != tests[i].wantKeptOriginal {
+				t.Errorf("expected kept original %t but got %t", tests[i].wantKeptOriginal, report.KeptOriginal)
+			}
+			if report.OptimizedSize != tests[i].wantOptimizedSize {
+				t.Errorf("expected optimized size %d but got %d", tests[i].wantOptimizedSize, report.OptimizedSize)
+			}
+			if got := report.Reduction(); got != tests[i].wantReduction {
+				t.Errorf("expected reduction %f but got %f", tests[i].wantReduction, got)
+			}
+		})
+	}
+}
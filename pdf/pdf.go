@@ -6,20 +6,108 @@ import (
 	"github.com/klippa-app/go-pdfium"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Be sure to close pools/instances when you're done with them.
 var pool pdfium.Pool
 var PdfiumInstance pdfium.Pdfium
 var isLoaded bool
+var keepLoaded bool
+var isKilled bool
+var killable bool
+var killLock sync.Mutex
+var loadedInstances int
 
-func ClosePdfium() {
+// LoadPdfium loads the pdfium library with one instance.
+func LoadPdfium() error {
+	return LoadPdfiumPool(1)
+}
+
+// SetPdfiumKillable makes busy calls interruptible by KillPdfium, this must
+// be set before loading pdfium. It makes the calls of WebAssembly builds a bit
+// slower.
+func SetPdfiumKillable(enabled bool) {
+	killable = enabled
+}
+
+// KillPdfium stops the instance, also when it's busy with a call. The call
+// then returns an error. The next load of pdfium replaces the instance with
+// a new one from the pool. Busy calls can only be interrupted in WebAssembly
+// builds that are killable.
+func KillPdfium() error {
+	killLock.Lock()
+	defer killLock.Unlock()
+
+	if !isLoaded || isKilled {
+		return nil
+	}
+
+	isKilled = true
+	return PdfiumInstance.Kill()
+}
+
+// reloadPdfium is used when pdfium is already loaded. It replaces a killed
+// instance, and checks whether the pool has room for the instances.
+func reloadPdfium(instances int) error {
+	if instances > loadedInstances {
+		return fmt.Errorf("pdfium is loaded with %d instance(s), but %d are needed", loadedInstances, instances)
+	}
+
+	killLock.Lock()
+	defer killLock.Unlock()
+
+	if !isKilled {
+		return nil
+	}
+
+	instance, err := pool.GetInstance(time.Second * 30)
+	if err != nil {
+		return err
+	}
+
+	PdfiumInstance = instance
+	isKilled = false
+
+	return nil
+}
+
+// NewPdfiumInstance returns another instance from the pool, the pool must be
+// loaded with room for it. Every instance has its own documents, so
+// instances can be used in parallel. Be sure to close the instance when
+// you're done with it.
+func NewPdfiumInstance() (pdfium.Pdfium, error) {
 	if !isLoaded {
+		return nil, errors.New("pdfium is not loaded")
+	}
+
+	return pool.GetInstance(time.Second * 30)
+}
+
+// KeepPdfiumLoaded makes ClosePdfium keep pdfium loaded, so that multiple
+// commands in the same process don't have to load pdfium again.
+func KeepPdfiumLoaded(keep bool) {
+	keepLoaded = keep
+}
+
+func ClosePdfium() {
+	if !isLoaded || keepLoaded {
 		return
 	}
 
-	PdfiumInstance.Close()
+	if !isKilled {
+		PdfiumInstance.Close()
+	}
+	pool.Close()
 	isLoaded = false
+	isKilled = false
+}
+
+// IsExperimentalError returns whether the error is caused by a function that
+// is only available in experimental builds.
+func IsExperimentalError(err error) bool {
+	return strings.Contains(err.Error(), "pdfium_experimental")
 }
 
 // NormalizePageRange converts a page range into separate page numbers so that
@@ -1,9 +1,113 @@
 package pdf
 
 import (
+	"bytes"
+	"sync"
 	"testing"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/enums"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/structs"
+)
+
+var (
+	loadTestPdfiumOnce sync.Once
+	loadTestPdfiumErr  error
 )
 
+// loadTestPdfium loads pdfium once for all tests, loading it takes a while.
+func loadTestPdfium(t *testing.T) pdfium.Pdfium {
+	t.Helper()
+	loadTestPdfiumOnce.Do(func() {
+		loadTestPdfiumErr = LoadPdfium()
+	})
+	if loadTestPdfiumErr != nil {
+		t.Fatalf("could not load pdfium: %v", loadTestPdfiumErr)
+	}
+	return PdfiumInstance
+}
+
+// generateTestPDF creates a PDF with a page for every text, every page has
+// the text and a filled rectangle.
+func generateTestPDF(t *testing.T, instance pdfium.Pdfium, texts ...string) []byte {
+	t.Helper()
+	document, err := instance.FPDF_CreateNewDocument(&requests.FPDF_CreateNewDocument{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{Document: document.Document})
+
+	for i, text := range texts {
+		page, err := instance.FPDFPage_New(&requests.FPDFPage_New{
+			Document:  document.Document,
+			PageIndex: i,
+			Width:     200,
+			Height:    100,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		textObject, err := instance.FPDFPageObj_NewTextObj(&requests.FPDFPageObj_NewTextObj{
+			Document: document.Document,
+			Font:     "Helvetica",
+			FontSize: 20,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := instance.FPDFText_SetText(&requests.FPDFText_SetText{PageObject: textObject.PageObject, Text: text}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := instance.FPDFPageObj_Transform(&requests.FPDFPageObj_Transform{PageObject: textObject.PageObject, Transform: structs.FPDF_FS_MATRIX{A: 1, D: 1, E: 20, F: 40}}); err != nil {
+			t.Fatal(err)
+		}
+
+		rect, err := instance.FPDFPageObj_CreateNewRect(&requests.FPDFPageObj_CreateNewRect{X: 20, Y: 10, W: 160, H: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := instance.FPDFPageObj_SetFillColor(&requests.FPDFPageObj_SetFillColor{PageObject: rect.PageObject, FillColor: structs.FPDF_COLOR{R: 255, A: 255}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := instance.FPDFPath_SetDrawMode(&requests.FPDFPath_SetDrawMode{PageObject: rect.PageObject, FillMode: enums.FPDF_FILLMODE_WINDING}); err != nil {
+			t.Fatal(err)
+		}
+
+		pageRef := requests.Page{ByReference: &page.Page}
+		for _, object := range []references.FPDF_PAGEOBJECT{textObject.PageObject, rect.PageObject} {
+			if _, err := instance.FPDFPage_InsertObject(&requests.FPDFPage_InsertObject{Page: pageRef, PageObject: object}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := instance.FPDFPage_GenerateContent(&requests.FPDFPage_GenerateContent{Page: pageRef}); err != nil {
+			t.Fatal(err)
+		}
+		instance.FPDF_ClosePage(&requests.FPDF_ClosePage{Page: page.Page})
+	}
+
+	file := &bytes.Buffer{}
+	if _, err := instance.FPDF_SaveAsCopy(&requests.FPDF_SaveAsCopy{Document: document.Document, FileWriter: file}); err != nil {
+		t.Fatal(err)
+	}
+	return file.Bytes()
+}
+
+// openTestPDF opens a PDF, it's closed when the test is done.
+func openTestPDF(t *testing.T, instance pdfium.Pdfium, file []byte) references.FPDF_DOCUMENT {
+	t.Helper()
+	document, err := instance.OpenDocument(&requests.OpenDocument{File: &file})
+	if err != nil {
+		t.Fatalf("could not open document: %v", err)
+	}
+	t.Cleanup(func() {
+		instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{Document: document.Document})
+	})
+	return document.Document
+}
+
 func TestNormalizePageRange(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -0,0 +1,105 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Region is a rectangle on a page, with the origin in the top left corner of
+// the page, like the coordinates of a rendered image.
+type Region struct {
+	X         float64
+	Y         float64
+	Width     float64
+	Height    float64
+	Fractions bool // Whether the values are fractions of the page size instead of points.
+}
+
+// ParseRegion parses a region in the format x,y,w,h. When all values are
+// between 0 and 1, they are fractions of the page size, otherwise they are
+// points (1/72 inch).
+func ParseRegion(value string) (*Region, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid region %s, use the format x,y,w,h", value)
+	}
+
+	values := make([]float64, 4)
+	for i := range parts {
+		parsedValue, err := strconv.ParseFloat(strings.TrimSpace(parts[i]), 64)
+		if err != nil || math.IsNaN(parsedValue) || math.IsInf(parsedValue, 0) {
+			return nil, fmt.Errorf("invalid region %s, %s is not a number", value, parts[i])
+		}
+		if parsedValue < 0 {
+			return nil, fmt.Errorf("invalid region %s, values can't be negative", value)
+		}
+		values[i] = parsedValue
+	}
+
+	if values[2] == 0 || values[3] == 0 {
+		return nil, fmt.Errorf("invalid region %s, width and height can't be 0", value)
+	}
+
+	region := &Region{
+		X:         values[0],
+		Y:         values[1],
+		Width:     values[2],
+		Height:    values[3],
+		Fractions: true,
+	}
+
+	for i := range values {
+		if values[i] > 1 {
+			region.Fractions = false
+		}
+	}
+
+	return region, nil
+}
+
+// InPoints returns the region in points for a page of the given size in
+// points. The region is clipped to the page.
+func (r Region) InPoints(pageWidth, pageHeight float64) (Region, error) {
+	region := r
+	if r.Fractions {
+		region = Region{
+			X:      r.X * pageWidth,
+			Y:      r.Y * pageHeight,
+			Width:  r.Width * pageWidth,
+			Height: r.Height * pageHeight,
+		}
+	}
+
+	if region.X >= pageWidth || region.Y >= pageHeight {
+		return Region{}, errors.New("region is outside of the page")
+	}
+
+	region.Width = math.Min(region.Width, pageWidth-region.X)
+	region.Height = math.Min(region.Height, pageHeight-region.Y)
+
+	return region, nil
+}
+
+// RenderSize calculates the size in pixels to render the region in, and the
+// scale from points to pixels. When a max width or height is given, the
+// region is scaled to fit in it while keeping the aspect ratio, otherwise
+// the DPI is used.
+func (r Region) RenderSize(dpi, maxWidth, maxHeight int) (int, int, float64) {
+	scale := float64(dpi) / 72
+	if maxWidth > 0 || maxHeight > 0 {
+		scale = math.Inf(1)
+		if maxWidth > 0 {
+			scale = float64(maxWidth) / r.Width
+		}
+		if maxHeight > 0 {
+			scale = math.Min(scale, float64(maxHeight)/r.Height)
+		}
+	}
+
+	width := int(math.Max(1, math.Round(r.Width*scale)))
+	height := int(math.Max(1, math.Round(r.Height*scale)))
+	return width, height, scale
+}
@@ -0,0 +1,141 @@
+package pdf
+
+import (
+	"testing"
+)
+
+func TestParseRegion(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    *Region
+		wantErr string
+	}{
+		{
+			"test points",
+			"10,20,300,400.5",
+			&Region{X: 10, Y: 20, Width: 300, Height: 400.5},
+			"",
+		},
+		{
+			"test fractions",
+			"0.5, 0, 0.5, 1",
+			&Region{X: 0.5, Y: 0, Width: 0.5, Height: 1, Fractions: true},
+			"",
+		},
+		{
+			"test too few values",
+			"10,20,300",
+			nil,
+			"invalid region 10,20,300, use the format x,y,w,h",
+		},
+		{
+			"test invalid number",
+			"10,20,abc,400",
+			nil,
+			"invalid region 10,20,abc,400, abc is not a number",
+		},
+		{
+			"test negative value",
+			"-10,20,300,400",
+			nil,
+			"invalid region -10,20,300,400, values can't be negative",
+		},
+		{
+			"test zero size",
+			"10,20,0,400",
+			nil,
+			"invalid region 10,20,0,400, width and height can't be 0",
+		},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, err := ParseRegion(tests[i].value)
+			if tests[i].wantErr == "" && err != nil {
+				t.Errorf("expected no error but got error %s", err.Error())
+			} else if tests[i].wantErr != "" && err == nil {
+				t.Errorf("expected error %s but got no error", tests[i].wantErr)
+			} else if tests[i].wantErr != "" && err != nil && err.Error() != tests[i].wantErr {
+				t.Errorf("expected error %s but got error %s", tests[i].wantErr, err.Error())
+			} else if err == nil && *got != *tests[i].want {
+				t.Errorf("expected %+v but got %+v", *tests[i].want, *got)
+			}
+		})
+	}
+}
+
+func TestRegionInPoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		region  Region
+		want    Region
+		wantErr bool
+	}{
+		{
+			"test points",
+			Region{X: 10, Y: 20, Width: 100, Height: 200},
+			Region{X: 10, Y: 20, Width: 100, Height: 200},
+			false,
+		},
+		{
+			"test fractions",
+			Region{X: 0.5, Y: 0.25, Width: 0.5, Height: 0.5, Fractions: true},
+			Region{X: 306, Y: 198, Width: 306, Height: 396},
+			false,
+		},
+		{
+			"test clipped to page",
+			Region{X: 500, Y: 700, Width: 500, Height: 500},
+			Region{X: 500, Y: 700, Width: 112, Height: 92},
+			false,
+		},
+		{
+			"test outside of page",
+			Region{X: 700, Y: 0, Width: 10, Height: 10},
+			Region{},
+			true,
+		},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, err := tests[i].region.InPoints(612, 792)
+			if tests[i].wantErr && err == nil {
+				t.Errorf("expected error but got no error")
+			} else if !tests[i].wantErr && err != nil {
+				t.Errorf("expected no error but got error %s", err.Error())
+			} else if got != tests[i].want {
+				t.Errorf("expected %+v but got %+v", tests[i].want, got)
+			}
+		})
+	}
+}
+
+func TestRegionRenderSize(t *testing.T) {
+	region := Region{Width: 144, Height: 72}
+
+	tests := []struct {
+		name       string
+		dpi        int
+		maxWidth   int
+		maxHeight  int
+		wantWidth  int
+		wantHeight int
+		wantScale  float64
+	}{
+		{"test dpi", 300, 0, 0, 600, 300, 300.0 / 72},
+		{"test max width", 300, 1000, 0, 1000, 500, 1000.0 / 144},
+		{"test max height", 300, 0, 100, 200, 100, 100.0 / 72},
+		{"test max width and height", 300, 1000, 100, 200, 100, 100.0 / 72},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			width, height, scale := region.RenderSize(tests[i].dpi, tests[i].maxWidth, tests[i].maxHeight)
+			if width != tests[i].wantWidth || height != tests[i].wantHeight || scale != tests[i].wantScale {
+				t.Errorf("expected %dx%d with scale %f but got %dx%d with scale %f", tests[i].wantWidth, tests[i].wantHeight, tests[i].wantScale, width, height, scale)
+			}
+		})
+	}
+}
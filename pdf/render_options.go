@@ -0,0 +1,83 @@
+package pdf
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klippa-app/go-pdfium/enums"
+)
+
+// RenderFlags are the names of the pdfium render flags that can be given.
+var RenderFlags = map[string]enums.FPDF_RENDER_FLAG{
+	"annotations":            enums.FPDF_RENDER_FLAG_ANNOT,
+	"lcd-text":               enums.FPDF_RENDER_FLAG_LCD_TEXT,
+	"no-native-text":         enums.FPDF_RENDER_FLAG_NO_NATIVETEXT,
+	"grayscale":              enums.FPDF_RENDER_FLAG_GRAYSCALE,
+	"convert-fill-to-stroke": enums.FPDF_RENDER_FLAG_CONVERT_FILL_TO_STROKE,
+	"limited-image-cache":    enums.FPDF_RENDER_FLAG_RENDER_LIMITEDIMAGECACHE,
+	"force-halftone":         enums.FPDF_RENDER_FLAG_RENDER_FORCEHALFTONE,
+	"print":                  enums.FPDF_RENDER_FLAG_PRINTING,
+	"no-smooth-text":         enums.FPDF_RENDER_FLAG_RENDER_NO_SMOOTHTEXT,
+	"no-smooth-image":        enums.FPDF_RENDER_FLAG_RENDER_NO_SMOOTHIMAGE,
+	"no-smooth-path":         enums.FPDF_RENDER_FLAG_RENDER_NO_SMOOTHPATH,
+}
+
+// RenderFlagNames returns the sorted names of the render flags.
+func RenderFlagNames() []string {
+	names := []string{}
+	for name := range RenderFlags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseRenderFlags parses a comma separated list of render flag names.
+func ParseRenderFlags(value string) (enums.FPDF_RENDER_FLAG, error) {
+	renderFlags := enums.FPDF_RENDER_FLAG(0)
+	if strings.TrimSpace(value) == "" {
+		return renderFlags, nil
+	}
+
+	for _, name := range strings.Split(value, ",") {
+		renderFlag, ok := RenderFlags[strings.TrimSpace(name)]
+		if !ok {
+			return 0, fmt.Errorf("invalid render flag %s, use one of %s", strings.TrimSpace(name), strings.Join(RenderFlagNames(), ", "))
+		}
+		renderFlags |= renderFlag
+	}
+
+	return renderFlags, nil
+}
+
+// ParseColor parses a color in the hex format RRGGBB or RRGGBBAA, with or
+// without #, or the value transparent.
+func ParseColor(value string) (color.NRGBA, error) {
+	if value == "transparent" {
+		return color.NRGBA{}, nil
+	}
+
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("invalid color %s, use a hex color like #ffffff or transparent", value)
+	}
+
+	parsedValue, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %s, use a hex color like #ffffff or transparent", value)
+	}
+
+	if len(hex) == 6 {
+		parsedValue = parsedValue<<8 | 0xFF
+	}
+
+	return color.NRGBA{
+		R: uint8(parsedValue >> 24),
+		G: uint8(parsedValue >> 16),
+		B: uint8(parsedValue >> 8),
+		A: uint8(parsedValue),
+	}, nil
+}
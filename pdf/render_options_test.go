@@ -0,0 +1,67 @@
+package pdf
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/klippa-app/go-pdfium/enums"
+)
+
+func TestParseRenderFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    enums.FPDF_RENDER_FLAG
+		wantErr string
+	}{
+		{"test empty", "", 0, ""},
+		{"test single flag", "print", enums.FPDF_RENDER_FLAG_PRINTING, ""},
+		{"test multiple flags", "lcd-text, no-smooth-text,no-smooth-path", enums.FPDF_RENDER_FLAG_LCD_TEXT | enums.FPDF_RENDER_FLAG_RENDER_NO_SMOOTHTEXT | enums.FPDF_RENDER_FLAG_RENDER_NO_SMOOTHPATH, ""},
+		{"test invalid flag", "print,fast", 0, "invalid render flag fast, use one of annotations, convert-fill-to-stroke, force-halftone, grayscale, lcd-text, limited-image-cache, no-native-text, no-smooth-image, no-smooth-path, no-smooth-text, print"},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, err := ParseRenderFlags(tests[i].value)
+			if tests[i].wantErr == "" && err != nil {
+				t.Errorf("expected no error but got error %s", err.Error())
+			} else if tests[i].wantErr != "" && err == nil {
+				t.Errorf("expected error %s but got no error", tests[i].wantErr)
+			} else if tests[i].wantErr != "" && err != nil && err.Error() != tests[i].wantErr {
+				t.Errorf("expected error %s but got error %s", tests[i].wantErr, err.Error())
+			} else if err == nil && got != tests[i].want {
+				t.Errorf("expected %d but got %d", tests[i].want, got)
+			}
+		})
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    color.NRGBA
+		wantErr bool
+	}{
+		{"test transparent", "transparent", color.NRGBA{}, false},
+		{"test hex with hash", "#ff8000", color.NRGBA{R: 255, G: 128, B: 0, A: 255}, false},
+		{"test hex without hash", "000000", color.NRGBA{A: 255}, false},
+		{"test hex with alpha", "#ffffff80", color.NRGBA{R: 255, G: 255, B: 255, A: 128}, false},
+		{"test short hex", "#fff", color.NRGBA{}, true},
+		{"test invalid hex", "#gggggg", color.NRGBA{}, true},
+		{"test color name", "white", color.NRGBA{}, true},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, err := ParseColor(tests[i].value)
+			if tests[i].wantErr && err == nil {
+				t.Errorf("expected error but got no error")
+			} else if !tests[i].wantErr && err != nil {
+				t.Errorf("expected no error but got error %s", err.Error())
+			} else if got != tests[i].want {
+				t.Errorf("expected %v but got %v", tests[i].want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/references"
+	"github.com/klippa-app/go-pdfium/requests"
+)
+
+// repairRenderDPI is the DPI in which the pages are rendered to compare them,
+// low because we only need to know whether they look the same.
+const repairRenderDPI = 18
+
+// RepairReport describes what was recovered when a PDF was repaired.
+type RepairReport struct {
+	File                     string
+	Output                   string
+	ValidCrossReferenceTable *bool `json:",omitempty"` // Whether the cross reference table of the input was valid, nil when unknown.
+	PageCount                int   // The amount of pages that were found in the input.
+	RepairedPageCount        int   // The amount of pages in the repaired PDF.
+	Pages                    []RepairPage
+	ContentLost              bool // Whether content of the input could not be recovered.
+}
+
+// RepairPage describes what was recovered of a page.
+type RepairPage struct {
+	Page            int    // The page number (1-index based).
+	Objects         int    // The amount of page objects in the input.
+	RepairedObjects int    // The amount of page objects in the repaired PDF.
+	DifferentPixels int    // The amount of pixels that look different in the repaired PDF.
+	LoadError       string `json:",omitempty"` // The error of loading the page of the input.
+	RenderError     string `json:",omitempty"` // The error of rendering the page of the input.
+	RepairedError   string `json:",omitempty"` // The error of loading or rendering the page of the repaired PDF.
+}
+
+// Lost returns whether content of the page was lost.
+func (p RepairPage) Lost() bool {
+	return p.LoadError != "" || p.RenderError != "" || p.RepairedError != "" || p.RepairedObjects < p.Objects || p.DifferentPixels > 0
+}
+
+// LostPages returns the pages of which content was lost.
+func (r *RepairReport) LostPages() []RepairPage {
+	lostPages := []RepairPage{}
+	for _, page := range r.Pages {
+		if page.Lost() {
+			lostPages = append(lostPages, page)
+		}
+	}
+	return lostPages
+}
+
+// RepairDocument saves the document again completely, without incremental
+// updates, so that a damaged structure like a broken cross reference table is
+// written again. The repaired PDF is opened again and every page is compared
+// with the page of the input, by their objects and by rendering them. The
+// input can only be read through the parse of pdfium, so this finds the pages
+// that pdfium can't read and the content that didn't survive saving. It
+// returns the repaired PDF and the report, of which File and Output are not
+// set.
+func RepairDocument(instance pdfium.Pdfium, document references.FPDF_DOCUMENT) ([]byte, *RepairReport, error) {
+	report := &RepairReport{
+		Pages: []RepairPage{},
+	}
+
+	// Only available in experimental builds of the native version.
+	crossReferenceTable, err := instance.FPDF_DocumentHasValidCrossReferenceTable(&requests.FPDF_DocumentHasValidCrossReferenceTable{
+		Document: document,
+	})
+	if err == nil {
+		report.ValidCrossReferenceTable = &crossReferenceTable.DocumentHasValidCrossReferenceTable
+	}
+
+	pageCount, err := instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+		Document: document,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get page count: %w", err)
+	}
+	report.PageCount = pageCount.PageCount
+
+	repairedFile := &bytes.Buffer{}
+	_, err = instance.FPDF_SaveAsCopy(&requests.FPDF_SaveAsCopy{
+		Document:   document,
+		Flags:      requests.SaveFlagNoIncremental,
+		FileWriter: repairedFile,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not save repaired document: %w", err)
+	}
+
+	repairedBytes := repairedFile.Bytes()
+	repairedDocument, err := instance.OpenDocument(&requests.OpenDocument{
+		File: &repairedBytes,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open repaired document: %w", err)
+	}
+	defer instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{Document: repairedDocument.Document})
+
+	repairedPageCount, err := instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+		Document: repairedDocument.Document,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get page count of repaired document: %w", err)
+	}
+	report.RepairedPageCount = repairedPageCount.PageCount
+
+	for i := 0; i < pageCount.PageCount; i++ {
+		report.Pages = append(report.Pages, compareRepairedPage(instance, document, repairedDocument.Document, i, repairedPageCount.PageCount))
+	}
+
+	report.ContentLost = report.RepairedPageCount < report.PageCount || len(report.LostPages()) > 0
+
+	return repairedBytes, report, nil
+}
+
+// compareRepairedPage loads and renders a page of the input and of the
+// repaired document, and compares them.
+func compareRepairedPage(instance pdfium.Pdfium, document references.FPDF_DOCUMENT, repairedDocument references.FPDF_DOCUMENT, index int, repairedPageCount int) RepairPage {
+	page := RepairPage{Page: index + 1}
+
+	objects, img, loadErr, renderErr := loadRepairPage(instance, document, index)
+	page.Objects = objects
+	if loadErr != nil {
+		page.LoadError = loadErr.Error()
+		return page
+	}
+	if renderErr != nil {
+		page.RenderError = renderErr.Error()
+		return page
+	}
+
+	if index >= repairedPageCount {
+		page.RepairedError = "page is missing"
+		return page
+	}
+
+	repairedObjects, repairedImg, loadErr, renderErr := loadRepairPage(instance, repairedDocument, index)
+	page.RepairedObjects = repairedObjects
+	if loadErr != nil {
+		page.RepairedError = loadErr.Error()
+		return page
+	}
+	if renderErr != nil {
+		page.RepairedError = renderErr.Error()
+		return page
+	}
+
+	page.DifferentPixels = DiffImages(img, repairedImg, 0).DifferentPixels
+	return page
+}
+
+// loadRepairPage loads a page, counts its objects and renders it. It returns
+// the error of loading and of rendering the page separately.
+func loadRepairPage(instance pdfium.Pdfium, document references.FPDF_DOCUMENT, index int) (int, image.Image, error, error) {
+	page, err := instance.FPDF_LoadPage(&requests.FPDF_LoadPage{
+		Document: document,
+		Index:    index,
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not load page: %w", err), nil
+	}
+	defer instance.FPDF_ClosePage(&requests.FPDF_ClosePage{Page: page.Page})
+
+	objects, err := instance.FPDFPage_CountObjects(&requests.FPDFPage_CountObjects{
+		Page: requests.Page{
+			ByReference: &page.Page,
+		},
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not count page objects: %w", err), nil
+	}
+
+	renderedPage, err := instance.RenderPageInDPI(&requests.RenderPageInDPI{
+		Page: requests.Page{
+			ByReference: &page.Page,
+		},
+		DPI: repairRenderDPI,
+	})
+	if err != nil {
+		return objects.Count, nil, nil, fmt.Errorf("could not render page: %w", err)
+	}
+	defer renderedPage.Cleanup()
+
+	// Copy the image, it's released by the cleanup.
+	img := image.NewRGBA(renderedPage.Result.Image.Bounds())
+	draw.Draw(img, img.Bounds(), renderedPage.Result.Image, img.Bounds().Min, draw.Src)
+
+	return objects.Count, img, nil, nil
+}
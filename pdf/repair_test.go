@@ -0,0 +1,90 @@
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRepairDocument(t *testing.T) {
+	instance := loadTestPdfium(t)
+	file := generateTestPDF(t, instance, "Page 1", "Page 2")
+	brokenFile := bytes.Replace(file, []byte("\nxref"), []byte("\nxxxx"), 1)
+	if bytes.Equal(brokenFile, file) {
+		t.Fatal("expected a cross reference table in the generated file")
+	}
+
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"test valid document", file},
+		// Break the keyword of the cross reference table, so that pdfium has
+		// to reconstruct it.
+		{"test broken cross reference table", brokenFile},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			document := openTestPDF(t, instance, tests[i].file)
+			repairedFile, report, err := RepairDocument(instance, document)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if report.ContentLost || report.PageCount != 2 || report.RepairedPageCount != 2 {
+				t.Errorf("expected 2 recovered pages but got %+v", report)
+			}
+			for _, page := range report.Pages {
+				if page.Lost() || page.Objects != 2 || page.RepairedObjects != 2 {
+					t.Errorf("expected page with 2 recovered objects but got %+v", page)
+				}
+			}
+
+			// The repaired file has to be usable on its own.
+			repairedDocument := openTestPDF(t, instance, repairedFile)
+			_, repairedReport, err := RepairDocument(instance, repairedDocument)
+			if err != nil || repairedReport.ContentLost || repairedReport.PageCount != 2 {
+				t.Errorf("expected the repaired file to have 2 pages but got %+v, %v", repairedReport, err)
+			}
+		})
+	}
+}
+
+func TestCompareRepairedPage(t *testing.T) {
+	instance := loadTestPdfium(t)
+	document := openTestPDF(t, instance, generateTestPDF(t, instance, "Original"))
+	otherDocument := openTestPDF(t, instance, generateTestPDF(t, instance, "Changed"))
+
+	tests := []struct {
+		name              string
+		repairedDocument  int
+		repairedPageCount int
+		wantLost          bool
+		wantDifferent     bool
+		wantRepairedError string
+	}{
+		{"test same page", 0, 1, false, false, ""},
+		{"test different page", 1, 1, true, true, ""},
+		{"test missing page", 0, 0, true, false, "page is missing"},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			repairedDocument := document
+			if tests[i].repairedDocument == 1 {
+				repairedDocument = otherDocument
+			}
+
+			page := compareRepairedPage(instance, document, repairedDocument, 0, tests[i].repairedPageCount)
+			if page.Lost() != tests[i].wantLost {
+				t.Errorf("expected lost %t but got %+v", tests[i].wantLost, page)
+			}
+			if (page.DifferentPixels > 0) != tests[i].wantDifferent {
+				t.Errorf("expected different pixels %t but got %d", tests[i].wantDifferent, page.DifferentPixels)
+			}
+			if page.RepairedError != tests[i].wantRepairedError {
+				t.Errorf("expected repaired error %q but got %q", tests[i].wantRepairedError, page.RepairedError)
+			}
+		})
+	}
+}
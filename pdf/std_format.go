@@ -0,0 +1,342 @@
+package pdf
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// StdFormat is the format of multiple files on stdin or stdout.
+type StdFormat string
+
+const (
+	StdFormatDelimiter    StdFormat = "delimiter"     // The files are separated by a delimiter on its own line.
+	StdFormatTar          StdFormat = "tar"           // A tar archive with an entry per file.
+	StdFormatZip          StdFormat = "zip"           // A zip archive with an entry per file.
+	StdFormatNDJSONBase64 StdFormat = "ndjson-base64" // A JSON object per line per file, with the content base64 encoded.
+)
+
+// The PAX records of the metadata of the files in a tar archive, as extended
+// attributes so that tar tools know them.
+const (
+	stdTarIndexRecord       = "SCHILY.xattr.user.pdfiumcli.index"
+	stdTarPageRecord        = "SCHILY.xattr.user.pdfiumcli.page"
+	stdTarContentTypeRecord = "SCHILY.xattr.user.pdfiumcli.content-type"
+)
+
+// ParseStdFormat parses the name of a std format.
+func ParseStdFormat(format string) (StdFormat, error) {
+	switch StdFormat(format) {
+	case StdFormatDelimiter, StdFormatTar, StdFormatZip, StdFormatNDJSONBase64:
+		return StdFormat(format), nil
+	}
+
+	return "", fmt.Errorf("invalid std format %s, should be delimiter, tar, zip or ndjson-base64", format)
+}
+
+// StdFile is the metadata of a file on stdout.
+type StdFile struct {
+	Name        string // The filename of the file.
+	Index       int    // The number of the file in the output (1-index based).
+	Page        int    `json:",omitempty"` // The page number of the file (1-index based), 0 when the file doesn't belong to one page.
+	ContentType string // The media type of the file, like image/png.
+}
+
+// ArchiveManifestName is the name of the manifest in an output archive.
+const ArchiveManifestName = "manifest.json"
+
+// ArchiveManifest describes the files in an output archive.
+type ArchiveManifest struct {
+	Command string        // The name of the command that wrote the archive.
+	Source  ArchiveSource // The input of the command.
+	Files   []ArchiveFile // The files in the archive, in order.
+}
+
+// ArchiveSource is the input of the command that wrote an archive.
+type ArchiveSource struct {
+	File      string // The path of the input file, - for stdin.
+	PageCount int    // The amount of pages of the input file.
+}
+
+// ArchiveFile is a file in an output archive.
+type ArchiveFile struct {
+	StdFile
+	Size int // The size of the file in bytes.
+}
+
+// stdNDJSONFile is a file in the ndjson-base64 format.
+type stdNDJSONFile struct {
+	ArchiveFile
+	Data []byte
+}
+
+// StdFileWriter writes multiple files into one stream, like stdout.
+type StdFileWriter struct {
+	writer    io.Writer
+	format    StdFormat
+	delimiter string
+	count     int
+	tar       *tar.Writer
+	zip       *zip.Writer
+	json      *json.Encoder
+	manifest  *ArchiveManifest
+}
+
+// NewStdFileWriter returns a writer that writes files into writer in the
+// given format. The delimiter is only used by the delimiter format.
+func NewStdFileWriter(writer io.Writer, format StdFormat, delimiter string) *StdFileWriter {
+	stdWriter := &StdFileWriter{
+		writer:    writer,
+		format:    format,
+		delimiter: delimiter,
+	}
+
+	switch format {
+	case StdFormatTar:
+		stdWriter.tar = tar.NewWriter(writer)
+	case StdFormatZip:
+		stdWriter.zip = zip.NewWriter(writer)
+	case StdFormatNDJSONBase64:
+		stdWriter.json = json.NewEncoder(writer)
+	}
+
+	return stdWriter
+}
+
+// SetManifest makes the writer write the manifest into the archive when it's
+// closed, the written files are added to the manifest. Only tar and zip
+// archives get a manifest.
+func (w *StdFileWriter) SetManifest(manifest *ArchiveManifest) {
+	w.manifest = manifest
+}
+
+// Count returns the amount of files that were written.
+func (w *StdFileWriter) Count() int {
+	return w.count
+}
+
+// WriteFile writes a file with its metadata.
+func (w *StdFileWriter) WriteFile(file StdFile, data []byte) error {
+	if err := w.writeEntry(file, data); err != nil {
+		return err
+	}
+
+	w.count++
+	if w.manifest != nil {
+		w.manifest.Files = append(w.manifest.Files, ArchiveFile{StdFile: file, Size: len(data)})
+	}
+
+	return nil
+}
+
+func (w *StdFileWriter) writeEntry(file StdFile, data []byte) error {
+	switch w.format {
+	case StdFormatTar:
+		paxRecords := map[string]string{
+			stdTarIndexRecord:       strconv.Itoa(file.Index),
+			stdTarContentTypeRecord: file.ContentType,
+		}
+		if file.Page > 0 {
+			paxRecords[stdTarPageRecord] = strconv.Itoa(file.Page)
+		}
+
+		err := w.tar.WriteHeader(&tar.Header{
+			Typeflag:   tar.TypeReg,
+			Name:       file.Name,
+			Mode:       0644,
+			Size:       int64(len(data)),
+			ModTime:    time.Now().Truncate(time.Second),
+			PAXRecords: paxRecords,
+			Format:     tar.FormatPAX,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = w.tar.Write(data)
+		return err
+	case StdFormatZip:
+		comment, err := json.Marshal(file)
+		if err != nil {
+			return err
+		}
+
+		entryWriter, err := w.zip.CreateHeader(&zip.FileHeader{
+			Name:     file.Name,
+			Comment:  string(comment),
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = entryWriter.Write(data)
+		return err
+	case StdFormatNDJSONBase64:
+		return w.json.Encode(stdNDJSONFile{ArchiveFile: ArchiveFile{StdFile: file, Size: len(data)}, Data: data})
+	default:
+		if w.count > 0 {
+			if _, err := io.WriteString(w.writer, "\n"+w.delimiter+"\n"); err != nil {
+				return err
+			}
+		}
+
+		_, err := w.writer.Write(data)
+		return err
+	}
+}
+
+// Close finishes the stream, archives are invalid without closing.
+func (w *StdFileWriter) Close() error {
+	if w.manifest != nil && (w.format == StdFormatTar || w.format == StdFormatZip) {
+		if w.manifest.Files == nil {
+			w.manifest.Files = []ArchiveFile{}
+		}
+
+		manifestJson, _ := json.MarshalIndent(w.manifest, "", "  ")
+		if err := w.writeEntry(StdFile{Name: ArchiveManifestName, ContentType: "application/json"}, manifestJson); err != nil {
+			return err
+		}
+	}
+
+	switch w.format {
+	case StdFormatTar:
+		return w.tar.Close()
+	case StdFormatZip:
+		return w.zip.Close()
+	}
+
+	return nil
+}
+
+// NewStdDocumentStream returns a stream of the documents in reader in the
+// given format. Documents that are larger than spoolSize are written into a
+// temporary file instead of kept in memory. A zip archive needs to be read as
+// a whole before the first document can be returned.
+func NewStdDocumentStream(reader io.Reader, format StdFormat, delimiter string, spoolSize int64) DocumentStream {
+	switch format {
+	case StdFormatTar:
+		return &tarDocumentStream{reader: tar.NewReader(reader), spoolSize: spoolSize}
+	case StdFormatZip:
+		return &zipDocumentStream{reader: reader, spoolSize: spoolSize}
+	case StdFormatNDJSONBase64:
+		return &ndjsonDocumentStream{decoder: json.NewDecoder(reader), spoolSize: spoolSize}
+	}
+
+	return NewDelimitedDocumentStream(reader, delimiter, spoolSize)
+}
+
+type tarDocumentStream struct {
+	reader    *tar.Reader
+	spoolSize int64
+}
+
+func (s *tarDocumentStream) Next() (*StreamDocument, error) {
+	for {
+		header, err := s.reader.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip folders and links.
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		return readStreamDocument(s.reader, header.Name, s.spoolSize)
+	}
+}
+
+type zipDocumentStream struct {
+	reader        io.Reader
+	spoolSize     int64
+	archive       *StreamDocument
+	archiveReader io.ReadSeekCloser
+	files         []*zip.File
+}
+
+func (s *zipDocumentStream) Next() (*StreamDocument, error) {
+	if s.archive == nil {
+		// Zip archives have their index at the end.
+		archive, err := readStreamDocument(s.reader, "", s.spoolSize)
+		if err != nil {
+			return nil, err
+		}
+		s.archive = archive
+
+		s.archiveReader, err = archive.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		zipReader, err := zip.NewReader(s.archiveReader.(io.ReaderAt), archive.Size())
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("could not read zip archive: %w", err)
+		}
+
+		for _, file := range zipReader.File {
+			if !file.FileInfo().IsDir() {
+				s.files = append(s.files, file)
+			}
+		}
+	}
+
+	if len(s.files) == 0 {
+		// The archive isn't needed anymore.
+		s.close()
+		return nil, io.EOF
+	}
+
+	file := s.files[0]
+	s.files = s.files[1:]
+
+	fileReader, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s from zip archive: %w", file.Name, err)
+	}
+	defer fileReader.Close()
+
+	return readStreamDocument(fileReader, file.Name, s.spoolSize)
+}
+
+func (s *zipDocumentStream) Archive() *StreamDocument {
+	return s.archive
+}
+
+func (s *zipDocumentStream) close() {
+	if s.archiveReader != nil {
+		s.archiveReader.Close()
+		s.archiveReader = nil
+	}
+	s.archive.Remove()
+}
+
+type ndjsonDocumentStream struct {
+	decoder   *json.Decoder
+	spoolSize int64
+}
+
+func (s *ndjsonDocumentStream) Next() (*StreamDocument, error) {
+	file := stdNDJSONFile{}
+	if err := s.decoder.Decode(&file); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("could not read JSON document: %w", err)
+	}
+
+	document := &StreamDocument{name: file.Name, spoolSize: s.spoolSize}
+	if err := document.write(file.Data); err != nil {
+		document.Remove()
+		return nil, err
+	}
+
+	return document, document.finish()
+}
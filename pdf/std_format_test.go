@@ -0,0 +1,254 @@
+package pdf
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestParseStdFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		want    StdFormat
+		wantErr bool
+	}{
+		{"test delimiter", "delimiter", StdFormatDelimiter, false},
+		{"test tar", "tar", StdFormatTar, false},
+		{"test zip", "zip", StdFormatZip, false},
+		{"test ndjson-base64", "ndjson-base64", StdFormatNDJSONBase64, false},
+		{"test invalid", "rar", "", true},
+		{"test empty", "", "", true},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, err := ParseStdFormat(tests[i].format)
+			if tests[i].wantErr {
+				if err == nil {
+					t.Errorf("expected error but got no error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+
+			if got != tests[i].want {
+				t.Errorf("expected %s but got %s", tests[i].want, got)
+			}
+		})
+	}
+}
+
+func TestStdFormatRoundTrip(t *testing.T) {
+	files := []StdFile{
+		{Name: "invoice-1.png", Index: 1, Page: 1, ContentType: "image/png"},
+		{Name: "invoice-2.png", Index: 2, Page: 2, ContentType: "image/png"},
+		{Name: "attachment.txt", Index: 3, ContentType: "text/plain; charset=utf-8"},
+	}
+	delimiter := "--pdfium-cli-file-boundary"
+	data := [][]byte{
+		[]byte("first"),
+		// Binary data that contains the delimiter.
+		[]byte("second\n" + delimiter + "\nstill second"),
+		{},
+	}
+
+	tests := []struct {
+		name      string
+		format    StdFormat
+		wantNames bool
+		wantData  [][]byte
+	}{
+		// The delimiter format can't contain the delimiter.
+		{"test delimiter", StdFormatDelimiter, false, [][]byte{[]byte("first"), []byte("second"), []byte("still second"), {}}},
+		{"test tar", StdFormatTar, true, data},
+		{"test zip", StdFormatZip, true, data},
+		{"test ndjson-base64", StdFormatNDJSONBase64, true, data},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			output := &bytes.Buffer{}
+			writer := NewStdFileWriter(output, tests[i].format, delimiter)
+			for j := range files {
+				if err := writer.WriteFile(files[j], data[j]); err != nil {
+					t.Fatalf("expected no error but got error %s", err.Error())
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+
+			stream := NewStdDocumentStream(bytes.NewReader(output.Bytes()), tests[i].format, delimiter, 8)
+			for j, want := range tests[i].wantData {
+				document, err := stream.Next()
+				if err != nil {
+					t.Fatalf("expected no error but got error %s", err.Error())
+				}
+
+				documentReader, err := document.Open()
+				if err != nil {
+					t.Fatalf("expected no error but got error %s", err.Error())
+				}
+				got, _ := io.ReadAll(documentReader)
+				documentReader.Close()
+				document.Remove()
+
+				if !bytes.Equal(got, want) {
+					t.Errorf("expected document %d to be %q but got %q", j+1, want, got)
+				}
+
+				if tests[i].wantNames && document.Name() != files[j].Name {
+					t.Errorf("expected name %s for document %d but got %s", files[j].Name, j+1, document.Name())
+				}
+			}
+
+			if _, err := stream.Next(); !errors.Is(err, io.EOF) {
+				t.Errorf("expected EOF but got %v", err)
+			}
+		})
+	}
+}
+
+func TestStdFormatMetadata(t *testing.T) {
+	file := StdFile{Name: "invoice-2.png", Index: 1, Page: 2, ContentType: "image/png"}
+
+	t.Run("test tar", func(t *testing.T) {
+		output := &bytes.Buffer{}
+		writer := NewStdFileWriter(output, StdFormatTar, "")
+		writer.WriteFile(file, []byte("png"))
+		writer.Close()
+
+		header, err := tar.NewReader(output).Next()
+		if err != nil {
+			t.Fatalf("expected no error but got error %s", err.Error())
+		}
+
+		want := map[string]string{"SCHILY.xattr.user.pdfiumcli.index": "1", "SCHILY.xattr.user.pdfiumcli.page": "2", "SCHILY.xattr.user.pdfiumcli.content-type": "image/png"}
+		if header.Name != file.Name || !reflect.DeepEqual(header.PAXRecords, want) {
+			t.Errorf("expected %s with %v but got %s with %v", file.Name, want, header.Name, header.PAXRecords)
+		}
+	})
+
+	t.Run("test zip", func(t *testing.T) {
+		output := &bytes.Buffer{}
+		writer := NewStdFileWriter(output, StdFormatZip, "")
+		writer.WriteFile(file, []byte("png"))
+		writer.Close()
+
+		zipReader, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+		if err != nil {
+			t.Fatalf("expected no error but got error %s", err.Error())
+		}
+
+		got := StdFile{}
+		if err := json.Unmarshal([]byte(zipReader.File[0].Comment), &got); err != nil {
+			t.Fatalf("expected no error but got error %s", err.Error())
+		}
+
+		if got != file {
+			t.Errorf("expected %+v but got %+v", file, got)
+		}
+	})
+
+	t.Run("test ndjson-base64", func(t *testing.T) {
+		output := &bytes.Buffer{}
+		writer := NewStdFileWriter(output, StdFormatNDJSONBase64, "")
+		writer.WriteFile(file, []byte("png"))
+		writer.Close()
+
+		want := `{"Name":"invoice-2.png","Index":1,"Page":2,"ContentType":"image/png","Size":3,"Data":"cG5n"}` + "\n"
+		if output.String() != want {
+			t.Errorf("expected %s but got %s", want, output.String())
+		}
+	})
+
+	t.Run("test manifest", func(t *testing.T) {
+		output := &bytes.Buffer{}
+		writer := NewStdFileWriter(output, StdFormatZip, "")
+		writer.SetManifest(&ArchiveManifest{Command: "render", Source: ArchiveSource{File: "invoice.pdf", PageCount: 3}})
+		writer.WriteFile(file, []byte("png"))
+		if err := writer.Close(); err != nil {
+			t.Fatalf("expected no error but got error %s", err.Error())
+		}
+
+		if writer.Count() != 1 {
+			t.Errorf("expected 1 file but got %d", writer.Count())
+		}
+
+		zipReader, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+		if err != nil {
+			t.Fatalf("expected no error but got error %s", err.Error())
+		}
+
+		if len(zipReader.File) != 2 || zipReader.File[1].Name != ArchiveManifestName {
+			t.Fatalf("expected the file and the manifest but got %d files", len(zipReader.File))
+		}
+
+		manifestReader, err := zipReader.File[1].Open()
+		if err != nil {
+			t.Fatalf("expected no error but got error %s", err.Error())
+		}
+		defer manifestReader.Close()
+
+		got := ArchiveManifest{}
+		if err := json.NewDecoder(manifestReader).Decode(&got); err != nil {
+			t.Fatalf("expected no error but got error %s", err.Error())
+		}
+
+		want := ArchiveManifest{
+			Command: "render",
+			Source:  ArchiveSource{File: "invoice.pdf", PageCount: 3},
+			Files:   []ArchiveFile{{StdFile: file, Size: 3}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %+v but got %+v", want, got)
+		}
+	})
+}
+
+func TestZipDocumentStreamArchive(t *testing.T) {
+	output := &bytes.Buffer{}
+	writer := NewStdFileWriter(output, StdFormatZip, "")
+	if err := writer.WriteFile(StdFile{Name: "first.pdf"}, []byte("first")); err != nil {
+		t.Fatalf("expected no error but got error %s", err.Error())
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("expected no error but got error %s", err.Error())
+	}
+
+	stream, ok := NewStdDocumentStream(bytes.NewReader(output.Bytes()), StdFormatZip, "", 8).(ArchiveDocumentStream)
+	if !ok {
+		t.Fatal("expected the zip stream to be an archive stream")
+	}
+	if stream.Archive() != nil {
+		t.Errorf("expected no archive before the first document")
+	}
+
+	document, err := stream.Next()
+	if err != nil {
+		t.Fatalf("expected no error but got error %s", err.Error())
+	}
+	document.Remove()
+
+	// The archive is larger than the spool size, so it's in a temporary file.
+	archive := stream.Archive()
+	if archive == nil || !archive.IsSpooled() {
+		t.Fatalf("expected the archive in a temporary file")
+	}
+
+	if _, err := stream.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected EOF but got %v", err)
+	}
+	if archive.IsSpooled() {
+		t.Errorf("expected the temporary file of the archive to be removed")
+	}
+}
@@ -0,0 +1,372 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PathSegmentType is the type of a segment of a path.
+type PathSegmentType int
+
+const (
+	PathSegmentMoveTo   PathSegmentType = iota // Starts a new subpath.
+	PathSegmentLineTo                          // A straight line to the point.
+	PathSegmentBezierTo                        // A point of a cubic bezier curve, a curve consists of 2 control points and the end point.
+)
+
+// PathSegment is a segment of a path, the points are in the coordinate space
+// of the path.
+type PathSegment struct {
+	Type  PathSegmentType
+	X     float64
+	Y     float64
+	Close bool // Whether the subpath is closed after this segment.
+}
+
+// TransformPath applies the matrix to all points of the path.
+func (m Matrix) TransformPath(segments []PathSegment) []PathSegment {
+	transformed := make([]PathSegment, len(segments))
+	for i := range segments {
+		transformed[i] = segments[i]
+		transformed[i].X, transformed[i].Y = m.TransformPoint(segments[i].X, segments[i].Y)
+	}
+	return transformed
+}
+
+// SVGPathData converts the segments of a path into the data of an SVG path.
+func SVGPathData(segments []PathSegment) string {
+	data := []string{}
+	for i := 0; i < len(segments); i++ {
+		segment := segments[i]
+		switch segment.Type {
+		case PathSegmentMoveTo:
+			data = append(data, "M"+svgNumber(segment.X)+" "+svgNumber(segment.Y))
+		case PathSegmentLineTo:
+			data = append(data, "L"+svgNumber(segment.X)+" "+svgNumber(segment.Y))
+		case PathSegmentBezierTo:
+			// A curve needs 3 points, skip incomplete curves.
+			if i+2 >= len(segments) || segments[i+1].Type != PathSegmentBezierTo || segments[i+2].Type != PathSegmentBezierTo {
+				continue
+			}
+			data = append(data, "C"+svgNumber(segment.X)+" "+svgNumber(segment.Y)+" "+svgNumber(segments[i+1].X)+" "+svgNumber(segments[i+1].Y)+" "+svgNumber(segments[i+2].X)+" "+svgNumber(segments[i+2].Y))
+			i += 2
+			segment = segments[i]
+		}
+
+		if segment.Close {
+			data = append(data, "Z")
+		}
+	}
+	return strings.Join(data, " ")
+}
+
+// SVGStyle is the paint of an SVG element. Without fill and stroke the
+// element is invisible.
+type SVGStyle struct {
+	Fill        *color.NRGBA
+	EvenOdd     bool // Whether to use the even-odd rule to fill, instead of the non-zero winding rule.
+	Stroke      *color.NRGBA
+	StrokeWidth float64 // 0 is the thinnest line that can be displayed.
+	LineCap     string  // butt, round or square.
+	LineJoin    string  // miter, round or bevel.
+	DashArray   []float64
+	DashPhase   float64
+}
+
+func (s SVGStyle) attributes() string {
+	attributes := []string{}
+	if s.Fill == nil {
+		attributes = append(attributes, `fill="none"`)
+	} else {
+		attributes = append(attributes, svgColorAttributes("fill", *s.Fill)...)
+		if s.EvenOdd {
+			attributes = append(attributes, `fill-rule="evenodd"`)
+		}
+	}
+
+	if s.Stroke != nil {
+		attributes = append(attributes, svgColorAttributes("stroke", *s.Stroke)...)
+		if s.StrokeWidth > 0 {
+			attributes = append(attributes, `stroke-width="`+svgNumber(s.StrokeWidth)+`"`)
+		} else {
+			attributes = append(attributes, `stroke-width="1"`, `vector-effect="non-scaling-stroke"`)
+		}
+		if s.LineCap != "" && s.LineCap != "butt" {
+			attributes = append(attributes, `stroke-linecap="`+s.LineCap+`"`)
+		}
+		if s.LineJoin != "" && s.LineJoin != "miter" {
+			attributes = append(attributes, `stroke-linejoin="`+s.LineJoin+`"`)
+		}
+		if len(s.DashArray) > 0 {
+			dashes := make([]string, len(s.DashArray))
+			for i := range s.DashArray {
+				dashes[i] = svgNumber(s.DashArray[i])
+			}
+			attributes = append(attributes, `stroke-dasharray="`+strings.Join(dashes, " ")+`"`)
+			if s.DashPhase != 0 {
+				attributes = append(attributes, `stroke-dashoffset="`+svgNumber(s.DashPhase)+`"`)
+			}
+		}
+	}
+
+	return strings.Join(attributes, " ")
+}
+
+// SVGText is a text on a page.
+type SVGText struct {
+	Text          string
+	Matrix        Matrix // The matrix from text space to page space.
+	FontSize      float64
+	FontFamily    string
+	GenericFamily string // The family to use when the font isn't available, serif, sans-serif or monospace.
+	Bold          bool
+	Italic        bool
+	Width         float64 // The width of the text in text space, the text is stretched to this width. 0 to use the width of the font of the viewer.
+	Style         SVGStyle
+}
+
+// SVGImage is an image on a page.
+type SVGImage struct {
+	MimeType string
+	Data     []byte
+	Matrix   Matrix // The matrix that maps the unit square to page space.
+}
+
+// SVGWriter writes the objects of a page into an SVG image. Objects are
+// added in page space, clip paths in page space as well.
+type SVGWriter struct {
+	viewBox Region
+	width   int
+	height  int
+
+	// The clip paths by their path data, to reuse clip paths that are
+	// shared between objects.
+	clipPathIDs map[string]string
+	// The clip path IDs of the open groups.
+	openClipPaths []string
+
+	defs bytes.Buffer
+	body bytes.Buffer
+}
+
+// NewSVGWriter creates an SVG writer. The view box is the part of the page
+// that is visible, in display coordinates (points), which is shown in the
+// given width and height in pixels. The page matrix transforms page space
+// into display coordinates, see DisplayMatrix.
+func NewSVGWriter(viewBox Region, width, height int, pageMatrix Matrix, background color.NRGBA) *SVGWriter {
+	writer := &SVGWriter{
+		viewBox:     viewBox,
+		width:       width,
+		height:      height,
+		clipPathIDs: map[string]string{},
+	}
+
+	if background.A > 0 {
+		fmt.Fprintf(&writer.body, `<rect x="%s" y="%s" width="%s" height="%s" %s/>`+"\n", svgNumber(viewBox.X), svgNumber(viewBox.Y), svgNumber(viewBox.Width), svgNumber(viewBox.Height), strings.Join(svgColorAttributes("fill", background), " "))
+	}
+	fmt.Fprintf(&writer.body, `<g transform="%s">`+"\n", svgMatrix(pageMatrix))
+
+	return writer
+}
+
+// AddPath adds a path, the matrix transforms the points of the path into page
+// space. The path is clipped by all the clip paths.
+func (w *SVGWriter) AddPath(segments []PathSegment, matrix Matrix, style SVGStyle, clipPaths [][]PathSegment) {
+	data := SVGPathData(segments)
+	if data == "" {
+		return
+	}
+
+	w.clip(clipPaths)
+	fmt.Fprintf(&w.body, `<path d="%s" transform="%s" %s/>`+"\n", data, svgMatrix(matrix), style.attributes())
+}
+
+// AddText adds a text, which is clipped by all the clip paths.
+func (w *SVGWriter) AddText(text SVGText, clipPaths [][]PathSegment) {
+	if strings.TrimSpace(text.Text) == "" {
+		return
+	}
+
+	fontFamilies := []string{}
+	if fontFamily := strings.NewReplacer(`'`, "", `"`, "", ",", "").Replace(text.FontFamily); fontFamily != "" {
+		fontFamilies = append(fontFamilies, "'"+fontFamily+"'")
+	}
+	if text.GenericFamily != "" {
+		fontFamilies = append(fontFamilies, text.GenericFamily)
+	}
+
+	attributes := []string{
+		// Text is drawn with the y-axis pointing down, flip it back because
+		// page space has the y-axis pointing up.
+		`transform="` + svgMatrix(Matrix{A: 1, D: -1}.Multiply(text.Matrix)) + `"`,
+		`font-size="` + svgNumber(text.FontSize) + `"`,
+	}
+	if len(fontFamilies) > 0 {
+		attributes = append(attributes, `font-family="`+xmlEscape(strings.Join(fontFamilies, ", "))+`"`)
+	}
+	if text.Bold {
+		attributes = append(attributes, `font-weight="bold"`)
+	}
+	if text.Italic {
+		attributes = append(attributes, `font-style="italic"`)
+	}
+	if text.Width > 0 {
+		attributes = append(attributes, `textLength="`+svgNumber(text.Width)+`"`, `lengthAdjust="spacingAndGlyphs"`)
+	}
+	attributes = append(attributes, text.Style.attributes())
+
+	w.clip(clipPaths)
+	fmt.Fprintf(&w.body, `<text %s>%s</text>`+"\n", strings.Join(attributes, " "), xmlEscape(text.Text))
+}
+
+// AddImage adds an image, which is clipped by all the clip paths.
+func (w *SVGWriter) AddImage(image SVGImage, clipPaths [][]PathSegment) {
+	w.clip(clipPaths)
+
+	// The image is drawn in the unit square with the first row at the top,
+	// flip it because the first row of a PDF image is at y = 1.
+	fmt.Fprintf(&w.body, `<image width="1" height="1" preserveAspectRatio="none" transform="%s" xlink:href="data:%s;base64,%s"/>`+"\n", svgMatrix(Matrix{A: 1, D: -1, F: 1}.Multiply(image.Matrix)), image.MimeType, base64.StdEncoding.EncodeToString(image.Data))
+}
+
+// clip makes sure that the next object is placed in groups that are clipped by
+// the clip paths. Objects that follow each other with the same clip paths share
+// the groups.
+func (w *SVGWriter) clip(clipPaths [][]PathSegment) {
+	clipPathIDs := []string{}
+	for _, clipPath := range clipPaths {
+		data := SVGPathData(clipPath)
+		if data == "" {
+			continue
+		}
+
+		id, ok := w.clipPathIDs[data]
+		if !ok {
+			id = "clip" + strconv.Itoa(len(w.clipPathIDs)+1)
+			w.clipPathIDs[data] = id
+			fmt.Fprintf(&w.defs, `<clipPath id="%s"><path d="%s"/></clipPath>`+"\n", id, data)
+		}
+		clipPathIDs = append(clipPathIDs, id)
+	}
+
+	if strings.Join(clipPathIDs, ",") == strings.Join(w.openClipPaths, ",") {
+		return
+	}
+
+	w.closeClipGroups()
+	for _, id := range clipPathIDs {
+		fmt.Fprintf(&w.body, `<g clip-path="url(#%s)">`+"\n", id)
+	}
+	w.openClipPaths = clipPathIDs
+}
+
+func (w *SVGWriter) closeClipGroups() {
+	for range w.openClipPaths {
+		w.body.WriteString("</g>\n")
+	}
+	w.openClipPaths = nil
+}
+
+// WriteTo writes the SVG image.
+func (w *SVGWriter) WriteTo(out io.Writer) (int64, error) {
+	w.closeClipGroups()
+
+	var result bytes.Buffer
+	result.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&result, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" version="1.1" width="%d" height="%d" viewBox="%s %s %s %s" xml:space="preserve">`+"\n", w.width, w.height, svgNumber(w.viewBox.X), svgNumber(w.viewBox.Y), svgNumber(w.viewBox.Width), svgNumber(w.viewBox.Height))
+	if w.defs.Len() > 0 {
+		result.WriteString("<defs>\n")
+		result.Write(w.defs.Bytes())
+		result.WriteString("</defs>\n")
+	}
+	result.Write(w.body.Bytes())
+	result.WriteString("</g>\n</svg>\n")
+
+	return result.WriteTo(out)
+}
+
+// svgNumber formats a number with at most 3 decimals.
+func svgNumber(value float64) string {
+	formatted := strconv.FormatFloat(value, 'f', 3, 64)
+	formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+	if formatted == "-0" {
+		return "0"
+	}
+	return formatted
+}
+
+// svgMatrix formats a matrix as SVG transform, with 6 significant digits
+// because small scales are common in text and image matrices.
+func svgMatrix(m Matrix) string {
+	values := []string{}
+	for _, value := range []float64{m.A, m.B, m.C, m.D, m.E, m.F} {
+		if value == 0 {
+			// Prevent -0.
+			value = 0
+		}
+		values = append(values, strconv.FormatFloat(value, 'g', 6, 64))
+	}
+	return "matrix(" + strings.Join(values, " ") + ")"
+}
+
+func svgColorAttributes(name string, c color.NRGBA) []string {
+	attributes := []string{fmt.Sprintf(`%s="#%02x%02x%02x"`, name, c.R, c.G, c.B)}
+	if c.A < 255 {
+		attributes = append(attributes, name+`-opacity="`+svgNumber(float64(c.A)/255)+`"`)
+	}
+	return attributes
+}
+
+// ParseFontName returns the family of a PDF font name and whether the name
+// indicates a bold or italic style. PDF font names look like
+// ABCDEF+TimesNewRomanPS-BoldItalicMT, with an optional subset prefix and a
+// style after a dash or comma, the family of that name is Times New Roman.
+func ParseFontName(name string) (family string, bold bool, italic bool) {
+	// Remove the subset prefix, which is 6 uppercase letters and a plus.
+	if len(name) > 7 && name[6] == '+' && strings.ToUpper(name[:6]) == name[:6] {
+		name = name[7:]
+	}
+
+	lowerName := strings.ToLower(name)
+	bold = strings.Contains(lowerName, "bold") || strings.Contains(lowerName, "black") || strings.Contains(lowerName, "heavy")
+	italic = strings.Contains(lowerName, "italic") || strings.Contains(lowerName, "oblique")
+
+	family, _, _ = strings.Cut(name, "-")
+	family, _, _ = strings.Cut(family, ",")
+	family = strings.TrimSuffix(strings.TrimSuffix(family, "MT"), "PS")
+
+	// Add spaces between the words of the family.
+	var spaced strings.Builder
+	for i, char := range family {
+		if i > 0 && char >= 'A' && char <= 'Z' && family[i-1] >= 'a' && family[i-1] <= 'z' {
+			spaced.WriteRune(' ')
+		}
+		spaced.WriteRune(char)
+	}
+
+	return spaced.String(), bold, italic
+}
+
+// GenericFontFamily returns the generic font family (serif, sans-serif or
+// monospace) for a font, based on the font flags and on the family name for
+// fonts that don't have the flags set, like the standard fonts.
+func GenericFontFamily(family string, serif bool, fixedPitch bool) string {
+	lowerFamily := strings.ToLower(family)
+	if fixedPitch || strings.Contains(lowerFamily, "mono") || strings.Contains(lowerFamily, "courier") || strings.Contains(lowerFamily, "consol") {
+		return "monospace"
+	}
+
+	if strings.Contains(lowerFamily, "sans") {
+		return "sans-serif"
+	}
+
+	if serif || strings.Contains(lowerFamily, "serif") || strings.Contains(lowerFamily, "times") || strings.Contains(lowerFamily, "georgia") || strings.Contains(lowerFamily, "garamond") || strings.Contains(lowerFamily, "cambria") {
+		return "serif"
+	}
+
+	return "sans-serif"
+}
@@ -0,0 +1,160 @@
+package pdf
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestSVGPathData(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []PathSegment
+		want     string
+	}{
+		{"test empty", nil, ""},
+		{
+			"test closed rectangle",
+			[]PathSegment{
+				{Type: PathSegmentMoveTo, X: 10, Y: 10},
+				{Type: PathSegmentLineTo, X: 20.5, Y: 10},
+				{Type: PathSegmentLineTo, X: 20.5, Y: 20.1234},
+				{Type: PathSegmentLineTo, X: 10, Y: 20.1234, Close: true},
+			},
+			"M10 10 L20.5 10 L20.5 20.123 L10 20.123 Z",
+		},
+		{
+			"test curve",
+			[]PathSegment{
+				{Type: PathSegmentMoveTo, X: 0, Y: 0},
+				{Type: PathSegmentBezierTo, X: 1, Y: 2},
+				{Type: PathSegmentBezierTo, X: 3, Y: 4},
+				{Type: PathSegmentBezierTo, X: 5, Y: 6, Close: true},
+			},
+			"M0 0 C1 2 3 4 5 6 Z",
+		},
+		{
+			"test incomplete curve",
+			[]PathSegment{
+				{Type: PathSegmentMoveTo, X: 0, Y: 0},
+				{Type: PathSegmentBezierTo, X: 1, Y: 2},
+				{Type: PathSegmentLineTo, X: -0.0001, Y: 4},
+			},
+			"M0 0 L0 4",
+		},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			if got := SVGPathData(tests[i].segments); got != tests[i].want {
+				t.Errorf("expected %s but got %s", tests[i].want, got)
+			}
+		})
+	}
+}
+
+func TestSVGWriter(t *testing.T) {
+	black := color.NRGBA{A: 0xFF}
+	red := color.NRGBA{R: 0xFF, A: 0x80}
+	square := []PathSegment{
+		{Type: PathSegmentMoveTo, X: 0, Y: 0},
+		{Type: PathSegmentLineTo, X: 10, Y: 0},
+		{Type: PathSegmentLineTo, X: 10, Y: 10, Close: true},
+	}
+
+	writer := NewSVGWriter(Region{Width: 100, Height: 200}, 50, 100, DisplayMatrix(Rect{Right: 100, Top: 200}, 0), color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF})
+	writer.AddPath(square, IdentityMatrix, SVGStyle{Fill: &red, EvenOdd: true}, [][]PathSegment{square})
+	writer.AddPath(square, Matrix{A: 2, D: 2}, SVGStyle{Stroke: &black, LineCap: "round", DashArray: []float64{3, 1}}, [][]PathSegment{square})
+	writer.AddText(SVGText{Text: "Total <€ 10>", Matrix: Matrix{A: 1, D: 1, E: 10, F: 20}, FontSize: 12, FontFamily: "Helvetica", GenericFamily: "sans-serif", Bold: true, Width: 50, Style: SVGStyle{Fill: &black}}, nil)
+	writer.AddText(SVGText{Text: " ", FontSize: 12}, nil)
+	writer.AddImage(SVGImage{MimeType: "image/png", Data: []byte("png"), Matrix: Matrix{A: 10, D: 20}}, [][]PathSegment{square})
+
+	var out bytes.Buffer
+	if _, err := writer.WriteTo(&out); err != nil {
+		t.Fatalf("expected no error but got error %s", err.Error())
+	}
+	got := out.String()
+
+	want := []string{
+		`width="50" height="100" viewBox="0 0 100 200"`,
+		`<rect x="0" y="0" width="100" height="200" fill="#ffffff"/>`,
+		`<g transform="matrix(1 0 0 -1 0 200)">`,
+		`<clipPath id="clip1"><path d="M0 0 L10 0 L10 10 Z"/></clipPath>`,
+		"<g clip-path=\"url(#clip1)\">\n" +
+			`<path d="M0 0 L10 0 L10 10 Z" transform="matrix(1 0 0 1 0 0)" fill="#ff0000" fill-opacity="0.502" fill-rule="evenodd"/>` + "\n" +
+			`<path d="M0 0 L10 0 L10 10 Z" transform="matrix(2 0 0 2 0 0)" fill="none" stroke="#000000" stroke-width="1" vector-effect="non-scaling-stroke" stroke-linecap="round" stroke-dasharray="3 1"/>` + "\n" +
+			"</g>\n",
+		`<text transform="matrix(1 0 0 -1 10 20)" font-size="12" font-family="&#39;Helvetica&#39;, sans-serif" font-weight="bold" textLength="50" lengthAdjust="spacingAndGlyphs" fill="#000000">Total &lt;€ 10&gt;</text>`,
+		"<g clip-path=\"url(#clip1)\">\n" +
+			`<image width="1" height="1" preserveAspectRatio="none" transform="matrix(10 0 0 -20 0 20)" xlink:href="data:image/png;base64,cG5n"/>` + "\n" +
+			"</g>\n</g>\n</svg>\n",
+	}
+
+	for _, snippet := range want {
+		if !strings.Contains(got, snippet) {
+			t.Errorf("expected svg to contain %s but got %s", snippet, got)
+		}
+	}
+
+	if strings.Count(got, "<clipPath") != 1 {
+		t.Errorf("expected the clip path to be reused but got %s", got)
+	}
+
+	if strings.Count(got, "<text") != 1 {
+		t.Errorf("expected empty text to be skipped but got %s", got)
+	}
+}
+
+func TestParseFontName(t *testing.T) {
+	tests := []struct {
+		name       string
+		fontName   string
+		wantFamily string
+		wantBold   bool
+		wantItalic bool
+	}{
+		{"test standard font", "Helvetica", "Helvetica", false, false},
+		{"test standard font with style", "Helvetica-BoldOblique", "Helvetica", true, true},
+		{"test subset font", "ABCDEF+ArialMT", "Arial", false, false},
+		{"test subset font with style", "QWERTY+TimesNewRomanPS-BoldItalicMT", "Times New Roman", true, true},
+		{"test style after comma", "Arial,Italic", "Arial", false, true},
+		{"test lowercase prefix is not a subset", "abcdef+Arial", "abcdef+Arial", false, false},
+		{"test empty", "", "", false, false},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			family, bold, italic := ParseFontName(tests[i].fontName)
+			if family != tests[i].wantFamily || bold != tests[i].wantBold || italic != tests[i].wantItalic {
+				t.Errorf("expected %s (bold %t, italic %t) but got %s (bold %t, italic %t)", tests[i].wantFamily, tests[i].wantBold, tests[i].wantItalic, family, bold, italic)
+			}
+		})
+	}
+}
+
+func TestGenericFontFamily(t *testing.T) {
+	tests := []struct {
+		name       string
+		family     string
+		serif      bool
+		fixedPitch bool
+		want       string
+	}{
+		{"test serif flag", "Foo", true, false, "serif"},
+		{"test fixed pitch flag", "Foo", true, true, "monospace"},
+		{"test standard serif font", "Times", false, false, "serif"},
+		{"test standard monospace font", "Courier", false, false, "monospace"},
+		{"test monospace name", "Deja Vu Sans Mono", false, false, "monospace"},
+		{"test sans serif name with serif flag", "Liberation Sans", true, false, "sans-serif"},
+		{"test unknown font", "Helvetica", false, false, "sans-serif"},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			if got := GenericFontFamily(tests[i].family, tests[i].serif, tests[i].fixedPitch); got != tests[i].want {
+				t.Errorf("expected %s but got %s", tests[i].want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package pdf
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// OutputTemplateValues contains the values for the tokens of an output
+// filename template.
+type OutputTemplateValues struct {
+	Input string // The filename of the input without folder and extension.
+	Page  int    // The page number (1-index based).
+	Index int    // The number of the output file within the command (1-index based).
+	Label string // The page label, like iv or A-1.
+	Name  string // The name of the item, like the attachment name.
+	Ext   string // The file extension without dot, like jpg.
+}
+
+// OutputTemplatePageTokens are the tokens that result in a different filename
+// for every page. The page label is not one of them, because labels don't
+// have to be unique.
+var OutputTemplatePageTokens = []string{"page", "index"}
+
+// ExpandOutputTemplate replaces the tokens in an output filename template.
+// Supported tokens are {input}, {page}, {index}, {label}, {name} and {ext}.
+// The numeric tokens {page} and {index} can be zero-padded by giving a width,
+// like {page:04} for 0001. The legacy %d placeholder in the template is
+// replaced by the page number, a %d in a value is kept. Path separators in
+// the values of {label} and {name} are replaced so that values from the PDF
+// can't write outside the output folder.
+func ExpandOutputTemplate(template string, values OutputTemplateValues) (string, error) {
+	result := strings.Builder{}
+
+	// Only the text of the template can contain the legacy placeholder.
+	writeText := func(text string) {
+		result.WriteString(strings.ReplaceAll(text, "%d", strconv.Itoa(values.Page)))
+	}
+
+	remaining := template
+	for {
+		start := strings.Index(remaining, "{")
+		if start == -1 {
+			writeText(remaining)
+			break
+		}
+
+		end := strings.Index(remaining[start:], "}")
+		if end == -1 {
+			return "", fmt.Errorf("unclosed token in output template %s", template)
+		}
+		end += start
+
+		writeText(remaining[:start])
+
+		token := remaining[start+1 : end]
+		format := ""
+		if formatStart := strings.Index(token, ":"); formatStart != -1 {
+			format = token[formatStart+1:]
+			token = token[:formatStart]
+		}
+
+		var value string
+		switch token {
+		case "input":
+			value = values.Input
+		case "page":
+			value = strconv.Itoa(values.Page)
+		case "index":
+			value = strconv.Itoa(values.Index)
+		case "label":
+			value = sanitizeOutputTemplateValue(values.Label)
+		case "name":
+			value = sanitizeOutputTemplateValue(values.Name)
+		case "ext":
+			value = values.Ext
+		default:
+			return "", fmt.Errorf("unknown token {%s} in output template %s", token, template)
+		}
+
+		if format != "" {
+			if token != "page" && token != "index" {
+				return "", fmt.Errorf("token {%s} in output template %s does not support a format", token, template)
+			}
+
+			width, err := strconv.Atoi(format)
+			if err != nil || width < 0 {
+				return "", fmt.Errorf("invalid format %s for token {%s} in output template %s, use a width like {%s:04}", format, token, template, token)
+			}
+
+			for len(value) < width {
+				value = "0" + value
+			}
+		}
+
+		result.WriteString(value)
+		remaining = remaining[end+1:]
+	}
+
+	return result.String(), nil
+}
+
+// OutputTemplateHasToken returns whether the template contains one of the given
+// tokens, with or without format. The token page also matches the legacy %d
+// placeholder.
+func OutputTemplateHasToken(template string, tokens ...string) bool {
+	for _, token := range tokens {
+		if strings.Contains(template, "{"+token+"}") || strings.Contains(template, "{"+token+":") {
+			return true
+		}
+
+		if token == "page" && strings.Contains(template, "%d") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OutputTemplateInputName returns the value for the {input} token for the
+// given input filename.
+func OutputTemplateInputName(filename string) string {
+	if filename == "-" {
+		return "stdin"
+	}
+
+	base := filepath.Base(LocationPath(filename))
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func sanitizeOutputTemplateValue(value string) string {
+	value = strings.NewReplacer("/", "_", "\\", "_").Replace(value)
+	if value == "." || value == ".." {
+		return "_"
+	}
+	return value
+}
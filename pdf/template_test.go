@@ -0,0 +1,147 @@
+package pdf
+
+import (
+	"testing"
+)
+
+func TestExpandOutputTemplate(t *testing.T) {
+	values := OutputTemplateValues{
+		Input: "invoice",
+		Page:  7,
+		Index: 2,
+		Label: "iv",
+		Name:  "terms.txt",
+		Ext:   "jpg",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		values   OutputTemplateValues
+		want     string
+		wantErr  string
+	}{
+		{
+			"test no tokens",
+			"output.jpg",
+			values,
+			"output.jpg",
+			"",
+		},
+		{
+			"test legacy page pattern",
+			"invoice-%d.jpg",
+			values,
+			"invoice-7.jpg",
+			"",
+		},
+		{
+			"test all tokens",
+			"{input}/{page}-{index}-{label}-{name}.{ext}",
+			values,
+			"invoice/7-2-iv-terms.txt.jpg",
+			"",
+		},
+		{
+			"test zero padded page",
+			"{input}-{page:04}.{ext}",
+			values,
+			"invoice-0007.jpg",
+			"",
+		},
+		{
+			"test padding smaller than value",
+			"{index:0}",
+			values,
+			"2",
+			"",
+		},
+		{
+			"test path separators in values",
+			"out/{name}",
+			OutputTemplateValues{Name: "../../etc/passwd"},
+			"out/.._.._etc_passwd",
+			"",
+		},
+		{
+			"test dot dot as value",
+			"out/{label}",
+			OutputTemplateValues{Label: ".."},
+			"out/_",
+			"",
+		},
+		{
+			"test legacy page pattern in values",
+			"{input}-%d-{label}-{name}",
+			OutputTemplateValues{Input: "in%d", Page: 3, Label: "l%d", Name: "a%d.pdf"},
+			"in%d-3-l%d-a%d.pdf",
+			"",
+		},
+		{
+			"test unknown token",
+			"{pages}.jpg",
+			values,
+			"",
+			"unknown token {pages} in output template {pages}.jpg",
+		},
+		{
+			"test unclosed token",
+			"{page.jpg",
+			values,
+			"",
+			"unclosed token in output template {page.jpg",
+		},
+		{
+			"test format on non-numeric token",
+			"{name:04}",
+			values,
+			"",
+			"token {name} in output template {name:04} does not support a format",
+		},
+		{
+			"test invalid format",
+			"{page:abc}",
+			values,
+			"",
+			"invalid format abc for token {page} in output template {page:abc}, use a width like {page:04}",
+		},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, err := ExpandOutputTemplate(tests[i].template, tests[i].values)
+			if tests[i].wantErr == "" && err != nil {
+				t.Errorf("expected no error but got error %s", err.Error())
+			} else if tests[i].wantErr != "" && err == nil {
+				t.Errorf("expected error %s but got no error", tests[i].wantErr)
+			} else if tests[i].wantErr != "" && err != nil && err.Error() != tests[i].wantErr {
+				t.Errorf("expected error %s but got error %s", tests[i].wantErr, err.Error())
+			} else if err == nil && tests[i].want != got {
+				t.Errorf("expected %s but got %s", tests[i].want, got)
+			}
+		})
+	}
+}
+
+func TestOutputTemplateHasToken(t *testing.T) {
+	tests := []struct {
+		template string
+		tokens   []string
+		want     bool
+	}{
+		{"invoice-%d.jpg", []string{"page"}, true},
+		{"invoice-%d.jpg", []string{"index"}, false},
+		{"invoice-{page:03}.jpg", OutputTemplatePageTokens, true},
+		{"invoice-{label}.jpg", OutputTemplatePageTokens, false},
+		{"invoice-{label}-{index}.jpg", OutputTemplatePageTokens, true},
+		{"invoice-{input}.jpg", OutputTemplatePageTokens, false},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].template, func(t *testing.T) {
+			if got := OutputTemplateHasToken(tests[i].template, tests[i].tokens...); got != tests[i].want {
+				t.Errorf("expected %v but got %v", tests[i].want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// TextLayerFormat is the file format of a text layer.
+type TextLayerFormat string
+
+const (
+	TextLayerFormatHOCR TextLayerFormat = "hocr" // hOCR, XHTML with the text in spans with a bbox.
+	TextLayerFormatALTO TextLayerFormat = "alto" // ALTO XML version 4.
+)
+
+// Extension returns the file extension of the text layer format.
+func (f TextLayerFormat) Extension() string {
+	if f == TextLayerFormatALTO {
+		return "xml"
+	}
+	return "hocr"
+}
+
+// TextBox is a box in pixels of an image, with the origin in the top left
+// corner of the image.
+type TextBox struct {
+	Left   float64
+	Top    float64
+	Right  float64
+	Bottom float64
+}
+
+func (b TextBox) isEmpty() bool {
+	return b.Right <= b.Left && b.Bottom <= b.Top
+}
+
+func (b TextBox) union(other TextBox) TextBox {
+	if b.isEmpty() {
+		return other
+	}
+	if other.isEmpty() {
+		return b
+	}
+	return TextBox{
+		Left:   math.Min(b.Left, other.Left),
+		Top:    math.Min(b.Top, other.Top),
+		Right:  math.Max(b.Right, other.Right),
+		Bottom: math.Max(b.Bottom, other.Bottom),
+	}
+}
+
+// rounded returns the box in whole pixels, the box grows to the pixels it
+// touches.
+func (b TextBox) rounded() (int, int, int, int) {
+	return int(math.Floor(b.Left)), int(math.Floor(b.Top)), int(math.Ceil(b.Right)), int(math.Ceil(b.Bottom))
+}
+
+// PageRect converts the box into PDF coordinates of a page of the given size
+// in points, on which the image of the given size in pixels covers the whole
+// page.
+func (b TextBox) PageRect(imageWidth int, imageHeight int, pageWidth float64, pageHeight float64) Rect {
+	scaleX := pageWidth / float64(imageWidth)
+	scaleY := pageHeight / float64(imageHeight)
+	return Rect{
+		Left:   b.Left * scaleX,
+		Bottom: pageHeight - b.Bottom*scaleY,
+		Right:  b.Right * scaleX,
+		Top:    pageHeight - b.Top*scaleY,
+	}
+}
+
+// NewTextBox converts a rectangle in PDF coordinates into a box in pixels of
+// a rendered image. The display matrix transforms the PDF coordinates into
+// display points (see DisplayMatrix), the region is the rendered part of the
+// page in display points and the scales convert points into pixels. It
+// returns false when the box is outside of the image, otherwise the box is
+// clipped to the image.
+func NewTextBox(rect Rect, displayMatrix Matrix, region Region, scaleX, scaleY float64) (TextBox, bool) {
+	// The bounding box in display coordinates has the y-axis pointing down,
+	// so its bottom is at the top of the image.
+	displayRect := displayMatrix.TransformRect(rect)
+	box := TextBox{
+		Left:   math.Max(0, (displayRect.Left-region.X)*scaleX),
+		Top:    math.Max(0, (displayRect.Bottom-region.Y)*scaleY),
+		Right:  math.Min(region.Width*scaleX, (displayRect.Right-region.X)*scaleX),
+		Bottom: math.Min(region.Height*scaleY, (displayRect.Top-region.Y)*scaleY),
+	}
+
+	if box.Right < box.Left || box.Bottom < box.Top {
+		return TextBox{}, false
+	}
+
+	return box, true
+}
+
+// TextChar is a character on a rendered image.
+type TextChar struct {
+	Text string
+	Box  TextBox
+}
+
+// TextWord is a word on a rendered image.
+type TextWord struct {
+	Text string
+	Box  TextBox
+}
+
+// TextLine is a line of words on a rendered image.
+type TextLine struct {
+	Words []TextWord
+	Box   TextBox
+}
+
+// GroupTextLines groups characters in the reading order of the PDF into words
+// and lines. Words end at whitespace, lines end at line breaks and when a
+// character is not on the same height as the line.
+func GroupTextLines(chars []TextChar) []TextLine {
+	lines := []TextLine{}
+	line := TextLine{}
+	word := TextWord{}
+
+	endWord := func() {
+		if word.Text != "" {
+			line.Words = append(line.Words, word)
+			line.Box = line.Box.union(word.Box)
+		}
+		word = TextWord{}
+	}
+
+	endLine := func() {
+		endWord()
+		if len(line.Words) > 0 {
+			lines = append(lines, line)
+		}
+		line = TextLine{}
+	}
+
+	for _, char := range chars {
+		if char.Text == "" {
+			continue
+		}
+
+		if char.Text == "\r" || char.Text == "\n" {
+			endLine()
+			continue
+		}
+
+		if strings.IndexFunc(char.Text, func(r rune) bool { return !unicode.IsSpace(r) }) == -1 {
+			endWord()
+			continue
+		}
+
+		lineBox := line.Box.union(word.Box)
+		if !lineBox.isEmpty() && !char.Box.isEmpty() && !isOnSameLine(lineBox, char.Box) {
+			endLine()
+		}
+
+		word.Text += char.Text
+		word.Box = word.Box.union(char.Box)
+	}
+	endLine()
+
+	return lines
+}
+
+// isOnSameLine returns whether the box of a character overlaps at least half
+// of the height of the line or of the character, and doesn't start before
+// the line.
+func isOnSameLine(line TextBox, char TextBox) bool {
+	overlap := math.Min(line.Bottom, char.Bottom) - math.Max(line.Top, char.Top)
+	minHeight := math.Min(line.Bottom-line.Top, char.Bottom-char.Top)
+	return overlap >= minHeight/2 && char.Right > line.Left
+}
+
+// TextLayerPage is the text layer of a rendered image of a page.
+type TextLayerPage struct {
+	Number    int    // The page number in the PDF.
+	ImageName string // The file name of the rendered image.
+	Width     int    // The width of the rendered image in pixels.
+	Height    int    // The height of the rendered image in pixels.
+	Lines     []TextLine
+}
+
+// WriteTextLayer writes the text layer of the pages in the given format.
+func WriteTextLayer(out io.Writer, format TextLayerFormat, pages []TextLayerPage) error {
+	var result bytes.Buffer
+	switch format {
+	case TextLayerFormatHOCR:
+		writeHOCR(&result, pages)
+	case TextLayerFormatALTO:
+		writeALTO(&result, pages)
+	default:
+		return fmt.Errorf("invalid text layer format %s, use hocr or alto", format)
+	}
+
+	_, err := result.WriteTo(out)
+	return err
+}
+
+func writeHOCR(out *bytes.Buffer, pages []TextLayerPage) {
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	out.WriteString(`<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">` + "\n")
+	out.WriteString(`<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en" lang="en">` + "\n")
+	out.WriteString("<head>\n<title></title>\n")
+	out.WriteString(`<meta http-equiv="Content-Type" content="text/html;charset=utf-8"/>` + "\n")
+	out.WriteString(`<meta name="ocr-system" content="pdfium-cli"/>` + "\n")
+	out.WriteString(`<meta name="ocr-capabilities" content="ocr_page ocr_carea ocr_par ocr_line ocrx_word"/>` + "\n")
+	out.WriteString("</head>\n<body>\n")
+
+	for _, page := range pages {
+		fmt.Fprintf(out, `<div class="ocr_page" id="page_%d" title="image &quot;%s&quot;; bbox 0 0 %d %d; ppageno %d">`+"\n", page.Number, xmlEscape(strings.ReplaceAll(page.ImageName, `"`, "")), page.Width, page.Height, page.Number-1)
+
+		// The text is in one block, because the PDF doesn't tell us where the
+		// blocks and paragraphs are.
+		if len(page.Lines) > 0 {
+			blockBox := TextBox{}
+			for _, line := range page.Lines {
+				blockBox = blockBox.union(line.Box)
+			}
+
+			fmt.Fprintf(out, `<div class="ocr_carea" id="block_%d_1" title="%s">`+"\n", page.Number, hOCRBox(blockBox))
+			fmt.Fprintf(out, `<p class="ocr_par" id="par_%d_1" title="%s">`+"\n", page.Number, hOCRBox(blockBox))
+
+			wordNumber := 0
+			for i, line := range page.Lines {
+				fmt.Fprintf(out, `<span class="ocr_line" id="line_%d_%d" title="%s">`, page.Number, i+1, hOCRBox(line.Box))
+				for j, word := range line.Words {
+					wordNumber++
+					if j > 0 {
+						out.WriteString(" ")
+					}
+					fmt.Fprintf(out, `<span class="ocrx_word" id="word_%d_%d" title="%s; x_wconf 100">%s</span>`, page.Number, wordNumber, hOCRBox(word.Box), xmlEscape(word.Text))
+				}
+				out.WriteString("</span>\n")
+			}
+
+			out.WriteString("</p>\n</div>\n")
+		}
+
+		out.WriteString("</div>\n")
+	}
+
+	out.WriteString("</body>\n</html>\n")
+}
+
+func hOCRBox(box TextBox) string {
+	left, top, right, bottom := box.rounded()
+	return fmt.Sprintf("bbox %d %d %d %d", left, top, right, bottom)
+}
+
+func writeALTO(out *bytes.Buffer, pages []TextLayerPage) {
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	out.WriteString(`<alto xmlns="http://www.loc.gov/standards/alto/ns-v4#" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.loc.gov/standards/alto/ns-v4# http://www.loc.gov/standards/alto/v4/alto-4-2.xsd">` + "\n")
+	out.WriteString("<Description>\n<MeasurementUnit>pixel</MeasurementUnit>\n")
+	if len(pages) > 0 {
+		fmt.Fprintf(out, "<sourceImageInformation>\n<fileName>%s</fileName>\n</sourceImageInformation>\n", xmlEscape(pages[0].ImageName))
+	}
+	out.WriteString("</Description>\n<Layout>\n")
+
+	for _, page := range pages {
+		fmt.Fprintf(out, `<Page ID="page_%d" PHYSICAL_IMG_NR="%d" WIDTH="%d" HEIGHT="%d">`+"\n", page.Number, page.Number, page.Width, page.Height)
+		fmt.Fprintf(out, `<PrintSpace HPOS="0" VPOS="0" WIDTH="%d" HEIGHT="%d">`+"\n", page.Width, page.Height)
+
+		if len(page.Lines) > 0 {
+			blockBox := TextBox{}
+			for _, line := range page.Lines {
+				blockBox = blockBox.union(line.Box)
+			}
+
+			fmt.Fprintf(out, `<TextBlock ID="block_%d_1" %s>`+"\n", page.Number, altoBox(blockBox))
+
+			wordNumber := 0
+			for i, line := range page.Lines {
+				fmt.Fprintf(out, `<TextLine ID="line_%d_%d" %s>`+"\n", page.Number, i+1, altoBox(line.Box))
+				for j, word := range line.Words {
+					wordNumber++
+					if j > 0 {
+						out.WriteString("<SP/>\n")
+					}
+					fmt.Fprintf(out, `<String ID="string_%d_%d" CONTENT="%s" %s/>`+"\n", page.Number, wordNumber, xmlEscape(word.Text), altoBox(word.Box))
+				}
+				out.WriteString("</TextLine>\n")
+			}
+
+			out.WriteString("</TextBlock>\n")
+		}
+
+		out.WriteString("</PrintSpace>\n</Page>\n")
+	}
+
+	out.WriteString("</Layout>\n</alto>\n")
+}
+
+func altoBox(box TextBox) string {
+	left, top, right, bottom := box.rounded()
+	return fmt.Sprintf(`HPOS="%d" VPOS="%d" WIDTH="%d" HEIGHT="%d"`, left, top, right-left, bottom-top)
+}
+
+// xmlEscape escapes text for use in XML, characters that are not allowed in
+// XML are replaced.
+func xmlEscape(value string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(value))
+	return escaped.String()
+}
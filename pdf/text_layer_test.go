@@ -0,0 +1,180 @@
+package pdf
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewTextBox(t *testing.T) {
+	// A page of 100x200 points.
+	displayMatrix := DisplayMatrix(Rect{Right: 100, Top: 200}, 0)
+
+	tests := []struct {
+		name          string
+		rect          Rect
+		displayMatrix Matrix
+		region        Region
+		scale         float64
+		want          TextBox
+		wantOK        bool
+	}{
+		{"test full page", Rect{Left: 10, Bottom: 180, Right: 20, Top: 190}, displayMatrix, Region{Width: 100, Height: 200}, 2, TextBox{Left: 20, Top: 20, Right: 40, Bottom: 40}, true},
+		{"test region", Rect{Left: 10, Bottom: 180, Right: 20, Top: 190}, displayMatrix, Region{X: 5, Y: 5, Width: 50, Height: 50}, 1, TextBox{Left: 5, Top: 5, Right: 15, Bottom: 15}, true},
+		{"test clipped to region", Rect{Left: 10, Bottom: 180, Right: 20, Top: 190}, displayMatrix, Region{X: 15, Y: 15, Width: 50, Height: 50}, 1, TextBox{Left: 0, Top: 0, Right: 5, Bottom: 5}, true},
+		{"test outside of region", Rect{Left: 10, Bottom: 180, Right: 20, Top: 190}, displayMatrix, Region{X: 50, Y: 50, Width: 50, Height: 50}, 1, TextBox{}, false},
+		{"test rotated page", Rect{Left: 10, Bottom: 180, Right: 20, Top: 190}, DisplayMatrix(Rect{Right: 100, Top: 200}, 90), Region{Width: 200, Height: 100}, 1, TextBox{Left: 180, Top: 10, Right: 190, Bottom: 20}, true},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, ok := NewTextBox(tests[i].rect, tests[i].displayMatrix, tests[i].region, tests[i].scale, tests[i].scale)
+			if ok != tests[i].wantOK || got != tests[i].want {
+				t.Errorf("expected %+v (%t) but got %+v (%t)", tests[i].want, tests[i].wantOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestTextBoxPageRect(t *testing.T) {
+	tests := []struct {
+		name       string
+		box        TextBox
+		pageWidth  float64
+		pageHeight float64
+		want       Rect
+	}{
+		{"test same size", TextBox{Left: 10, Top: 20, Right: 30, Bottom: 40}, 200, 100, Rect{Left: 10, Bottom: 60, Right: 30, Top: 80}},
+		{"test scaled", TextBox{Left: 10, Top: 20, Right: 30, Bottom: 40}, 100, 50, Rect{Left: 5, Bottom: 30, Right: 15, Top: 40}},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got := tests[i].box.PageRect(200, 100, tests[i].pageWidth, tests[i].pageHeight)
+			if got != tests[i].want {
+				t.Errorf("expected %+v but got %+v", tests[i].want, got)
+			}
+		})
+	}
+}
+
+func TestGroupTextLines(t *testing.T) {
+	char := func(text string, left, top float64) TextChar {
+		return TextChar{Text: text, Box: TextBox{Left: left, Top: top, Right: left + 10, Bottom: top + 20}}
+	}
+
+	tests := []struct {
+		name  string
+		chars []TextChar
+		want  []TextLine
+	}{
+		{"test empty", nil, []TextLine{}},
+		{
+			"test words and line break",
+			[]TextChar{char("a", 0, 0), char("b", 10, 0), {Text: " "}, char("c", 30, 2), {Text: "\r"}, {Text: "\n"}, char("d", 0, 30)},
+			[]TextLine{
+				{
+					Words: []TextWord{
+						{Text: "ab", Box: TextBox{Left: 0, Top: 0, Right: 20, Bottom: 20}},
+						{Text: "c", Box: TextBox{Left: 30, Top: 2, Right: 40, Bottom: 22}},
+					},
+					Box: TextBox{Left: 0, Top: 0, Right: 40, Bottom: 22},
+				},
+				{
+					Words: []TextWord{{Text: "d", Box: TextBox{Left: 0, Top: 30, Right: 10, Bottom: 50}}},
+					Box:   TextBox{Left: 0, Top: 30, Right: 10, Bottom: 50},
+				},
+			},
+		},
+		{
+			"test new line without line break",
+			[]TextChar{char("a", 0, 0), char("b", 0, 40)},
+			[]TextLine{
+				{Words: []TextWord{{Text: "a", Box: TextBox{Left: 0, Top: 0, Right: 10, Bottom: 20}}}, Box: TextBox{Left: 0, Top: 0, Right: 10, Bottom: 20}},
+				{Words: []TextWord{{Text: "b", Box: TextBox{Left: 0, Top: 40, Right: 10, Bottom: 60}}}, Box: TextBox{Left: 0, Top: 40, Right: 10, Bottom: 60}},
+			},
+		},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			if got := GroupTextLines(tests[i].chars); !reflect.DeepEqual(got, tests[i].want) {
+				t.Errorf("expected %+v but got %+v", tests[i].want, got)
+			}
+		})
+	}
+}
+
+func TestWriteTextLayer(t *testing.T) {
+	pages := []TextLayerPage{
+		{
+			Number:    2,
+			ImageName: "invoice-2.jpg",
+			Width:     200,
+			Height:    300,
+			Lines: []TextLine{
+				{
+					Words: []TextWord{
+						{Text: "Total", Box: TextBox{Left: 10.5, Top: 20, Right: 50, Bottom: 30.2}},
+						{Text: "<€10>", Box: TextBox{Left: 60, Top: 20, Right: 90, Bottom: 30}},
+					},
+					Box: TextBox{Left: 10.5, Top: 20, Right: 90, Bottom: 30.2},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		format  TextLayerFormat
+		want    []string
+		wantErr bool
+	}{
+		{
+			"test hocr",
+			TextLayerFormatHOCR,
+			[]string{
+				`<div class="ocr_page" id="page_2" title="image &quot;invoice-2.jpg&quot;; bbox 0 0 200 300; ppageno 1">`,
+				`<p class="ocr_par" id="par_2_1" title="bbox 10 20 90 31">`,
+				`<span class="ocr_line" id="line_2_1" title="bbox 10 20 90 31"><span class="ocrx_word" id="word_2_1" title="bbox 10 20 50 31; x_wconf 100">Total</span> <span class="ocrx_word" id="word_2_2" title="bbox 60 20 90 30; x_wconf 100">&lt;€10&gt;</span></span>`,
+			},
+			false,
+		},
+		{
+			"test alto",
+			TextLayerFormatALTO,
+			[]string{
+				`<fileName>invoice-2.jpg</fileName>`,
+				`<Page ID="page_2" PHYSICAL_IMG_NR="2" WIDTH="200" HEIGHT="300">`,
+				`<TextLine ID="line_2_1" HPOS="10" VPOS="20" WIDTH="80" HEIGHT="11">`,
+				`<String ID="string_2_1" CONTENT="Total" HPOS="10" VPOS="20" WIDTH="40" HEIGHT="11"/>` + "\n<SP/>\n" + `<String ID="string_2_2" CONTENT="&lt;€10&gt;" HPOS="60" VPOS="20" WIDTH="30" HEIGHT="10"/>`,
+			},
+			false,
+		},
+		{"test invalid format", "pdf", nil, true},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := WriteTextLayer(&out, tests[i].format, pages)
+			if tests[i].wantErr {
+				if err == nil {
+					t.Errorf("expected error but got no error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+
+			for _, snippet := range tests[i].want {
+				if !strings.Contains(out.String(), snippet) {
+					t.Errorf("expected text layer to contain %s but got %s", snippet, out.String())
+				}
+			}
+		})
+	}
+}
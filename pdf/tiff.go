@@ -0,0 +1,361 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+// TIFFCompression is the compression of the image data in a TIFF file.
+type TIFFCompression string
+
+const (
+	TIFFCompressionNone    TIFFCompression = "none"     // No compression.
+	TIFFCompressionLZW     TIFFCompression = "lzw"      // LZW compression, lossless and supported by every reader.
+	TIFFCompressionDeflate TIFFCompression = "deflate"  // Deflate (zlib) compression, lossless and usually smaller than LZW.
+	TIFFCompressionCCITTG4 TIFFCompression = "ccitt-g4" // CCITT Group 4 fax compression, only for black and white images.
+)
+
+// The TIFF tags that we write, see the TIFF 6.0 specification.
+const (
+	tiffTagNewSubfileType            = 254
+	tiffTagImageWidth                = 256
+	tiffTagImageLength               = 257
+	tiffTagBitsPerSample             = 258
+	tiffTagCompression               = 259
+	tiffTagPhotometricInterpretation = 262
+	tiffTagStripOffsets              = 273
+	tiffTagSamplesPerPixel           = 277
+	tiffTagRowsPerStrip              = 278
+	tiffTagStripByteCounts           = 279
+	tiffTagXResolution               = 282
+	tiffTagYResolution               = 283
+	tiffTagResolutionUnit            = 296
+	tiffTagPageNumber                = 297
+)
+
+const (
+	tiffTypeShort    = 3
+	tiffTypeLong     = 4
+	tiffTypeRational = 5
+)
+
+const (
+	tiffPhotometricWhiteIsZero = 0
+	tiffPhotometricBlackIsZero = 1
+	tiffPhotometricRGB         = 2
+)
+
+// tiffPage is an encoded page, ready to be written into the file.
+type tiffPage struct {
+	width         int
+	height        int
+	bitsPerSample int
+	samples       int
+	photometric   int
+	compression   int
+	resolution    float64
+	data          []byte
+}
+
+// TIFFWriter writes one or more images into a single (multi-page) TIFF file.
+// The pages are compressed when they are added and written by WriteTo.
+type TIFFWriter struct {
+	compression TIFFCompression
+	pages       []tiffPage
+}
+
+// NewTIFFWriter creates a writer that compresses the pages with the given
+// compression.
+func NewTIFFWriter(compression TIFFCompression) (*TIFFWriter, error) {
+	switch compression {
+	case TIFFCompressionNone, TIFFCompressionLZW, TIFFCompressionDeflate, TIFFCompressionCCITTG4:
+	default:
+		return nil, fmt.Errorf("invalid TIFF compression %s, use none, lzw, deflate or ccitt-g4", compression)
+	}
+
+	return &TIFFWriter{
+		compression: compression,
+	}, nil
+}
+
+// AddPage adds an image as a page. Resolution is the image resolution in
+// pixels per inch, 0 when unknown. Images are written as RGB, 8-bit gray
+// images as gray and black and white images (a paletted image with two
+// colors) as 1-bit. With CCITT G4 compression every image is converted to
+// black and white.
+func (w *TIFFWriter) AddPage(img image.Image, resolution float64) error {
+	bounds := img.Bounds()
+	if bounds.Dx() < 1 || bounds.Dy() < 1 {
+		return errors.New("invalid image size")
+	}
+
+	page := tiffPage{
+		width:      bounds.Dx(),
+		height:     bounds.Dy(),
+		resolution: resolution,
+	}
+
+	var raw []byte
+	bitonal := w.compression == TIFFCompressionCCITTG4 || isBitonalImage(img)
+	if bitonal {
+		page.bitsPerSample = 1
+		page.samples = 1
+		page.photometric = tiffPhotometricWhiteIsZero
+		raw = packBitonalRows(img)
+	} else if grayImage, ok := img.(*image.Gray); ok {
+		page.bitsPerSample = 8
+		page.samples = 1
+		page.photometric = tiffPhotometricBlackIsZero
+		raw = make([]byte, 0, page.width*page.height)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			offset := grayImage.PixOffset(bounds.Min.X, y)
+			raw = append(raw, grayImage.Pix[offset:offset+page.width]...)
+		}
+	} else {
+		page.bitsPerSample = 8
+		page.samples = 3
+		page.photometric = tiffPhotometricRGB
+		raw = make([]byte, 0, page.width*page.height*3)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+				raw = append(raw, c.R, c.G, c.B)
+			}
+		}
+	}
+
+	switch w.compression {
+	case TIFFCompressionNone:
+		page.compression = 1
+		page.data = raw
+	case TIFFCompressionCCITTG4:
+		page.compression = 4
+		page.data = encodeCCITTG4(raw, page.width, page.height)
+	case TIFFCompressionLZW:
+		page.compression = 5
+		page.data = encodeTIFFLZW(raw)
+	case TIFFCompressionDeflate:
+		page.compression = 8
+		var buf bytes.Buffer
+		zlibWriter := zlib.NewWriter(&buf)
+		if _, err := zlibWriter.Write(raw); err != nil {
+			return err
+		}
+		if err := zlibWriter.Close(); err != nil {
+			return err
+		}
+		page.data = buf.Bytes()
+	}
+
+	w.pages = append(w.pages, page)
+	return nil
+}
+
+// WriteTo writes the TIFF file with all the added pages.
+func (w *TIFFWriter) WriteTo(out io.Writer) (int64, error) {
+	if len(w.pages) == 0 {
+		return 0, errors.New("no pages to write")
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{'I', 'I', 42, 0})
+
+	// Placeholder for the offset of the first IFD.
+	previousNextIFDOffset := buf.Len()
+	buf.Write([]byte{0, 0, 0, 0})
+
+	for i, page := range w.pages {
+		dataOffset := buf.Len()
+		buf.Write(page.data)
+		if buf.Len()%2 == 1 {
+			// IFDs have to start on a word boundary.
+			buf.WriteByte(0)
+		}
+
+		type ifdEntry struct {
+			tag       uint16
+			fieldType uint16
+			values    []uint32
+		}
+
+		resolution := page.resolution
+		if resolution <= 0 {
+			resolution = 72
+		}
+
+		entries := []ifdEntry{}
+		if len(w.pages) > 1 {
+			// Marks the image as one page of a multi-page image.
+			entries = append(entries, ifdEntry{tiffTagNewSubfileType, tiffTypeLong, []uint32{2}})
+		}
+
+		bitsPerSample := make([]uint32, page.samples)
+		for sample := range bitsPerSample {
+			bitsPerSample[sample] = uint32(page.bitsPerSample)
+		}
+
+		// The resolution is stored as rational with a precision of 1/100.
+		resolutionRational := []uint32{uint32(math.Round(resolution * 100)), 100}
+
+		entries = append(entries,
+			ifdEntry{tiffTagImageWidth, tiffTypeLong, []uint32{uint32(page.width)}},
+			ifdEntry{tiffTagImageLength, tiffTypeLong, []uint32{uint32(page.height)}},
+			ifdEntry{tiffTagBitsPerSample, tiffTypeShort, bitsPerSample},
+			ifdEntry{tiffTagCompression, tiffTypeShort, []uint32{uint32(page.compression)}},
+			ifdEntry{tiffTagPhotometricInterpretation, tiffTypeShort, []uint32{uint32(page.photometric)}},
+			ifdEntry{tiffTagStripOffsets, tiffTypeLong, []uint32{uint32(dataOffset)}},
+			ifdEntry{tiffTagSamplesPerPixel, tiffTypeShort, []uint32{uint32(page.samples)}},
+			ifdEntry{tiffTagRowsPerStrip, tiffTypeLong, []uint32{uint32(page.height)}},
+			ifdEntry{tiffTagStripByteCounts, tiffTypeLong, []uint32{uint32(len(page.data))}},
+			ifdEntry{tiffTagXResolution, tiffTypeRational, resolutionRational},
+			ifdEntry{tiffTagYResolution, tiffTypeRational, resolutionRational},
+			ifdEntry{tiffTagResolutionUnit, tiffTypeShort, []uint32{2}}, // Inch.
+			ifdEntry{tiffTagPageNumber, tiffTypeShort, []uint32{uint32(i), uint32(len(w.pages))}},
+		)
+
+		ifdOffset := buf.Len()
+		binary.LittleEndian.PutUint32(buf.Bytes()[previousNextIFDOffset:], uint32(ifdOffset))
+
+		// Values that don't fit in the 4 bytes of the entry are written
+		// after the IFD.
+		ifdSize := 2 + len(entries)*12 + 4
+		extraDataOffset := ifdOffset + ifdSize
+		extraData := &bytes.Buffer{}
+
+		binary.Write(buf, binary.LittleEndian, uint16(len(entries)))
+		for _, entry := range entries {
+			valueBytes := &bytes.Buffer{}
+			for _, value := range entry.values {
+				if entry.fieldType == tiffTypeShort {
+					binary.Write(valueBytes, binary.LittleEndian, uint16(value))
+				} else {
+					binary.Write(valueBytes, binary.LittleEndian, value)
+				}
+			}
+
+			count := len(entry.values)
+			if entry.fieldType == tiffTypeRational {
+				count /= 2
+			}
+
+			binary.Write(buf, binary.LittleEndian, entry.tag)
+			binary.Write(buf, binary.LittleEndian, entry.fieldType)
+			binary.Write(buf, binary.LittleEndian, uint32(count))
+			if valueBytes.Len() <= 4 {
+				value := make([]byte, 4)
+				copy(value, valueBytes.Bytes())
+				buf.Write(value)
+			} else {
+				binary.Write(buf, binary.LittleEndian, uint32(extraDataOffset+extraData.Len()))
+				extraData.Write(valueBytes.Bytes())
+			}
+		}
+
+		previousNextIFDOffset = buf.Len()
+		buf.Write([]byte{0, 0, 0, 0})
+		buf.Write(extraData.Bytes())
+	}
+
+	written, err := out.Write(buf.Bytes())
+	return int64(written), err
+}
+
+// isBitonalImage returns whether the image is a black and white image.
+func isBitonalImage(img image.Image) bool {
+	paletted, ok := img.(*image.Paletted)
+	return ok && len(paletted.Palette) == 2
+}
+
+// packBitonalRows converts the image to black and white and packs it into
+// rows of 1-bit pixels, where 1 is black and every row starts on a new byte.
+func packBitonalRows(img image.Image) []byte {
+	bounds := img.Bounds()
+	rowSize := (bounds.Dx() + 7) / 8
+	packed := make([]byte, rowSize*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := packed[(y-bounds.Min.Y)*rowSize:]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+			if gray.Y < 128 {
+				column := x - bounds.Min.X
+				row[column/8] |= 0x80 >> (column % 8)
+			}
+		}
+	}
+	return packed
+}
+
+// encodeTIFFLZW compresses the data with the LZW variant of TIFF, which
+// writes the codes MSB first and increases the code width one code early.
+func encodeTIFFLZW(data []byte) []byte {
+	const (
+		clearCode = 256
+		eoiCode   = 257
+		firstCode = 258
+		maxCode   = 4094
+	)
+
+	out := &bytes.Buffer{}
+	bitBuffer := uint32(0)
+	bitCount := uint(0)
+	codeWidth := uint(9)
+	writeCode := func(code int) {
+		bitBuffer = bitBuffer<<codeWidth | uint32(code)
+		bitCount += codeWidth
+		for bitCount >= 8 {
+			out.WriteByte(byte(bitBuffer >> (bitCount - 8)))
+			bitCount -= 8
+		}
+	}
+
+	// The table maps the code of a prefix and the next byte to the code
+	// of the combined string.
+	table := map[uint32]int{}
+	nextCode := firstCode
+
+	writeCode(clearCode)
+	if len(data) == 0 {
+		writeCode(eoiCode)
+	} else {
+		prefix := int(data[0])
+		for _, b := range data[1:] {
+			key := uint32(prefix)<<8 | uint32(b)
+			if code, ok := table[key]; ok {
+				prefix = code
+				continue
+			}
+
+			writeCode(prefix)
+			prefix = int(b)
+
+			if nextCode == maxCode {
+				writeCode(clearCode)
+				table = map[uint32]int{}
+				nextCode = firstCode
+				codeWidth = 9
+				continue
+			}
+
+			table[key] = nextCode
+			nextCode++
+			if nextCode+1 > 1<<codeWidth {
+				codeWidth++
+			}
+		}
+		writeCode(prefix)
+		writeCode(eoiCode)
+	}
+
+	if bitCount > 0 {
+		out.WriteByte(byte(bitBuffer << (8 - bitCount)))
+	}
+
+	return out.Bytes()
+}
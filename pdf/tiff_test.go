@@ -0,0 +1,119 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+func testTIFFImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x * 7), G: uint8(y * 13), B: uint8((x + y) % 3 * 100), A: 255})
+		}
+	}
+	return img
+}
+
+func testBitonalImage(width, height int) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, width, height), color.Palette{color.Black, color.White})
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// A mix of short and long runs, and some noise.
+			black := (x/5+y/3)%2 == 0 || (x > width/2 && y%17 < 9) || (x*y)%23 == 0
+			if !black {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return img
+}
+
+func TestTIFFWriter(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression TIFFCompression
+		images      []image.Image
+	}{
+		{"test rgb without compression", TIFFCompressionNone, []image.Image{testTIFFImage(31, 17)}},
+		{"test rgb with lzw", TIFFCompressionLZW, []image.Image{testTIFFImage(300, 200)}},
+		{"test rgb with deflate", TIFFCompressionDeflate, []image.Image{testTIFFImage(64, 64)}},
+		{"test gray with lzw", TIFFCompressionLZW, []image.Image{image.NewGray(image.Rect(0, 0, 10, 10))}},
+		{"test bitonal with lzw", TIFFCompressionLZW, []image.Image{testBitonalImage(45, 20)}},
+		{"test bitonal with ccitt g4", TIFFCompressionCCITTG4, []image.Image{testBitonalImage(3000, 40)}},
+		{"test multiple pages", TIFFCompressionCCITTG4, []image.Image{testBitonalImage(100, 50), testBitonalImage(77, 3), testBitonalImage(1, 1)}},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			writer, err := NewTIFFWriter(tests[i].compression)
+			if err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+
+			for _, img := range tests[i].images {
+				if err := writer.AddPage(img, 300); err != nil {
+					t.Fatalf("expected no error but got error %s", err.Error())
+				}
+			}
+
+			buf := &bytes.Buffer{}
+			if _, err := writer.WriteTo(buf); err != nil {
+				t.Fatalf("expected no error but got error %s", err.Error())
+			}
+
+			// The decoder only reads the first page, so we check the other
+			// pages by moving the offset of the first IFD to their IFD.
+			data := buf.Bytes()
+			for page, want := range tests[i].images {
+				if page > 0 {
+					data = nextTIFFPage(t, data)
+				}
+
+				got, err := tiff.Decode(bytes.NewReader(data))
+				if err != nil {
+					t.Fatalf("expected no error decoding page %d but got error %s", page, err.Error())
+				}
+
+				if got.Bounds() != want.Bounds() {
+					t.Fatalf("expected bounds %v for page %d but got %v", want.Bounds(), page, got.Bounds())
+				}
+
+				for y := want.Bounds().Min.Y; y < want.Bounds().Max.Y; y++ {
+					for x := want.Bounds().Min.X; x < want.Bounds().Max.X; x++ {
+						wantR, wantG, wantB, _ := want.At(x, y).RGBA()
+						gotR, gotG, gotB, _ := got.At(x, y).RGBA()
+						if wantR>>8 != gotR>>8 || wantG>>8 != gotG>>8 || wantB>>8 != gotB>>8 {
+							t.Fatalf("expected pixel %d,%d of page %d to be %v but got %v", x, y, page, want.At(x, y), got.At(x, y))
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNewTIFFWriterInvalidCompression(t *testing.T) {
+	if _, err := NewTIFFWriter("jpeg"); err == nil {
+		t.Errorf("expected error but got no error")
+	}
+}
+
+// nextTIFFPage returns a copy of the file where the first IFD offset points
+// to the IFD of the second page.
+func nextTIFFPage(t *testing.T, data []byte) []byte {
+	firstIFD := int(data[4]) | int(data[5])<<8 | int(data[6])<<16 | int(data[7])<<24
+	entries := int(data[firstIFD]) | int(data[firstIFD+1])<<8
+	nextIFDOffset := firstIFD + 2 + entries*12
+	if bytes.Equal(data[nextIFDOffset:nextIFDOffset+4], []byte{0, 0, 0, 0}) {
+		t.Fatalf("expected another page but the file has no next IFD")
+	}
+
+	next := append([]byte{}, data...)
+	copy(next[4:8], data[nextIFDOffset:nextIFDOffset+4])
+	return next
+}
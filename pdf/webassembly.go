@@ -6,19 +6,30 @@ import (
 	"time"
 
 	"github.com/klippa-app/go-pdfium/webassembly"
+	"github.com/tetratelabs/wazero"
 )
 
-func LoadPdfium() error {
+// LoadPdfiumPool loads the pdfium library with a pool of the given amount of
+// instances, every instance is a separate WebAssembly module.
+func LoadPdfiumPool(instances int) error {
 	if isLoaded {
-		return nil
+		return reloadPdfium(instances)
+	}
+
+	// Checking whether a busy instance is killed makes calls slower, so it
+	// is only enabled when needed.
+	runtimeConfig := wazero.NewRuntimeConfig()
+	if killable {
+		runtimeConfig = runtimeConfig.WithCloseOnContextDone(true)
 	}
 
 	var err error
 	// Init the PDFium library and return the instance to open documents.
 	pool, err = webassembly.Init(webassembly.Config{
-		MinIdle:  1,
-		MaxIdle:  1,
-		MaxTotal: 1,
+		MinIdle:       1,
+		MaxIdle:       instances,
+		MaxTotal:      instances,
+		RuntimeConfig: runtimeConfig,
 	})
 	if err != nil {
 		return err
@@ -30,6 +41,7 @@ func LoadPdfium() error {
 	}
 
 	isLoaded = true
+	loadedInstances = instances
 
 	return nil
 }